@@ -1,43 +1,155 @@
 package main
 
 import (
-	"log"
-	"time"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	// The alpine runtime image has no zoneinfo for POSTING_TIMEZONE
+	_ "time/tzdata"
 
 	"github.com/joho/godotenv"
 	"github.com/think-root/threads-connector/internal/config"
+	"github.com/think-root/threads-connector/internal/logging"
 	"github.com/think-root/threads-connector/internal/server"
 	"github.com/think-root/threads-connector/internal/threads"
 )
 
 func main() {
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found or error loading it")
+	// The environment beats the config file, which beats .env. The file is applied
+	// first, but .env may still name it.
+	dotenv, envErr := godotenv.Read()
+	var fileErr error
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		path = dotenv["CONFIG_FILE"]
+	}
+	if path != "" {
+		fileErr = config.LoadFile(path)
+	}
+	if envErr == nil {
+		envErr = godotenv.Load()
 	}
 
 	cfg := config.Load()
-	if cfg.ThreadsUserID == "" || cfg.ThreadsAccessToken == "" || cfg.APIKey == "" {
-		log.Fatal("THREADS_USER_ID, THREADS_ACCESS_TOKEN, and API_KEY must be set")
+	if err := logging.Setup(cfg.LogFormat, cfg.Debug); err != nil {
+		fatal("Invalid LOG_FORMAT", "error", err)
+	}
+	if envErr != nil {
+		slog.Info("No .env file found or error loading it")
+	}
+	if fileErr != nil {
+		fatal("Invalid CONFIG_FILE", "error", fileErr)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		fatal("Invalid configuration", "error", err)
+	}
+	if err := cfg.LoadAPIKeys(); err != nil {
+		fatal("Invalid API keys", "error", err)
+	}
+	if len(cfg.APIKeys) == 0 {
+		fatal("At least one of API_KEY, API_KEYS or API_KEYS_FILE must be set")
 	}
 
-	client := threads.NewClient(cfg.ThreadsUserID, cfg.ThreadsAccessToken)
+	if err := cfg.LoadBannedWords(); err != nil {
+		fatal("Invalid banned words", "error", err)
+	}
 
-	// Validate access token at startup
-	tokenInfo, err := client.ValidateToken()
-	if err != nil {
-		log.Printf("Failed to validate Threads access token: %v", err)
-	} else if !tokenInfo.IsValid {
-		log.Println("Threads access token is invalid!")
-	} else {
-		expiresAt := time.Unix(tokenInfo.ExpiresAt, 0)
-		daysLeft := int(time.Until(expiresAt).Hours() / 24)
-		log.Printf("Threads access token is valid (expires: %s, %d days remaining)",
-			expiresAt.Format("2006-01-02"), daysLeft)
-		// log.Printf("Token scopes: %v", tokenInfo.Scopes)
+	if err := cfg.LoadThreadsAccounts(); err != nil {
+		fatal("Invalid Threads accounts", "error", err)
+	}
+
+	client := newClient(cfg, cfg.ThreadsUserID, cfg.ThreadsAccessToken)
+	if err := validateToken(cfg, client); err != nil {
+		fatal("Unusable Threads access token", "user_id", client.UserID, "error", err)
+	}
+
+	accounts := make(map[string]*threads.Client, len(cfg.ThreadsAccounts))
+	for userID, token := range cfg.ThreadsAccounts {
+		accounts[userID] = newClient(cfg, userID, token)
+		if err := validateToken(cfg, accounts[userID]); err != nil {
+			fatal("Unusable Threads access token", "user_id", userID, "error", err)
+		}
 	}
 
+	// Stop gracefully on SIGINT/SIGTERM, e.g. when the container is rotated
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	srv := server.New(cfg, client)
-	if err := srv.Start(); err != nil {
-		log.Fatalf("Server failed: %v", err)
+	srv.Accounts = accounts
+	if err := srv.Start(ctx); err != nil {
+		fatal("Server failed", "error", err)
+	}
+}
+
+// newClient creates a Threads client for the account userID with the settings from cfg.
+func newClient(cfg *config.Config, userID, token string) *threads.Client {
+	client := threads.NewClientWithHTTP(userID, token, &http.Client{Timeout: cfg.HTTPTimeout})
+	client.BaseURL = cfg.ThreadsBaseURL
+	client.NumberChunks = cfg.NumberChunks
+	client.SmartSplit = cfg.SmartSplit
+	client.MaxThreadChunks = cfg.MaxThreadChunks
+	client.MaxAttempts = cfg.RetryMaxAttempts
+	client.RetryBaseDelay = cfg.RetryBaseDelay
+	client.InterPostDelay = cfg.InterPostDelay
+	client.InterPostDelays = cfg.InterPostDelays
+	client.PostDelayJitter = cfg.PostDelayJitter
+	client.URLReplyTimeout = cfg.URLReplyTimeout
+	client.ContainerTimeout = cfg.ContainerTimeout
+	client.VideoContainerTimeout = cfg.VideoContainerTimeout
+	client.ContainerPollInterval = cfg.ContainerPollInterval
+	client.FastPublish = cfg.FastPublish
+	client.RecreateExpired = cfg.RecreateExpired
+	client.CheckMediaURLs = cfg.CheckMediaURLs
+	client.MaxImageBytes = cfg.MaxImageBytes
+	client.MaxVideoBytes = cfg.MaxVideoBytes
+	client.CheckMentions = cfg.CheckMentions
+	client.UserAgent = cfg.UserAgent
+	client.MaxResponseBytes = cfg.MaxResponseBytes
+	client.Footer = cfg.PostFooter
+	client.FooterPlacement = threads.FooterPlacement(cfg.FooterPlacement)
+	client.AutoLinkPreview = cfg.AutoLinkPreview
+	client.MaxConcurrentPosts = cfg.MaxConcurrentPosts
+	client.RejectWhenBusy = cfg.RejectWhenBusy
+	client.BreakerThreshold = cfg.BreakerThreshold
+	client.BreakerCooldown = cfg.BreakerCooldown
+	return client
+}
+
+// errInvalidToken is returned by validateToken when Threads reports the token as invalid.
+var errInvalidToken = errors.New("threads access token is invalid")
+
+// validateToken checks the access token of client at startup. An unusable token is only
+// logged, keeping /ready failing, unless REQUIRE_VALID_TOKEN is set, in which case it is
+// returned as an error.
+func validateToken(cfg *config.Config, client *threads.Client) error {
+	tokenInfo, err := client.ValidateToken()
+	if err == nil && !tokenInfo.IsValid {
+		err = errInvalidToken
+	}
+	if err != nil {
+		if cfg.RequireValidToken {
+			return err
+		}
+		slog.Error("Threads access token is unusable", "user_id", client.UserID, "error", err)
+		return nil
 	}
+
+	slog.Info("Threads access token is valid",
+		"user_id", client.UserID,
+		"expires", tokenInfo.ExpiresAtTime().Format("2006-01-02"),
+		"days_remaining", tokenInfo.DaysRemaining())
+	// slog.Info("Token scopes", "scopes", tokenInfo.Scopes)
+	return nil
+}
+
+// fatal logs msg at error level and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
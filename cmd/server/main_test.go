@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/think-root/threads-connector/internal/config"
+	"github.com/think-root/threads-connector/internal/threads"
+	"github.com/think-root/threads-connector/internal/threads/threadstest"
+)
+
+func TestValidateToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		invalid bool
+		apiDown bool
+		require bool
+		wantErr bool
+	}{
+		{"valid token", false, false, true, false},
+		{"invalid token", true, false, false, false},
+		{"invalid token required", true, false, true, true},
+		{"debug_token fails", false, true, false, false},
+		{"debug_token fails required", false, true, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+			if tt.invalid {
+				api.TokenInfo = func() threads.TokenInfo { return threads.TokenInfo{UserID: threadstest.UserID} }
+			}
+			if tt.apiDown {
+				api.Fail = func(r *http.Request) *threads.APIError {
+					return &threads.APIError{StatusCode: http.StatusBadRequest, Message: "unsupported", Code: threads.ErrorCodeInvalidParameter}
+				}
+			}
+
+			err := validateToken(&config.Config{RequireValidToken: tt.require}, api.Client())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateToken = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.invalid && tt.wantErr && !errors.Is(err, errInvalidToken) {
+				t.Errorf("validateToken = %v, want errInvalidToken", err)
+			}
+		})
+	}
+}
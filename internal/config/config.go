@@ -1,23 +1,385 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/think-root/threads-connector/internal/scheduler"
+	"github.com/think-root/threads-connector/internal/threads"
+	"github.com/think-root/threads-connector/internal/version"
 )
 
 type Config struct {
-	ThreadsUserID      string
-	ThreadsAccessToken string
-	Port               string
-	APIKey             string
+	ThreadsUserID         string
+	ThreadsAccessToken    string
+	ThreadsAccountsList   string
+	Port                  string
+	APIKey                string
+	APIKeysList           string
+	APIKeysFile           string
+	NumberChunks          bool
+	RetryMaxAttempts      int
+	RetryBaseDelay        time.Duration
+	URLMode               string
+	InterPostDelay        time.Duration
+	URLReplyTimeout       time.Duration
+	ContainerTimeout      time.Duration
+	VideoContainerTimeout time.Duration
+	ContainerPollInterval time.Duration
+	IdempotencyTTL        time.Duration
+	TokenRefreshDays      int
+	TokenRefreshInterval  time.Duration
+	LogFormat             string
+	Debug                 bool
+	ShutdownTimeout       time.Duration
+	CheckMediaURLs        bool
+	MetricsAddr           string
+	SmartSplit            bool
+	HTTPTimeout           time.Duration
+	ThreadsBaseURL        string
+	CallbackURL           string
+	HashtagPolicy         string
+	PostDelayJitter       float64
+	CORSOrigins           string
+	RateLimitPerMinute    int
+	MaxBodyBytes          int64
+	MaxThreadChunks       int
+	AutoLinkPreview       bool
+	CheckMentions         bool
+	DefaultImageURL       string
+	S3Endpoint            string
+	S3Region              string
+	S3Bucket              string
+	S3AccessKeyID         string
+	S3SecretAccessKey     string
+	S3PublicURL           string
+	MaxUploadBytes        int64
+	RequestTimeout        time.Duration
+	UserAgent             string
+	BannedWordsList       string
+	BannedWordsFile       string
+	PostFooter            string
+	FooterPlacement       string
+	Markdown              bool
+	InterPostDelays       []time.Duration
+	BreakerThreshold      int
+	BreakerCooldown       time.Duration
+	RecreateExpired       bool
+	FastPublish           bool
+	Truncate              bool
+	MaxImageBytes         int64
+	MaxVideoBytes         int64
+	PostingWindows        string
+	PostingTimezone       string
+	OutsideWindow         string
+	HashtagTopic          string
+	MaxResponseBytes      int64
+	MaxConcurrentPosts    int
+	RejectWhenBusy        bool
+	RequireValidToken     bool
+
+	// APIKeys maps every accepted API key to its name; filled by LoadAPIKeys
+	APIKeys map[string]string
+	// ThreadsAccounts maps the user IDs of additional Threads accounts to their access
+	// tokens; filled by LoadThreadsAccounts
+	ThreadsAccounts map[string]string
+	// BannedWords lists the terms posts are checked against; filled by LoadBannedWords
+	BannedWords []string
+
+	// parseErrors holds the values Load couldn't parse, for Validate to report
+	parseErrors []error
 }
 
 func Load() *Config {
-	return &Config{
-		ThreadsUserID:      getEnv("THREADS_USER_ID", ""),
-		ThreadsAccessToken: getEnv("THREADS_ACCESS_TOKEN", ""),
-		Port:               getEnv("PORT", "8080"),
-		APIKey:             getEnv("API_KEY", ""),
+	var l envLoader
+	c := &Config{
+		ThreadsUserID:         getEnv("THREADS_USER_ID", ""),
+		ThreadsAccessToken:    getEnv("THREADS_ACCESS_TOKEN", ""),
+		ThreadsAccountsList:   getEnv("THREADS_ACCOUNTS", ""),
+		Port:                  getEnv("PORT", "8080"),
+		APIKey:                getEnv("API_KEY", ""),
+		APIKeysList:           getEnv("API_KEYS", ""),
+		APIKeysFile:           getEnv("API_KEYS_FILE", ""),
+		NumberChunks:          l.getEnvBool("NUMBER_CHUNKS", false),
+		RetryMaxAttempts:      l.getEnvInt("RETRY_MAX_ATTEMPTS", 3),
+		RetryBaseDelay:        l.getEnvDuration("RETRY_BASE_DELAY", 1*time.Second),
+		URLMode:               getEnv("URL_MODE", "reply"),
+		InterPostDelay:        l.getEnvDuration("INTER_POST_DELAY", 1*time.Second),
+		URLReplyTimeout:       l.getEnvDuration("URL_REPLY_TIMEOUT", 30*time.Second),
+		ContainerTimeout:      l.getEnvDuration("CONTAINER_TIMEOUT", 30*time.Second),
+		VideoContainerTimeout: l.getEnvDuration("VIDEO_CONTAINER_TIMEOUT", 5*time.Minute),
+		ContainerPollInterval: l.getEnvDuration("CONTAINER_POLL_INTERVAL", 2*time.Second),
+		IdempotencyTTL:        l.getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		TokenRefreshDays:      l.getEnvInt("TOKEN_REFRESH_DAYS", 0),
+		TokenRefreshInterval:  l.getEnvDuration("TOKEN_REFRESH_INTERVAL", 12*time.Hour),
+		LogFormat:             getEnv("LOG_FORMAT", "text"),
+		Debug:                 l.getEnvBool("DEBUG", false),
+		ShutdownTimeout:       l.getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+		CheckMediaURLs:        l.getEnvBool("CHECK_MEDIA_URLS", false),
+		MetricsAddr:           getEnv("METRICS_ADDR", ""),
+		SmartSplit:            l.getEnvBool("SMART_SPLIT", false),
+		HTTPTimeout:           l.getEnvDuration("HTTP_TIMEOUT", 60*time.Second),
+		ThreadsBaseURL:        getEnv("THREADS_BASE_URL", "https://graph.threads.net/v1.0"),
+		CallbackURL:           getEnv("CALLBACK_URL", ""),
+		HashtagPolicy:         getEnv("HASHTAG_POLICY", "ignore"),
+		PostDelayJitter:       l.getEnvFloat("POST_DELAY_JITTER", 0.2),
+		CORSOrigins:           getEnv("CORS_ORIGINS", ""),
+		RateLimitPerMinute:    l.getEnvInt("RATE_LIMIT_PER_MINUTE", 0),
+		MaxBodyBytes:          l.getEnvInt64("MAX_BODY_BYTES", 256<<10),
+		MaxThreadChunks:       l.getEnvInt("MAX_THREAD_CHUNKS", 10),
+		AutoLinkPreview:       l.getEnvBool("AUTO_LINK_PREVIEW", false),
+		CheckMentions:         l.getEnvBool("CHECK_MENTIONS", false),
+		DefaultImageURL:       getEnv("DEFAULT_IMAGE_URL", ""),
+		S3Endpoint:            getEnv("S3_ENDPOINT", ""),
+		S3Region:              getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:              getEnv("S3_BUCKET", ""),
+		S3AccessKeyID:         getEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey:     getEnv("S3_SECRET_ACCESS_KEY", ""),
+		S3PublicURL:           getEnv("S3_PUBLIC_URL", ""),
+		MaxUploadBytes:        l.getEnvInt64("MAX_UPLOAD_BYTES", 8<<20),
+		RequestTimeout:        l.getEnvDuration("REQUEST_TIMEOUT", 5*time.Minute),
+		UserAgent:             getEnv("USER_AGENT", "threads-connector/"+version.Version),
+		BannedWordsList:       getEnv("BANNED_WORDS", ""),
+		BannedWordsFile:       getEnv("BANNED_WORDS_FILE", ""),
+		PostFooter:            getEnv("POST_FOOTER", ""),
+		FooterPlacement:       getEnv("FOOTER_PLACEMENT", "last"),
+		Markdown:              l.getEnvBool("MARKDOWN", false),
+		InterPostDelays:       l.getEnvDurations("INTER_POST_DELAYS"),
+		BreakerThreshold:      l.getEnvInt("BREAKER_THRESHOLD", 0),
+		BreakerCooldown:       l.getEnvDuration("BREAKER_COOLDOWN", 30*time.Second),
+		RecreateExpired:       l.getEnvBool("RECREATE_EXPIRED_CONTAINERS", false),
+		FastPublish:           l.getEnvBool("FAST_PUBLISH", false),
+		Truncate:              l.getEnvBool("TRUNCATE", false),
+		MaxImageBytes:         l.getEnvInt64("MAX_IMAGE_BYTES", 8<<20),
+		MaxVideoBytes:         l.getEnvInt64("MAX_VIDEO_BYTES", 1<<30),
+		PostingWindows:        getEnv("POSTING_WINDOWS", ""),
+		PostingTimezone:       getEnv("POSTING_TIMEZONE", "UTC"),
+		OutsideWindow:         getEnv("OUTSIDE_WINDOW", "reject"),
+		HashtagTopic:          getEnv("HASHTAG_TOPIC", "off"),
+		MaxResponseBytes:      l.getEnvInt64("MAX_RESPONSE_BYTES", 1<<20),
+		MaxConcurrentPosts:    l.getEnvInt("MAX_CONCURRENT_POSTS", 0),
+		RejectWhenBusy:        l.getEnvBool("REJECT_WHEN_BUSY", false),
+		RequireValidToken:     l.getEnvBool("REQUIRE_VALID_TOKEN", false),
+	}
+	c.parseErrors = l.errs
+	return c
+}
+
+// Validate checks the settings Load can't reject on its own and returns every problem
+// found, so a misconfigured deployment can be fixed in one go.
+func (c *Config) Validate() error {
+	errs := append([]error(nil), c.parseErrors...)
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.ThreadsUserID != "", "THREADS_USER_ID must be set")
+	check(c.ThreadsAccessToken != "", "THREADS_ACCESS_TOKEN must be set")
+
+	port, err := strconv.Atoi(c.Port)
+	check(err == nil && port >= 1 && port <= 65535, "PORT must be a number between 1 and 65535, got %q", c.Port)
+
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"CONTAINER_TIMEOUT", c.ContainerTimeout},
+		{"VIDEO_CONTAINER_TIMEOUT", c.VideoContainerTimeout},
+		{"CONTAINER_POLL_INTERVAL", c.ContainerPollInterval},
+		{"IDEMPOTENCY_TTL", c.IdempotencyTTL},
+		{"SHUTDOWN_TIMEOUT", c.ShutdownTimeout},
+		{"HTTP_TIMEOUT", c.HTTPTimeout},
+	} {
+		check(d.value > 0, "%s must be positive, got %s", d.name, d.value)
+	}
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"RETRY_BASE_DELAY", c.RetryBaseDelay},
+		{"INTER_POST_DELAY", c.InterPostDelay},
+		{"URL_REPLY_TIMEOUT", c.URLReplyTimeout},
+		{"REQUEST_TIMEOUT", c.RequestTimeout},
+	} {
+		check(d.value >= 0, "%s must not be negative, got %s", d.name, d.value)
+	}
+	for i, d := range c.InterPostDelays {
+		check(d >= 0, "INTER_POST_DELAYS entry %d must not be negative, got %s", i+1, d)
+	}
+	if c.TokenRefreshDays > 0 {
+		check(c.TokenRefreshInterval > 0, "TOKEN_REFRESH_INTERVAL must be positive, got %s", c.TokenRefreshInterval)
+	}
+	if c.BreakerThreshold > 0 {
+		check(c.BreakerCooldown > 0, "BREAKER_COOLDOWN must be positive, got %s", c.BreakerCooldown)
+	}
+
+	check(c.RetryMaxAttempts >= 1, "RETRY_MAX_ATTEMPTS must be at least 1, got %d", c.RetryMaxAttempts)
+	check(c.TokenRefreshDays >= 0 && c.TokenRefreshDays < 60, "TOKEN_REFRESH_DAYS must be between 0 and 59, got %d", c.TokenRefreshDays)
+	check(c.RateLimitPerMinute >= 0, "RATE_LIMIT_PER_MINUTE must not be negative, got %d", c.RateLimitPerMinute)
+	check(c.MaxThreadChunks >= 0, "MAX_THREAD_CHUNKS must not be negative, got %d", c.MaxThreadChunks)
+	check(c.MaxConcurrentPosts >= 0, "MAX_CONCURRENT_POSTS must not be negative, got %d", c.MaxConcurrentPosts)
+	check(c.BreakerThreshold >= 0, "BREAKER_THRESHOLD must not be negative, got %d", c.BreakerThreshold)
+	check(c.MaxBodyBytes > 0, "MAX_BODY_BYTES must be positive, got %d", c.MaxBodyBytes)
+	check(c.MaxUploadBytes > 0, "MAX_UPLOAD_BYTES must be positive, got %d", c.MaxUploadBytes)
+	check(c.MaxResponseBytes >= 0, "MAX_RESPONSE_BYTES must not be negative, got %d", c.MaxResponseBytes)
+	check(c.MaxImageBytes >= 0, "MAX_IMAGE_BYTES must not be negative, got %d", c.MaxImageBytes)
+	check(c.MaxVideoBytes >= 0, "MAX_VIDEO_BYTES must not be negative, got %d", c.MaxVideoBytes)
+	if _, err := scheduler.ParseWindows(c.PostingWindows, c.PostingTimezone); err != nil {
+		errs = append(errs, fmt.Errorf("POSTING_WINDOWS: %w", err))
+	}
+	check(c.OutsideWindow == "reject" || c.OutsideWindow == "queue", "OUTSIDE_WINDOW must be reject or queue, got %q", c.OutsideWindow)
+	check(threads.URLMode(c.URLMode).Valid(), "URL_MODE must be reply, attachment, prepend, append or none, got %q", c.URLMode)
+	check(threads.HashtagPolicy(c.HashtagPolicy).Valid(), "HASHTAG_POLICY must be ignore, warn or reject, got %q", c.HashtagPolicy)
+	check(threads.HashtagTopic(c.HashtagTopic).Valid(), "HASHTAG_TOPIC must be off, first or all, got %q", c.HashtagTopic)
+	check(threads.FooterPlacement(c.FooterPlacement).Valid(), "FOOTER_PLACEMENT must be last or first, got %q", c.FooterPlacement)
+	check(utf8.RuneCountInString(c.PostFooter) <= threads.MaxFooterLength, "POST_FOOTER must be at most %d characters, got %d", threads.MaxFooterLength, utf8.RuneCountInString(c.PostFooter))
+	check(c.PostDelayJitter >= 0 && c.PostDelayJitter <= 1, "POST_DELAY_JITTER must be between 0 and 1, got %g", c.PostDelayJitter)
+
+	for _, u := range []struct {
+		name     string
+		value    string
+		optional bool
+	}{
+		{"THREADS_BASE_URL", c.ThreadsBaseURL, false},
+		{"CALLBACK_URL", c.CallbackURL, true},
+		{"DEFAULT_IMAGE_URL", c.DefaultImageURL, true},
+		{"S3_ENDPOINT", c.S3Endpoint, true},
+		{"S3_PUBLIC_URL", c.S3PublicURL, true},
+	} {
+		if u.optional && u.value == "" {
+			continue
+		}
+		check(validURL(u.value), "%s must be an absolute http or https URL, got %q", u.name, u.value)
+	}
+
+	return errors.Join(errs...)
+}
+
+// validURL reports whether s is an absolute http or https URL with a host.
+func validURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// LoadAPIKeys collects the accepted API keys into APIKeys: API_KEY under the name
+// "default", the pairs in API_KEYS and those in API_KEYS_FILE.
+func (c *Config) LoadAPIKeys() error {
+	keys := make(map[string]string)
+	add := func(name, key string) error {
+		if other, ok := keys[key]; ok {
+			return fmt.Errorf("API keys %q and %q are identical", other, name)
+		}
+		keys[key] = name
+		return nil
+	}
+
+	if c.APIKey != "" {
+		keys[c.APIKey] = "default"
+	}
+	for _, pair := range strings.Split(c.APIKeysList, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, key, err := parseAPIKey(pair)
+		if err != nil {
+			return fmt.Errorf("API_KEYS: %w", err)
+		}
+		if err := add(name, key); err != nil {
+			return err
+		}
+	}
+	if c.APIKeysFile != "" {
+		data, err := os.ReadFile(c.APIKeysFile)
+		if err != nil {
+			return fmt.Errorf("failed to read API_KEYS_FILE: %w", err)
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			name, key, err := parseAPIKey(line)
+			if err != nil {
+				return fmt.Errorf("API_KEYS_FILE line %d: %w", i+1, err)
+			}
+			if err := add(name, key); err != nil {
+				return err
+			}
+		}
+	}
+
+	c.APIKeys = keys
+	return nil
+}
+
+// LoadThreadsAccounts collects the userID:token pairs in THREADS_ACCOUNTS into
+// ThreadsAccounts. THREADS_USER_ID remains the default account and can't be listed again.
+func (c *Config) LoadThreadsAccounts() error {
+	accounts := make(map[string]string)
+	for _, pair := range strings.Split(c.ThreadsAccountsList, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		userID, token, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		userID, token = strings.TrimSpace(userID), strings.TrimSpace(token)
+		if !ok || userID == "" || token == "" {
+			return fmt.Errorf("THREADS_ACCOUNTS: expected userID:token")
+		}
+		if userID == c.ThreadsUserID {
+			return fmt.Errorf("THREADS_ACCOUNTS: user %s is already THREADS_USER_ID", userID)
+		}
+		if _, ok := accounts[userID]; ok {
+			return fmt.Errorf("THREADS_ACCOUNTS: user %s is listed twice", userID)
+		}
+		accounts[userID] = token
+	}
+
+	c.ThreadsAccounts = accounts
+	return nil
+}
+
+// LoadBannedWords collects the terms in BANNED_WORDS and BANNED_WORDS_FILE into BannedWords.
+func (c *Config) LoadBannedWords() error {
+	var words []string
+	for _, word := range strings.Split(c.BannedWordsList, ",") {
+		if word = strings.TrimSpace(word); word != "" {
+			words = append(words, word)
+		}
 	}
+	if c.BannedWordsFile != "" {
+		data, err := os.ReadFile(c.BannedWordsFile)
+		if err != nil {
+			return fmt.Errorf("failed to read BANNED_WORDS_FILE: %w", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			words = append(words, line)
+		}
+	}
+
+	c.BannedWords = words
+	return nil
+}
+
+// parseAPIKey splits a "name:key" pair.
+func parseAPIKey(pair string) (name, key string, err error) {
+	name, key, ok := strings.Cut(strings.TrimSpace(pair), ":")
+	name, key = strings.TrimSpace(name), strings.TrimSpace(key)
+	if !ok || name == "" || key == "" {
+		return "", "", fmt.Errorf("expected name:key")
+	}
+	return name, key, nil
 }
 
 func getEnv(key, fallback string) string {
@@ -26,3 +388,95 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// envLoader reads typed settings from the environment. A value that can't be parsed
+// leaves the default in place and is remembered, so Validate can report it along with
+// everything else. Empty values count as unset.
+type envLoader struct {
+	errs []error
+}
+
+// lookup returns the value of key, or false when it is unset or empty.
+func (l *envLoader) lookup(key string) (string, bool) {
+	value, exists := os.LookupEnv(key)
+	return value, exists && strings.TrimSpace(value) != ""
+}
+
+func (l *envLoader) fail(key, want, value string) {
+	l.errs = append(l.errs, fmt.Errorf("%s must be %s, got %q", key, want, value))
+}
+
+func (l *envLoader) getEnvBool(key string, fallback bool) bool {
+	if value, ok := l.lookup(key); ok {
+		parsed, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err == nil {
+			return parsed
+		}
+		l.fail(key, "true or false", value)
+	}
+	return fallback
+}
+
+func (l *envLoader) getEnvInt(key string, fallback int) int {
+	if value, ok := l.lookup(key); ok {
+		parsed, err := strconv.Atoi(strings.TrimSpace(value))
+		if err == nil {
+			return parsed
+		}
+		l.fail(key, "a whole number", value)
+	}
+	return fallback
+}
+
+func (l *envLoader) getEnvInt64(key string, fallback int64) int64 {
+	if value, ok := l.lookup(key); ok {
+		parsed, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err == nil {
+			return parsed
+		}
+		l.fail(key, "a whole number", value)
+	}
+	return fallback
+}
+
+func (l *envLoader) getEnvFloat(key string, fallback float64) float64 {
+	if value, ok := l.lookup(key); ok {
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err == nil {
+			return parsed
+		}
+		l.fail(key, "a number", value)
+	}
+	return fallback
+}
+
+func (l *envLoader) getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if value, ok := l.lookup(key); ok {
+		parsed, err := time.ParseDuration(strings.TrimSpace(value))
+		if err == nil {
+			return parsed
+		}
+		l.fail(key, "a duration with a unit, such as 30s or 5m", value)
+	}
+	return fallback
+}
+
+// getEnvDurations parses a comma-separated list of durations. It returns nil when the
+// variable is unset or any entry is invalid.
+func (l *envLoader) getEnvDurations(key string) []time.Duration {
+	value, ok := l.lookup(key)
+	if !ok {
+		return nil
+	}
+
+	var durations []time.Duration
+	for _, item := range strings.Split(value, ",") {
+		parsed, err := time.ParseDuration(strings.TrimSpace(item))
+		if err != nil {
+			l.fail(key, "a comma-separated list of durations such as 5s,3s", value)
+			return nil
+		}
+		durations = append(durations, parsed)
+	}
+	return durations
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		// wantErr lists substrings of the expected error; none means the config is valid
+		wantErr []string
+	}{
+		{"defaults", nil, nil},
+		{"missing credentials", map[string]string{"THREADS_USER_ID": "", "THREADS_ACCESS_TOKEN": ""}, []string{"THREADS_USER_ID must be set", "THREADS_ACCESS_TOKEN must be set"}},
+		{"bad port", map[string]string{"PORT": "99999"}, []string{"PORT must be a number"}},
+		{"unparsable int", map[string]string{"RETRY_MAX_ATTEMPTS": "three"}, []string{`RETRY_MAX_ATTEMPTS must be a whole number, got "three"`}},
+		{"unparsable bool", map[string]string{"NUMBER_CHUNKS": "yes please"}, []string{"NUMBER_CHUNKS must be"}},
+		{"unparsable duration", map[string]string{"HTTP_TIMEOUT": "60"}, []string{"HTTP_TIMEOUT must be"}},
+		{"unparsable float", map[string]string{"POST_DELAY_JITTER": "a lot"}, []string{"POST_DELAY_JITTER must be"}},
+		{"unparsable duration list", map[string]string{"INTER_POST_DELAYS": "1s,soon"}, []string{"INTER_POST_DELAYS must be"}},
+		{"out of range", map[string]string{"RETRY_MAX_ATTEMPTS": "0", "POST_DELAY_JITTER": "2"}, []string{"RETRY_MAX_ATTEMPTS must be at least 1", "POST_DELAY_JITTER must be between 0 and 1"}},
+		{"negative duration", map[string]string{"INTER_POST_DELAY": "-1s"}, []string{"INTER_POST_DELAY must not be negative"}},
+		{"negative media limit", map[string]string{"MAX_IMAGE_BYTES": "-1", "MAX_VIDEO_BYTES": "-1"}, []string{"MAX_IMAGE_BYTES must not be negative", "MAX_VIDEO_BYTES must not be negative"}},
+		{"negative response limit", map[string]string{"MAX_RESPONSE_BYTES": "-1"}, []string{"MAX_RESPONSE_BYTES must not be negative"}},
+		{"negative post limit", map[string]string{"MAX_CONCURRENT_POSTS": "-1"}, []string{"MAX_CONCURRENT_POSTS must not be negative"}},
+		{"duration list", map[string]string{"INTER_POST_DELAYS": "3s, 1s"}, nil},
+		{"negative duration in a list", map[string]string{"INTER_POST_DELAYS": "3s,-1s"}, []string{"INTER_POST_DELAYS entry 2 must not be negative"}},
+		{"breaker without cooldown", map[string]string{"BREAKER_THRESHOLD": "3", "BREAKER_COOLDOWN": "0s"}, []string{"BREAKER_COOLDOWN must be positive"}},
+		{"URL mode", map[string]string{"URL_MODE": "inline"}, []string{"URL_MODE must be"}},
+		{"hashtag policy", map[string]string{"HASHTAG_POLICY": "strict"}, []string{"HASHTAG_POLICY must be"}},
+		{"hashtag topic", map[string]string{"HASHTAG_TOPIC": "last"}, []string{"HASHTAG_TOPIC must be"}},
+		{"footer placement", map[string]string{"FOOTER_PLACEMENT": "middle"}, []string{"FOOTER_PLACEMENT must be"}},
+		{"footer too long", map[string]string{"POST_FOOTER": strings.Repeat("ї", 101)}, []string{"POST_FOOTER must be at most 100 characters, got 101"}},
+		{"footer at the limit", map[string]string{"POST_FOOTER": strings.Repeat("ї", 100)}, nil},
+		{"posting windows", map[string]string{"POSTING_WINDOWS": "8-22"}, []string{"POSTING_WINDOWS"}},
+		{"posting time zone", map[string]string{"POSTING_WINDOWS": "08:00-22:00", "POSTING_TIMEZONE": "Mars/Olympus"}, []string{"POSTING_WINDOWS"}},
+		{"outside window", map[string]string{"OUTSIDE_WINDOW": "drop"}, []string{"OUTSIDE_WINDOW must be reject or queue"}},
+		{"callback URL", map[string]string{"CALLBACK_URL": "example.com/hook"}, []string{"CALLBACK_URL must be an absolute http or https URL"}},
+		{"base URL", map[string]string{"THREADS_BASE_URL": "graph.threads.net/v1.0"}, []string{"THREADS_BASE_URL must be an absolute http or https URL"}},
+		{"empty base URL", map[string]string{"THREADS_BASE_URL": ""}, []string{"THREADS_BASE_URL must be"}},
+		{"default image URL", map[string]string{"DEFAULT_IMAGE_URL": "/logo.png"}, []string{"DEFAULT_IMAGE_URL must be an absolute http or https URL"}},
+		{"empty optional URL", map[string]string{"CALLBACK_URL": ""}, nil},
+		{"every problem is reported", map[string]string{"PORT": "http", "URL_MODE": "inline", "MAX_BODY_BYTES": "big"}, []string{"PORT", "URL_MODE", "MAX_BODY_BYTES"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("THREADS_USER_ID", "1234567890")
+			t.Setenv("THREADS_ACCESS_TOKEN", "token")
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			err := Load().Validate()
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() = nil, want errors containing %q", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadAPIKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		apiKey string
+		list   string
+		// file is the content of API_KEYS_FILE, none when empty
+		file    string
+		want    map[string]string
+		wantErr string
+	}{
+		{"API_KEY only", "secret", "", "", map[string]string{"secret": "default"}, ""},
+		{"named keys", "", " dashboard:abc123 , cron:def456,", "", map[string]string{"abc123": "dashboard", "def456": "cron"}, ""},
+		{"all sources", "secret", "dashboard:abc123", "# consumers\n\nbackup:ghi789\n", map[string]string{"secret": "default", "abc123": "dashboard", "ghi789": "backup"}, ""},
+		{"none", "", "", "", map[string]string{}, ""},
+		{"missing name", "", ":abc123", "", nil, "API_KEYS: expected name:key"},
+		{"missing key", "", "cron:", "", nil, "API_KEYS: expected name:key"},
+		{"bad file line", "", "", "backup:ghi789\nnot a pair\n", nil, "API_KEYS_FILE line 2"},
+		{"duplicate key", "secret", "cron:secret", "", nil, `API keys "default" and "cron" are identical`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{APIKey: tt.apiKey, APIKeysList: tt.list}
+			if tt.file != "" {
+				c.APIKeysFile = filepath.Join(t.TempDir(), "keys")
+				if err := os.WriteFile(c.APIKeysFile, []byte(tt.file), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := c.LoadAPIKeys()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadAPIKeys() = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(c.APIKeys, tt.want) {
+				t.Errorf("APIKeys = %v, want %v", c.APIKeys, tt.want)
+			}
+		})
+	}
+
+	c := &Config{APIKeysFile: filepath.Join(t.TempDir(), "missing")}
+	if err := c.LoadAPIKeys(); err == nil || !strings.Contains(err.Error(), "API_KEYS_FILE") {
+		t.Errorf("LoadAPIKeys() with a missing file = %v, want an API_KEYS_FILE error", err)
+	}
+}
+
+func TestLoadThreadsAccounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		list    string
+		want    map[string]string
+		wantErr string
+	}{
+		{"none", "", map[string]string{}, ""},
+		{"accounts", " 5555:tokenA , 6666:tokenB,", map[string]string{"5555": "tokenA", "6666": "tokenB"}, ""},
+		{"missing token", "5555:", nil, "THREADS_ACCOUNTS: expected userID:token"},
+		{"missing colon", "5555", nil, "THREADS_ACCOUNTS: expected userID:token"},
+		{"default account", "1234:tokenA", nil, "user 1234 is already THREADS_USER_ID"},
+		{"listed twice", "5555:tokenA,5555:tokenB", nil, "user 5555 is listed twice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{ThreadsUserID: "1234", ThreadsAccountsList: tt.list}
+			err := c.LoadThreadsAccounts()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadThreadsAccounts() = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(c.ThreadsAccounts, tt.want) {
+				t.Errorf("ThreadsAccounts = %v, want %v", c.ThreadsAccounts, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadInterPostDelays(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []time.Duration
+	}{
+		{"", nil},
+		{"3s", []time.Duration{3 * time.Second}},
+		{" 3s , 500ms ,1m", []time.Duration{3 * time.Second, 500 * time.Millisecond, time.Minute}},
+	}
+	for _, tt := range tests {
+		t.Setenv("INTER_POST_DELAYS", tt.value)
+		if got := Load().InterPostDelays; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("INTER_POST_DELAYS=%q: InterPostDelays = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
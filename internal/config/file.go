@@ -0,0 +1,79 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LoadFile reads settings from the JSON file at path into the environment, so Load
+// picks them up like any other variable. The file is an object keyed by environment
+// variable name, e.g. {"INTER_POST_DELAY": "2s", "NUMBER_CHUNKS": true}; lists such as
+// API_KEYS or INTER_POST_DELAYS may be given as arrays. Variables that already have a
+// value are left alone, so the environment takes precedence over the file; empty ones
+// don't count. Call it before loading .env, which would otherwise shadow the file.
+func LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	for key, raw := range values {
+		value, err := fileValue(raw)
+		if err != nil {
+			return fmt.Errorf("config file %s: %s: %w", path, key, err)
+		}
+		if os.Getenv(key) != "" {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("config file %s: %s: %w", path, key, err)
+		}
+	}
+	return nil
+}
+
+// fileValue renders a JSON value the way it would be written in the environment.
+// Numbers keep their literal form and arrays are joined with commas.
+func fileValue(raw json.RawMessage) (string, error) {
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err == nil {
+		items := make([]string, 0, len(list))
+		for _, item := range list {
+			value, err := scalarValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, value)
+		}
+		return strings.Join(items, ","), nil
+	}
+	return scalarValue(raw)
+}
+
+func scalarValue(raw json.RawMessage) (string, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return "", err
+	}
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("expected a string, number, boolean or array of those")
+	}
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadFile(t *testing.T) {
+	// t.Setenv restores every variable the file may set once the test is done
+	for _, key := range []string{"INTER_POST_DELAY", "NUMBER_CHUNKS", "RETRY_MAX_ATTEMPTS", "API_KEYS", "MAX_THREAD_CHUNKS"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("PORT", "7000")
+
+	if err := LoadFile("testdata/config.json"); err != nil {
+		t.Fatal(err)
+	}
+	cfg := Load()
+
+	if cfg.Port != "7000" {
+		t.Errorf("Port = %q, want the environment's 7000", cfg.Port)
+	}
+	if cfg.InterPostDelay != 2*time.Second {
+		t.Errorf("InterPostDelay = %v, want the file's 2s", cfg.InterPostDelay)
+	}
+	if !cfg.NumberChunks {
+		t.Error("NumberChunks = false, want the file's true")
+	}
+	if cfg.RetryMaxAttempts != 5 {
+		t.Errorf("RetryMaxAttempts = %d, want the file's 5", cfg.RetryMaxAttempts)
+	}
+	if cfg.APIKeysList != "first-key,second-key" {
+		t.Errorf("APIKeysList = %q, want the file's array joined with commas", cfg.APIKeysList)
+	}
+	if cfg.MaxThreadChunks != 10 {
+		t.Errorf("MaxThreadChunks = %d, want the default 10", cfg.MaxThreadChunks)
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{"missing file", "testdata/missing.json", "failed to read config file"},
+		{"nested object", "testdata/nested.json", "PORT: expected a string, number, boolean or array"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PORT", "")
+			err := LoadFile(tt.path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("LoadFile(%q) = %v, want error containing %q", tt.path, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// Package logging sets up structured logging and carries request IDs through contexts,
+// so log lines written deep inside the Threads client correlate to the HTTP request
+// that triggered them.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+)
+
+type (
+	requestIDKey  struct{}
+	apiKeyNameKey struct{}
+)
+
+// Setup installs the default slog logger. format is "text" (the default) or "json".
+// debug enables debug level, which adds full Threads API response bodies. Secrets in
+// string and error attributes are masked with Redact.
+func Setup(format string, debug bool) error {
+	opts := &slog.HandlerOptions{ReplaceAttr: redactAttr}
+	if debug {
+		opts.Level = slog.LevelDebug
+	}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "", "text":
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return fmt.Errorf("unknown log format %q (expected text or json)", format)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// secretPatterns match credentials in URLs, such as the access_token query parameter
+// of a failed GET request, and in JSON bodies like a token refresh response.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`((?:access_token|input_token|client_secret|fb_exchange_token)=)[^&\s"']+`),
+	regexp.MustCompile(`("access_token"\s*:\s*")[^"]*`),
+}
+
+// Redact masks access tokens and other credentials in s, so it can be logged or shown to a caller.
+func Redact(s string) string {
+	for _, pattern := range secretPatterns {
+		s = pattern.ReplaceAllString(s, "${1}REDACTED")
+	}
+	return s
+}
+
+// redactAttr applies Redact to string and error attribute values.
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	switch v := a.Value.Any().(type) {
+	case string:
+		a.Value = slog.StringValue(Redact(v))
+	case error:
+		a.Value = slog.StringValue(Redact(v.Error()))
+	}
+	return a
+}
+
+// NewRequestID returns a random identifier for correlating log lines.
+func NewRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// WithRequestID returns a copy of ctx carrying the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or an empty string.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithAPIKeyName returns a copy of ctx carrying the name of the API key that made the request.
+func WithAPIKeyName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, apiKeyNameKey{}, name)
+}
+
+// APIKeyName returns the API key name carried by ctx, or an empty string.
+func APIKeyName(ctx context.Context) string {
+	name, _ := ctx.Value(apiKeyNameKey{}).(string)
+	return name
+}
+
+// FromContext returns the default logger, annotated with the request ID and API key
+// name carried by ctx if any.
+func FromContext(ctx context.Context) *slog.Logger {
+	logger := slog.Default()
+	if id := RequestID(ctx); id != "" {
+		logger = logger.With("request_id", id)
+	}
+	if name := APIKeyName(ctx); name != "" {
+		logger = logger.With("api_key", name)
+	}
+	return logger
+}
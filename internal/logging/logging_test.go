@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"query parameter", "https://graph.threads.net/v1.0/me?access_token=secret&fields=id", "https://graph.threads.net/v1.0/me?access_token=REDACTED&fields=id"},
+		{"debug_token", "/debug_token?input_token=secret", "/debug_token?input_token=REDACTED"},
+		{"token exchange", "grant_type=fb_exchange_token&fb_exchange_token=secret&client_secret=shh", "grant_type=fb_exchange_token&fb_exchange_token=REDACTED&client_secret=REDACTED"},
+		{"JSON body", `{"access_token": "secret", "expires_in": 5183944}`, `{"access_token": "REDACTED", "expires_in": 5183944}`},
+		{"nothing to redact", "container status FINISHED", "container status FINISHED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Redact(tt.in); got != tt.want {
+				t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactLogOutput(t *testing.T) {
+	const token = "EAAsecret123"
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{ReplaceAttr: redactAttr}))
+
+	endpoint := "https://graph.threads.net/v1.0/1234/threads?access_token=" + token
+	urlErr := &url.Error{Op: "Get", URL: endpoint, Err: errors.New("connection refused")}
+	logger.Error("Request failed",
+		"url", endpoint,
+		"error", fmt.Errorf("failed to check container status: %w", urlErr),
+		"body", `{"access_token":"`+token+`"}`)
+
+	out := buf.String()
+	if strings.Contains(out, token) {
+		t.Errorf("log output contains the token: %s", out)
+	}
+	if n := strings.Count(out, "REDACTED"); n != 3 {
+		t.Errorf("log output has %d redactions, want 3: %s", n, out)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	tests := []struct {
+		name      string
+		format    string
+		debug     bool
+		wantErr   bool
+		wantDebug bool
+	}{
+		{"default", "", false, false, false},
+		{"json", "JSON", false, false, false},
+		{"debug", "text", true, false, true},
+		{"unknown format", "xml", false, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Setup(tt.format, tt.debug)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Setup(%q, %v) = %v, want error %v", tt.format, tt.debug, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := slog.Default().Enabled(context.Background(), slog.LevelDebug); got != tt.wantDebug {
+				t.Errorf("debug level enabled = %v, want %v", got, tt.wantDebug)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// Package metrics defines the Prometheus metrics exported by the connector.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "threads_connector"
+
+var (
+	// PostsCreated counts posts (including whole threads) published successfully.
+	PostsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "posts_created_total",
+		Help:      "Posts published successfully.",
+	})
+
+	// PostsFailed counts posts that failed, labeled by error category.
+	PostsFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "posts_failed_total",
+		Help:      "Posts that failed to publish, by error category.",
+	}, []string{"category"})
+
+	// ContainerTimeouts counts media containers that weren't ready in time.
+	ContainerTimeouts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "container_timeouts_total",
+		Help:      "Media containers that did not become ready before the timeout.",
+	})
+
+	// PostDuration observes the end-to-end time to publish a post or thread.
+	PostDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "post_duration_seconds",
+		Help:      "End-to-end time to publish a post, including all thread replies.",
+		Buckets:   []float64{1, 2, 5, 10, 20, 30, 60, 120, 300, 600},
+	})
+
+	// PostsInFlight is the number of posts being published right now.
+	PostsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "posts_in_flight",
+		Help:      "Posts currently being published, limited by MAX_CONCURRENT_POSTS.",
+	})
+
+	// HTTPRequests counts handled API requests by route and status code.
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by route, method and status code.",
+	}, []string{"route", "method", "status"})
+)
+
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		PostsCreated,
+		PostsFailed,
+		ContainerTimeouts,
+		PostDuration,
+		PostsInFlight,
+		HTTPRequests,
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
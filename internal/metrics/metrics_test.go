@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	// Vectors only show up once a label combination was used
+	PostsFailed.WithLabelValues("api")
+	HTTPRequests.WithLabelValues("GET /health", http.MethodGet, "200")
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, want %d", w.Code, http.StatusOK)
+	}
+
+	for _, name := range []string{
+		"threads_connector_posts_created_total",
+		`threads_connector_posts_failed_total{category="api"}`,
+		"threads_connector_container_timeouts_total",
+		"threads_connector_post_duration_seconds_bucket",
+		"threads_connector_posts_in_flight",
+		`threads_connector_http_requests_total{method="GET",route="GET /health",status="200"}`,
+		"go_goroutines",
+		"process_start_time_seconds",
+	} {
+		if !strings.Contains(w.Body.String(), name) {
+			t.Errorf("metrics output lacks %s", name)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/think-root/threads-connector/internal/logging"
+	"github.com/think-root/threads-connector/internal/threads"
+)
+
+// idleWait is how long Run sleeps when no jobs are pending; adding a job wakes it early.
+const idleWait = time.Hour
+
+// PublishFunc publishes a post whose time has come and returns its ID.
+type PublishFunc func(ctx context.Context, p threads.PostParams) (string, error)
+
+// CompleteFunc is told the outcome of a published job; err is nil on success.
+type CompleteFunc func(ctx context.Context, job Job, postID string, err error)
+
+// Job is a post waiting to be published at PublishAt.
+type Job struct {
+	ID        string
+	PublishAt time.Time
+	CreatedAt time.Time
+	Params    threads.PostParams
+}
+
+// Scheduler keeps pending posts in memory and publishes them when they are due.
+// Pending jobs are lost when the process exits.
+type Scheduler struct {
+	publish PublishFunc
+
+	// OnComplete, if set, is called after each job has been attempted
+	OnComplete CompleteFunc
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+	wake chan struct{}
+}
+
+func New(publish PublishFunc) *Scheduler {
+	return &Scheduler{
+		publish: publish,
+		jobs:    make(map[string]*Job),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Add schedules p to be published at publishAt.
+func (s *Scheduler) Add(publishAt time.Time, p threads.PostParams) (*Job, error) {
+	id, err := NewJobID()
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{
+		ID:        id,
+		PublishAt: publishAt,
+		CreatedAt: time.Now(),
+		Params:    p,
+	}
+
+	s.mu.Lock()
+	s.jobs[id] = job
+	s.mu.Unlock()
+
+	s.notify()
+	return job, nil
+}
+
+// List returns the pending jobs ordered by publish time.
+func (s *Scheduler) List() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].PublishAt.Before(jobs[j].PublishAt)
+	})
+	return jobs
+}
+
+// Cancel removes a pending job. It reports false if the job doesn't exist or has already started.
+func (s *Scheduler) Cancel(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[id]; !ok {
+		return false
+	}
+	delete(s.jobs, id)
+	return true
+}
+
+// Run publishes due jobs until ctx is canceled. Jobs are published one at a time
+// so they respect the client's rate-limit delays.
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		for _, job := range s.takeDue(time.Now()) {
+			s.runJob(ctx, job)
+		}
+
+		timer := time.NewTimer(s.nextWait(time.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-s.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+func (s *Scheduler) runJob(ctx context.Context, job *Job) {
+	// The job ID stands in for a request ID so client logs correlate to the job
+	ctx = logging.WithRequestID(ctx, "job-"+job.ID)
+	logger := logging.FromContext(ctx).With("job_id", job.ID)
+
+	logger.Info("Publishing scheduled post", "publish_at", job.PublishAt.Format(time.RFC3339))
+
+	postID, err := s.publish(ctx, job.Params)
+	if err != nil {
+		logger.Error("Error publishing scheduled post", "error", err)
+	} else {
+		logger.Info("Successfully published scheduled post", "post_id", postID)
+	}
+
+	if s.OnComplete != nil {
+		s.OnComplete(ctx, *job, postID, err)
+	}
+}
+
+// takeDue removes and returns the jobs due at now, earliest first.
+func (s *Scheduler) takeDue(now time.Time) []*Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*Job
+	for id, job := range s.jobs {
+		if !job.PublishAt.After(now) {
+			due = append(due, job)
+			delete(s.jobs, id)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].PublishAt.Before(due[j].PublishAt)
+	})
+	return due
+}
+
+// nextWait returns how long to sleep until the earliest pending job is due.
+func (s *Scheduler) nextWait(now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wait := idleWait
+	for _, job := range s.jobs {
+		wait = min(wait, job.PublishAt.Sub(now))
+	}
+	return max(wait, 0)
+}
+
+// notify wakes Run so it recalculates the next due time.
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// NewJobID returns a random job identifier.
+func NewJobID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate job ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/think-root/threads-connector/internal/threads"
+)
+
+// completion is one OnComplete call.
+type completion struct {
+	text   string
+	postID string
+	err    error
+}
+
+func TestRun(t *testing.T) {
+	errPublish := errors.New("publish failed")
+	s := New(func(ctx context.Context, p threads.PostParams) (string, error) {
+		if p.Text == "fails" {
+			return "", errPublish
+		}
+		return "post-" + p.Text, nil
+	})
+	completed := make(chan completion, 10)
+	s.OnComplete = func(ctx context.Context, job Job, postID string, err error) {
+		completed <- completion{job.Params.Text, postID, err}
+	}
+
+	now := time.Now()
+	add := func(after time.Duration, text string) *Job {
+		t.Helper()
+		job, err := s.Add(now.Add(after), threads.PostParams{Text: text})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return job
+	}
+	add(40*time.Millisecond, "third")
+	add(-time.Minute, "overdue")
+	add(20*time.Millisecond, "fails")
+	canceled := add(30*time.Millisecond, "canceled")
+	later := add(time.Hour, "later")
+
+	if !s.Cancel(canceled.ID) {
+		t.Fatal("Cancel of a pending job = false, want true")
+	}
+	if s.Cancel(canceled.ID) {
+		t.Error("second Cancel = true, want false")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	want := []completion{{"overdue", "post-overdue", nil}, {"fails", "", errPublish}, {"third", "post-third", nil}}
+	var got []completion
+	for range want {
+		select {
+		case c := <-completed:
+			got = append(got, c)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out after completions %v, want %v", got, want)
+		}
+	}
+	if !slices.EqualFunc(got, want, func(a, b completion) bool {
+		return a.text == b.text && a.postID == b.postID && errors.Is(a.err, b.err)
+	}) {
+		t.Errorf("completions = %v, want %v", got, want)
+	}
+
+	// A job added while Run sleeps wakes it up
+	add(-time.Second, "added")
+	select {
+	case c := <-completed:
+		if c.text != "added" {
+			t.Errorf("completed %q, want the added job", c.text)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a job added while Run was idle")
+	}
+
+	if pending := s.List(); len(pending) != 1 || pending[0].ID != later.ID {
+		t.Errorf("List() = %v, want only the job due in an hour", pending)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't return after its context was canceled")
+	}
+}
@@ -0,0 +1,118 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// minutesPerDay is the length of a day in the minutes Window counts in.
+const minutesPerDay = 24 * 60
+
+// Window is a daily time range in which posting is allowed. Start and End are minutes
+// after midnight; a window whose End is before its Start spans midnight.
+type Window struct {
+	Start, End int
+}
+
+// contains reports whether minute m of the day falls in the window.
+func (w Window) contains(m int) bool {
+	if w.Start <= w.End {
+		return m >= w.Start && m < w.End
+	}
+	return m >= w.Start || m < w.End
+}
+
+// Windows is a set of posting windows in a time zone. The zero value has no windows
+// and allows posting at any time.
+type Windows struct {
+	Ranges   []Window
+	Location *time.Location
+}
+
+// ParseWindows parses comma-separated "HH:MM-HH:MM" ranges, e.g. "08:00-12:00,22:00-01:30",
+// in the IANA time zone tz ("" means UTC). An empty spec allows posting at any time.
+func ParseWindows(spec, tz string) (Windows, error) {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return Windows{}, fmt.Errorf("invalid time zone %q: %w", tz, err)
+	}
+
+	windows := Windows{Location: loc}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(part, "-")
+		if !ok {
+			return Windows{}, fmt.Errorf("invalid window %q: expected HH:MM-HH:MM", part)
+		}
+		start, err := parseClock(strings.TrimSpace(from))
+		if err != nil {
+			return Windows{}, fmt.Errorf("invalid window %q: %w", part, err)
+		}
+		end, err := parseClock(strings.TrimSpace(to))
+		if err != nil {
+			return Windows{}, fmt.Errorf("invalid window %q: %w", part, err)
+		}
+		if start == minutesPerDay {
+			return Windows{}, fmt.Errorf("invalid window %q: 24:00 can only end a window", part)
+		}
+		if start == end%minutesPerDay && end != minutesPerDay {
+			return Windows{}, fmt.Errorf("invalid window %q: start and end are the same", part)
+		}
+		windows.Ranges = append(windows.Ranges, Window{Start: start, End: end})
+	}
+	return windows, nil
+}
+
+// parseClock parses "HH:MM" into minutes after midnight; "24:00" is the end of the day.
+func parseClock(s string) (int, error) {
+	var h, m int
+	if _, err := fmt.Sscanf(s, "%d:%d", &h, &m); err != nil || len(s) != 5 {
+		return 0, fmt.Errorf("%q is not an HH:MM time", s)
+	}
+	if h == 24 && m == 0 {
+		return minutesPerDay, nil
+	}
+	if h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, fmt.Errorf("%q is not an HH:MM time", s)
+	}
+	return h*60 + m, nil
+}
+
+// Open reports whether posting is allowed at t.
+func (w Windows) Open(t time.Time) bool {
+	if len(w.Ranges) == 0 {
+		return true
+	}
+	t = t.In(w.Location)
+	m := t.Hour()*60 + t.Minute()
+	for _, r := range w.Ranges {
+		if r.contains(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpen returns the earliest time at or after t when posting is allowed.
+func (w Windows) NextOpen(t time.Time) time.Time {
+	if w.Open(t) {
+		return t
+	}
+
+	local := t.In(w.Location)
+	var next time.Time
+	// Every window starts at least once within the next two calendar days
+	for day := 0; day <= 1; day++ {
+		for _, r := range w.Ranges {
+			start := time.Date(local.Year(), local.Month(), local.Day()+day, r.Start/60, r.Start%60, 0, 0, w.Location)
+			if start.After(t) && (next.IsZero() || start.Before(next)) {
+				next = start
+			}
+		}
+	}
+	return next
+}
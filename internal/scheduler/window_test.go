@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseWindows(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []Window
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"08:00-22:00", []Window{{8 * 60, 22 * 60}}, false},
+		{" 08:00-12:00 , 22:00-01:30 ", []Window{{8 * 60, 12 * 60}, {22 * 60, 90}}, false},
+		{"00:00-24:00", []Window{{0, minutesPerDay}}, false},
+		{"08:00", nil, true},
+		{"8:00-22:00", nil, true},
+		{"08:00-25:00", nil, true},
+		{"08:60-22:00", nil, true},
+		{"08:00-08:00", nil, true},
+		{"24:00-00:00", nil, true},
+		{"morning-night", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseWindows(tt.spec, "UTC")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWindows(%q) error = %v, want error %v", tt.spec, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got.Ranges, tt.want) {
+				t.Errorf("ParseWindows(%q) = %v, want %v", tt.spec, got.Ranges, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseWindows("08:00-22:00", "Mars/Olympus"); err == nil {
+		t.Error("ParseWindows with an unknown time zone succeeded")
+	}
+}
+
+func TestWindowsOpen(t *testing.T) {
+	day := func(h, m int) time.Time { return time.Date(2026, 3, 10, h, m, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name string
+		spec string
+		tz   string
+		at   time.Time
+		want bool
+	}{
+		{"no windows", "", "UTC", day(3, 0), true},
+		{"inside", "08:00-22:00", "UTC", day(12, 0), true},
+		{"at the start", "08:00-22:00", "UTC", day(8, 0), true},
+		{"at the end", "08:00-22:00", "UTC", day(22, 0), false},
+		{"before", "08:00-22:00", "UTC", day(7, 59), false},
+		{"spanning midnight, late", "22:00-02:00", "UTC", day(23, 30), true},
+		{"spanning midnight, early", "22:00-02:00", "UTC", day(1, 0), true},
+		{"spanning midnight, outside", "22:00-02:00", "UTC", day(12, 0), false},
+		{"second range", "08:00-09:00,18:00-19:00", "UTC", day(18, 30), true},
+		{"whole day", "00:00-24:00", "UTC", day(23, 59), true},
+		// 06:00 UTC is 08:00 in Kyiv in March (EET, UTC+2)
+		{"time zone", "08:00-22:00", "Europe/Kyiv", day(6, 0), true},
+		{"time zone, outside", "08:00-22:00", "Europe/Kyiv", day(5, 59), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := ParseWindows(tt.spec, tt.tz)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := w.Open(tt.at); got != tt.want {
+				t.Errorf("Open(%v) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindowsNextOpen(t *testing.T) {
+	day := func(d, h, m int) time.Time { return time.Date(2026, 3, d, h, m, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name string
+		spec string
+		at   time.Time
+		want time.Time
+	}{
+		{"already open", "08:00-22:00", day(10, 12, 0), day(10, 12, 0)},
+		{"later today", "08:00-22:00", day(10, 6, 30), day(10, 8, 0)},
+		{"tomorrow", "08:00-22:00", day(10, 22, 0), day(11, 8, 0)},
+		{"earliest of several", "18:00-19:00,08:00-09:00", day(10, 10, 0), day(10, 18, 0)},
+		{"spanning midnight", "22:00-02:00", day(10, 2, 0), day(10, 22, 0)},
+		{"end of month", "08:00-09:00", time.Date(2026, 3, 31, 10, 0, 0, 0, time.UTC), time.Date(2026, 4, 1, 8, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := ParseWindows(tt.spec, "UTC")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := w.NextOpen(tt.at); !got.Equal(tt.want) {
+				t.Errorf("NextOpen(%v) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
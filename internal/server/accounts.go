@@ -0,0 +1,35 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/think-root/threads-connector/internal/threads"
+)
+
+// scheduledAccountMessage explains why a post for another account can't be scheduled:
+// the scheduler only publishes through the default client.
+const scheduledAccountMessage = "Scheduled and queued posts can only be made as THREADS_USER_ID"
+
+// accountClient returns the client that posts as userID: the default account for an
+// empty userID or THREADS_USER_ID, otherwise one of THREADS_ACCOUNTS.
+func (s *Server) accountClient(userID string) (*threads.Client, *validationError) {
+	if userID == "" || userID == s.Client.UserID {
+		return s.Client, nil
+	}
+	if client, ok := s.Accounts[userID]; ok {
+		return client, nil
+	}
+	return nil, &validationError{"user_id", fmt.Sprintf("Unknown user_id %q", userID)}
+}
+
+// queryAccountClient picks the client for the user_id query parameter of a read
+// request, writing a validation error and returning false for an unknown ID.
+func (s *Server) queryAccountClient(w http.ResponseWriter, r *http.Request) (*threads.Client, bool) {
+	client, verr := s.accountClient(r.URL.Query().Get("user_id"))
+	if verr != nil {
+		writeValidationError(w, verr)
+		return nil, false
+	}
+	return client, true
+}
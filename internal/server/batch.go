@@ -0,0 +1,128 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/think-root/threads-connector/internal/logging"
+	"github.com/think-root/threads-connector/internal/threads"
+)
+
+// maxBatchSize bounds how many posts one batch request may contain.
+const maxBatchSize = 50
+
+type batchRequest struct {
+	Posts []postRequest `json:"posts"`
+}
+
+// batchResult is the outcome of one batch item: one of PostID, JobID or Error is set.
+type batchResult struct {
+	Index     int      `json:"index"`
+	PostID    string   `json:"post_id,omitempty"`
+	PostIDs   []string `json:"post_ids,omitempty"`
+	Permalink string   `json:"permalink,omitempty"`
+	// JobID is set instead of PostID when the post was queued outside POSTING_WINDOWS
+	JobID string `json:"job_id,omitempty"`
+	// URLReplyPosted is only reported when the URL was to be posted as a reply
+	URLReplyPosted *bool        `json:"url_reply_posted,omitempty"`
+	Error          *errorDetail `json:"error,omitempty"`
+}
+
+type batchResponse struct {
+	Results []batchResult `json:"results"`
+}
+
+// handleBatchPost publishes several independent posts one after another. A failed item
+// doesn't stop the rest; each result reports its own outcome.
+func (s *Server) handleBatchPost(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+
+	var req batchRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+	if len(req.Posts) == 0 {
+		writeValidationError(w, &validationError{"posts", "posts must contain at least one post"})
+		return
+	}
+	if len(req.Posts) > maxBatchSize {
+		writeValidationError(w, &validationError{"posts", fmt.Sprintf("posts supports at most %d items", maxBatchSize)})
+		return
+	}
+	// Each post counts against RATE_LIMIT_PER_MINUTE, not just the request
+	if !s.chargeBatch(w, r, len(req.Posts)) {
+		return
+	}
+
+	opensAt, paused := s.postingPaused()
+	if paused && s.Config.OutsideWindow != outsideWindowQueue {
+		writeError(w, http.StatusConflict, codeOutsidePostingWindow, pausedMessage(opensAt))
+		return
+	}
+
+	logger.Info("Processing batch request", "items", len(req.Posts))
+
+	results := make([]batchResult, len(req.Posts))
+	attempted := false
+	for i, item := range req.Posts {
+		results[i].Index = i
+		var client *threads.Client
+
+		params, verr := s.postParams(r.Context(), item)
+		if verr == nil {
+			client, verr = s.accountClient(item.UserID)
+		}
+		if verr != nil {
+			results[i].Error = &errorDetail{Code: codeValidationFailed, Field: verr.Field, Message: verr.Message}
+			continue
+		}
+
+		if paused {
+			if client != s.Client {
+				results[i].Error = &errorDetail{Code: codeOutsidePostingWindow, Message: scheduledAccountMessage}
+				continue
+			}
+			job, err := s.Scheduler.Add(opensAt, params)
+			if err != nil {
+				results[i].Error = &errorDetail{Code: codeInternalError, Message: fmt.Sprintf("Failed to queue post: %v", err)}
+				continue
+			}
+			results[i].JobID = job.ID
+			continue
+		}
+
+		// Space out posts like the parts of a thread, backing off near the rate limit
+		if attempted {
+			if err := threads.SleepContext(r.Context(), client.NextPostDelay()); err != nil {
+				for j := i; j < len(results); j++ {
+					results[j].Index = j
+					results[j].Error = &errorDetail{Code: codeUnavailable, Message: "Request canceled before the post was published"}
+				}
+				break
+			}
+		}
+
+		attempted = true
+		result, err := client.Publish(r.Context(), params)
+		if err != nil {
+			logger.Error("Error creating batch post", "index", i, "error", err)
+			_, detail := createPostError(err)
+			results[i].Error = &detail
+			continue
+		}
+		postID := result.ID
+		results[i].PostID = postID
+		results[i].PostIDs = result.IDs
+		results[i].URLReplyPosted = urlReplyPosted(result)
+
+		if post, err := client.GetPostContext(r.Context(), postID); err != nil {
+			logger.Warn("Error fetching permalink", "post_id", postID, "error", err)
+		} else {
+			results[i].Permalink = post.Permalink
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batchResponse{Results: results})
+}
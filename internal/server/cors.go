@@ -0,0 +1,70 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// CORS settings for browser clients. Only the allowed origins are configurable.
+const (
+	corsAllowedMethods = "GET, POST, PATCH, DELETE, OPTIONS"
+	corsAllowedHeaders = "Content-Type, X-API-Key, Idempotency-Key, X-Request-ID"
+	corsExposedHeaders = "X-Request-ID"
+	corsMaxAge         = "600"
+)
+
+// corsMiddleware adds CORS headers for requests from origins listed in CORS_ORIGINS
+// ("*" allows any) and answers preflight requests itself. With no origins configured
+// it does nothing.
+func (s *Server) corsMiddleware(next http.Handler) http.Handler {
+	origins := parseOrigins(s.Config.CORSOrigins)
+	if len(origins) == 0 {
+		return next
+	}
+	allowAny := slices.Contains(origins, "*")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		allowed := allowAny || slices.Contains(origins, origin)
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if !allowed {
+			if preflight {
+				writeError(w, http.StatusForbidden, codeForbidden, "Origin not allowed")
+				return
+			}
+			// The browser blocks the response without CORS headers
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		if preflight {
+			w.Header().Set("Access-Control-Allow-Methods", corsAllowedMethods)
+			w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+			w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// parseOrigins splits a comma-separated origin list, dropping empty entries.
+func parseOrigins(value string) []string {
+	var origins []string
+	for _, origin := range strings.Split(value, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, strings.TrimSuffix(origin, "/"))
+		}
+	}
+	return origins
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/think-root/threads-connector/internal/config"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	const allowed = "https://app.example.com"
+
+	tests := []struct {
+		name    string
+		origins string
+		method  string
+		origin  string
+		// preflight sets Access-Control-Request-Method
+		preflight   bool
+		status      int
+		wantAllow   string
+		wantMethods bool
+		wantHandler bool
+	}{
+		{"preflight", allowed + "/, https://other.example.com", http.MethodOptions, allowed, true, http.StatusNoContent, allowed, true, false},
+		{"preflight from any origin", "*", http.MethodOptions, "https://evil.example.com", true, http.StatusNoContent, "https://evil.example.com", true, false},
+		{"preflight from a disallowed origin", allowed, http.MethodOptions, "https://evil.example.com", true, http.StatusForbidden, "", false, false},
+		{"request from an allowed origin", allowed, http.MethodPost, allowed, false, http.StatusOK, allowed, false, true},
+		{"request from a disallowed origin", allowed, http.MethodPost, "https://evil.example.com", false, http.StatusOK, "", false, true},
+		{"request without an origin", allowed, http.MethodPost, "", false, http.StatusOK, "", false, true},
+		{"CORS disabled", "", http.MethodOptions, allowed, true, http.StatusOK, "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{Config: &config.Config{CORSOrigins: tt.origins}}
+			handled := false
+			h := s.corsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handled = true
+			}))
+
+			r := httptest.NewRequest(tt.method, "/threads/post", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Errorf("status %d, want %d", w.Code, tt.status)
+			}
+			if handled != tt.wantHandler {
+				t.Errorf("handler called %v, want %v", handled, tt.wantHandler)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantAllow {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantAllow)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Methods"); (got == corsAllowedMethods) != tt.wantMethods {
+				t.Errorf("Access-Control-Allow-Methods = %q, want it set %v", got, tt.wantMethods)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Headers"); tt.wantMethods && !strings.Contains(got, "X-Request-ID") {
+				t.Errorf("Access-Control-Allow-Headers = %q, want it to allow X-Request-ID", got)
+			}
+			// Browsers may only read the request ID of an actual request when it is exposed
+			wantExpose := tt.wantAllow != "" && !tt.preflight
+			if got := w.Header().Get("Access-Control-Expose-Headers"); (got == "X-Request-ID") != wantExpose {
+				t.Errorf("Access-Control-Expose-Headers = %q, want X-Request-ID exposed %v", got, wantExpose)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/think-root/threads-connector/internal/threads"
+)
+
+// Machine-readable error codes returned in errorResponse.
+const (
+	codeUnauthorized         = "unauthorized"
+	codeForbidden            = "forbidden"
+	codeMethodNotAllowed     = "method_not_allowed"
+	codeInvalidJSON          = "invalid_json"
+	codeInvalidForm          = "invalid_form"
+	codePayloadTooLarge      = "payload_too_large"
+	codeUnsupportedMediaType = "unsupported_media_type"
+	codeValidationFailed     = "validation_failed"
+	codeInvalidMediaURL      = "invalid_media_url"
+	codeNotFound             = "not_found"
+	codeConflict             = "conflict"
+	codeDuplicateContent     = "duplicate_content"
+	codeOutsidePostingWindow = "outside_posting_window"
+	codeRateLimited          = "rate_limited"
+	codeNotImplemented       = "not_implemented"
+	codeUpstreamError        = "upstream_error"
+	codeUnavailable          = "unavailable"
+	codeTimeout              = "timeout"
+	codeInternalError        = "internal_error"
+)
+
+// errorResponse is the JSON body of every API error:
+//
+//	{"error": {"code": "validation_failed", "field": "text", "message": "..."}}
+type errorResponse struct {
+	Error errorDetail `json:"error"`
+}
+
+type errorDetail struct {
+	Code    string `json:"code"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+	// PublishedPostIDs lists the posts of a thread that went live before it failed
+	PublishedPostIDs []string `json:"published_post_ids,omitempty"`
+	// ThreadsError carries the Threads API error codes behind an upstream failure
+	ThreadsError *threadsErrorDetail `json:"threads_error,omitempty"`
+}
+
+type threadsErrorDetail struct {
+	Code      int    `json:"code"`
+	Subcode   int    `json:"subcode,omitempty"`
+	FBTraceID string `json:"fbtrace_id,omitempty"`
+}
+
+// validationError describes an invalid request field. Its message is shown to the caller.
+type validationError struct {
+	Field   string
+	Message string
+}
+
+func (e *validationError) Error() string {
+	return e.Message
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeErrorDetail(w, status, errorDetail{Code: code, Message: message})
+}
+
+func writeValidationError(w http.ResponseWriter, err *validationError) {
+	writeErrorDetail(w, http.StatusUnprocessableEntity, errorDetail{
+		Code:    codeValidationFailed,
+		Field:   err.Field,
+		Message: err.Message,
+	})
+}
+
+func writeErrorDetail(w http.ResponseWriter, status int, detail errorDetail) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{Error: detail})
+}
+
+// createPostError maps an error from Client.CreatePostContext to an HTTP status and body.
+func createPostError(err error) (int, errorDetail) {
+	var partialErr *threads.PartialPostError
+	var mediaErr *threads.MediaURLError
+	switch {
+	case errors.As(err, &partialErr):
+		status, detail := upstreamError(err, "create post")
+		detail.PublishedPostIDs = partialErr.PublishedIDs
+		return status, detail
+	case errors.As(err, &mediaErr):
+		return http.StatusUnprocessableEntity, errorDetail{Code: codeInvalidMediaURL, Field: mediaErr.Field, Message: err.Error()}
+	case errors.Is(err, threads.ErrInvalidMediaURL):
+		return http.StatusUnprocessableEntity, errorDetail{Code: codeInvalidMediaURL, Message: err.Error()}
+	case errors.Is(err, threads.ErrInvalidPost):
+		return http.StatusUnprocessableEntity, errorDetail{Code: codeValidationFailed, Message: err.Error()}
+	case errors.Is(err, threads.ErrTooManyPosts):
+		return http.StatusTooManyRequests, errorDetail{Code: codeRateLimited, Message: "Too many posts in flight; retry shortly"}
+	case errors.Is(err, threads.ErrCircuitOpen):
+		return http.StatusServiceUnavailable, errorDetail{Code: codeUnavailable, Message: err.Error()}
+	default:
+		return upstreamError(err, "create post")
+	}
+}
+
+// upstreamError maps a failed Threads API call to an HTTP status and body. Errors with
+// known Graph API codes get a more specific status than 502 and report the codes, so
+// callers can tell whether retrying makes sense.
+func upstreamError(err error, action string) (int, errorDetail) {
+	detail := errorDetail{Code: codeUpstreamError, Message: fmt.Sprintf("Failed to %s: %v", action, err)}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		detail.Code = codeTimeout
+		return http.StatusGatewayTimeout, detail
+	}
+
+	var apiErr *threads.APIError
+	if !errors.As(err, &apiErr) {
+		return http.StatusBadGateway, detail
+	}
+	if apiErr.Code != 0 {
+		detail.ThreadsError = &threadsErrorDetail{Code: apiErr.Code, Subcode: apiErr.Subcode, FBTraceID: apiErr.FBTraceID}
+	}
+
+	switch {
+	case apiErr.Duplicate():
+		detail.Code = codeDuplicateContent
+		return http.StatusConflict, detail
+	case apiErr.RateLimited():
+		detail.Code = codeRateLimited
+		return http.StatusTooManyRequests, detail
+	case apiErr.Code == threads.ErrorCodeInvalidToken:
+		detail.Code = codeUnavailable
+		return http.StatusServiceUnavailable, detail
+	case apiErr.Code == threads.ErrorCodeInvalidParameter:
+		detail.Code = codeValidationFailed
+		return http.StatusUnprocessableEntity, detail
+	case apiErr.Temporary():
+		detail.Code = codeUnavailable
+		return http.StatusServiceUnavailable, detail
+	}
+	return http.StatusBadGateway, detail
+}
+
+func writeUpstreamError(w http.ResponseWriter, err error, action string) {
+	status, detail := upstreamError(err, action)
+	writeErrorDetail(w, status, detail)
+}
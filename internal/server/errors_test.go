@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/think-root/threads-connector/internal/threads"
+)
+
+func TestUpstreamError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantAPI    *threadsErrorDetail
+	}{
+		{"network error", errors.New("connection refused"), http.StatusBadGateway, codeUpstreamError, nil},
+		{"deadline", fmt.Errorf("wait: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, codeTimeout, nil},
+		{
+			"unparsable API error",
+			&threads.APIError{StatusCode: http.StatusBadRequest, Message: "<html>"},
+			http.StatusBadGateway, codeUpstreamError, nil,
+		},
+		{
+			"duplicate content",
+			&threads.APIError{StatusCode: http.StatusBadRequest, Code: threads.ErrorCodeDuplicateContent, FBTraceID: "trace"},
+			http.StatusConflict, codeDuplicateContent, &threadsErrorDetail{Code: threads.ErrorCodeDuplicateContent, FBTraceID: "trace"},
+		},
+		{
+			"rate limited",
+			&threads.APIError{StatusCode: http.StatusBadRequest, Code: 613},
+			http.StatusTooManyRequests, codeRateLimited, &threadsErrorDetail{Code: 613},
+		},
+		{
+			"invalid token",
+			&threads.APIError{StatusCode: http.StatusUnauthorized, Code: threads.ErrorCodeInvalidToken, Subcode: 463},
+			http.StatusServiceUnavailable, codeUnavailable, &threadsErrorDetail{Code: threads.ErrorCodeInvalidToken, Subcode: 463},
+		},
+		{
+			"invalid parameter",
+			&threads.APIError{StatusCode: http.StatusBadRequest, Code: threads.ErrorCodeInvalidParameter, Subcode: 2207052},
+			http.StatusUnprocessableEntity, codeValidationFailed, &threadsErrorDetail{Code: threads.ErrorCodeInvalidParameter, Subcode: 2207052},
+		},
+		{
+			"temporary",
+			&threads.APIError{StatusCode: http.StatusInternalServerError, Code: 2},
+			http.StatusServiceUnavailable, codeUnavailable, &threadsErrorDetail{Code: 2},
+		},
+		{
+			"other code",
+			&threads.APIError{StatusCode: http.StatusForbidden, Code: 10},
+			http.StatusBadGateway, codeUpstreamError, &threadsErrorDetail{Code: 10},
+		},
+		{
+			"wrapped",
+			fmt.Errorf("chunk 2: %w", &threads.APIError{StatusCode: http.StatusBadRequest, Code: threads.ErrorCodeInvalidParameter, Subcode: 33}),
+			http.StatusUnprocessableEntity, codeValidationFailed, &threadsErrorDetail{Code: threads.ErrorCodeInvalidParameter, Subcode: 33},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, detail := upstreamError(tt.err, "create post")
+			if status != tt.wantStatus || detail.Code != tt.wantCode {
+				t.Errorf("got %d %s, want %d %s", status, detail.Code, tt.wantStatus, tt.wantCode)
+			}
+			if !reflect.DeepEqual(detail.ThreadsError, tt.wantAPI) {
+				t.Errorf("threads_error = %+v, want %+v", detail.ThreadsError, tt.wantAPI)
+			}
+		})
+	}
+}
+
+func TestCreatePostError(t *testing.T) {
+	apiErr := &threads.APIError{StatusCode: http.StatusInternalServerError, Code: 1}
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{"invalid post", fmt.Errorf("%w: no content", threads.ErrInvalidPost), http.StatusUnprocessableEntity, codeValidationFailed},
+		{"invalid media URL", &threads.MediaURLError{Field: "image_url", Reason: "is unreachable"}, http.StatusUnprocessableEntity, codeInvalidMediaURL},
+		{"too many posts", threads.ErrTooManyPosts, http.StatusTooManyRequests, codeRateLimited},
+		{"circuit open", threads.ErrCircuitOpen, http.StatusServiceUnavailable, codeUnavailable},
+		{"partial thread", &threads.PartialPostError{PublishedIDs: []string{"1"}, Err: apiErr}, http.StatusServiceUnavailable, codeUnavailable},
+		{"API error", apiErr, http.StatusServiceUnavailable, codeUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, detail := createPostError(tt.err)
+			if status != tt.wantStatus || detail.Code != tt.wantCode {
+				t.Errorf("got %d %s, want %d %s", status, detail.Code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+
+	_, detail := createPostError(&threads.MediaURLError{Field: "image_urls[3]", Reason: "is 2000 bytes, more than the limit of 1000"})
+	if detail.Field != "image_urls[3]" {
+		t.Errorf("invalid media URL field = %q, want the failing item image_urls[3]", detail.Field)
+	}
+
+	_, detail = createPostError(&threads.PartialPostError{PublishedIDs: []string{"1", "2"}, Err: apiErr})
+	if !reflect.DeepEqual(detail.PublishedPostIDs, []string{"1", "2"}) || detail.ThreadsError == nil {
+		t.Errorf("partial thread detail = %+v, want published IDs and threads_error", detail)
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// idempotencyStore remembers the responses of POST /threads/post by Idempotency-Key,
+// so a retried request returns the original post instead of publishing it again.
+// Keys are scoped to the API key that sent them, so clients can't collide with or
+// read each other's results.
+type idempotencyStore struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[idempotencyID]*idempotencyEntry
+}
+
+// idempotencyID identifies a stored response: the name of the API key and the
+// Idempotency-Key it sent.
+type idempotencyID struct {
+	apiKey string
+	key    string
+}
+
+type idempotencyEntry struct {
+	// response is nil while the original request is still being processed
+	response  *storedResponse
+	expiresAt time.Time
+}
+
+// storedResponse is a successful response as it was sent.
+type storedResponse struct {
+	status      int
+	contentType string
+	body        []byte
+}
+
+// write sends the stored response again.
+func (r *storedResponse) write(w http.ResponseWriter) {
+	if r.contentType != "" {
+		w.Header().Set("Content-Type", r.contentType)
+	}
+	w.WriteHeader(r.status)
+	w.Write(r.body)
+}
+
+func newIdempotencyStore(ttl time.Duration) *idempotencyStore {
+	return &idempotencyStore{
+		ttl:     ttl,
+		entries: make(map[idempotencyID]*idempotencyEntry),
+	}
+}
+
+// begin claims id for a new request. If a request with the same id already succeeded
+// within the TTL its response is returned. inProgress is true when another request with
+// the id hasn't finished yet.
+func (s *idempotencyStore) begin(id idempotencyID) (cached *storedResponse, inProgress bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.pruneLocked(now)
+
+	if entry, ok := s.entries[id]; ok {
+		return entry.response, entry.response == nil
+	}
+
+	s.entries[id] = &idempotencyEntry{expiresAt: now.Add(s.ttl)}
+	return nil, false
+}
+
+// finish stores the response captured for a claimed id if it succeeded, and otherwise
+// releases the id so the client can retry.
+func (s *idempotencyStore) finish(id idempotencyID, rec *responseCapture) {
+	if rec.status < 200 || rec.status > 299 {
+		s.release(id)
+		return
+	}
+	s.complete(id, storedResponse{
+		status:      rec.status,
+		contentType: rec.Header().Get("Content-Type"),
+		body:        rec.body.Bytes(),
+	})
+}
+
+// complete stores the response for a claimed id.
+func (s *idempotencyStore) complete(id idempotencyID, resp storedResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[id] = &idempotencyEntry{response: &resp, expiresAt: time.Now().Add(s.ttl)}
+}
+
+// release drops a claimed id after a failed request so the client can retry it.
+func (s *idempotencyStore) release(id idempotencyID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, id)
+}
+
+func (s *idempotencyStore) pruneLocked(now time.Time) {
+	for id, entry := range s.entries {
+		if entry.response != nil && now.After(entry.expiresAt) {
+			delete(s.entries, id)
+		}
+	}
+}
+
+// responseCapture passes a response through while keeping a copy of its status and
+// body for the idempotency store.
+type responseCapture struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (c *responseCapture) WriteHeader(status int) {
+	c.status = status
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *responseCapture) Write(b []byte) (int, error) {
+	c.body.Write(b)
+	return c.ResponseWriter.Write(b)
+}
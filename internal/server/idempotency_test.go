@@ -0,0 +1,88 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyStore(t *testing.T) {
+	first := idempotencyID{apiKey: "default", key: "k1"}
+	ok := storedResponse{status: http.StatusOK, contentType: "application/json", body: []byte(`{"post_id":"1"}`)}
+
+	tests := []struct {
+		name string
+		// setup runs against a fresh store before begin(id) is checked
+		setup          func(s *idempotencyStore)
+		id             idempotencyID
+		wantCached     bool
+		wantInProgress bool
+	}{
+		{"new key", func(*idempotencyStore) {}, first, false, false},
+		{"in progress", func(s *idempotencyStore) { s.begin(first) }, first, false, true},
+		{"completed", func(s *idempotencyStore) { s.begin(first); s.complete(first, ok) }, first, true, false},
+		{"released after a failure", func(s *idempotencyStore) { s.begin(first); s.release(first) }, first, false, false},
+		{"same key from another API key", func(s *idempotencyStore) { s.begin(first); s.complete(first, ok) }, idempotencyID{apiKey: "other", key: "k1"}, false, false},
+		{"other key", func(s *idempotencyStore) { s.begin(first); s.complete(first, ok) }, idempotencyID{apiKey: "default", key: "k2"}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newIdempotencyStore(time.Hour)
+			tt.setup(s)
+
+			cached, inProgress := s.begin(tt.id)
+			if (cached != nil) != tt.wantCached || inProgress != tt.wantInProgress {
+				t.Fatalf("begin = (%v, %v), want cached %v, in progress %v", cached, inProgress, tt.wantCached, tt.wantInProgress)
+			}
+			if cached != nil && (cached.status != ok.status || string(cached.body) != string(ok.body)) {
+				t.Errorf("cached response = %d %s, want %d %s", cached.status, cached.body, ok.status, ok.body)
+			}
+		})
+	}
+}
+
+func TestIdempotencyStoreExpiry(t *testing.T) {
+	id := idempotencyID{apiKey: "default", key: "k1"}
+	s := newIdempotencyStore(time.Millisecond)
+	s.begin(id)
+	s.complete(id, storedResponse{status: http.StatusOK})
+
+	time.Sleep(5 * time.Millisecond)
+	if cached, inProgress := s.begin(id); cached != nil || inProgress {
+		t.Errorf("begin after the TTL = (%v, %v), want a new claim", cached, inProgress)
+	}
+}
+
+func TestIdempotencyStoreFinish(t *testing.T) {
+	tests := []struct {
+		status     int
+		wantCached bool
+	}{
+		{http.StatusOK, true},
+		{http.StatusAccepted, true},
+		{http.StatusUnprocessableEntity, false},
+		{http.StatusBadGateway, false},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			id := idempotencyID{apiKey: "default", key: "k1"}
+			s := newIdempotencyStore(time.Hour)
+			s.begin(id)
+
+			rec := &responseCapture{ResponseWriter: httptest.NewRecorder(), status: http.StatusOK}
+			rec.Header().Set("Content-Type", "application/json")
+			rec.WriteHeader(tt.status)
+			rec.Write([]byte(`{}`))
+			s.finish(id, rec)
+
+			cached, inProgress := s.begin(id)
+			if inProgress || (cached != nil) != tt.wantCached {
+				t.Fatalf("begin = (%v, %v), want cached %v", cached, inProgress, tt.wantCached)
+			}
+			if cached != nil && (cached.status != tt.status || cached.contentType != "application/json" || string(cached.body) != `{}`) {
+				t.Errorf("cached = %+v", cached)
+			}
+		})
+	}
+}
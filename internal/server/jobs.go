@@ -0,0 +1,179 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/think-root/threads-connector/internal/logging"
+	"github.com/think-root/threads-connector/internal/scheduler"
+	"github.com/think-root/threads-connector/internal/threads"
+)
+
+// jobTTL is how long a finished job stays available at GET /threads/jobs/{id}.
+const jobTTL = 24 * time.Hour
+
+// callbackTimeout bounds each request to CALLBACK_URL.
+const callbackTimeout = 10 * time.Second
+
+// Job states reported in jobResponse.Status.
+const (
+	jobPending   = "pending"
+	jobSucceeded = "succeeded"
+	jobFailed    = "failed"
+)
+
+// jobResponse is the state of an async or scheduled post. It is returned by
+// GET /threads/jobs/{id} and sent to CALLBACK_URL once the job finishes.
+type jobResponse struct {
+	ID          string       `json:"id"`
+	Kind        string       `json:"kind"`
+	Status      string       `json:"status"`
+	PostID      string       `json:"post_id,omitempty"`
+	Permalink   string       `json:"permalink,omitempty"`
+	Error       *errorDetail `json:"error,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	CompletedAt *time.Time   `json:"completed_at,omitempty"`
+}
+
+// jobStore keeps async jobs and the outcome of scheduled ones in memory.
+type jobStore struct {
+	mu   sync.Mutex
+	jobs map[string]*jobResponse
+}
+
+func newJobStore() *jobStore {
+	return &jobStore{jobs: make(map[string]*jobResponse)}
+}
+
+func (s *jobStore) put(job jobResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneLocked(time.Now())
+	s.jobs[job.ID] = &job
+}
+
+func (s *jobStore) get(id string) (jobResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return jobResponse{}, false
+	}
+	return *job, true
+}
+
+func (s *jobStore) pruneLocked(now time.Time) {
+	for id, job := range s.jobs {
+		if job.CompletedAt != nil && now.Sub(*job.CompletedAt) > jobTTL {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// startAsyncPost publishes params in the background and responds with 202 Accepted
+// and the job ID right away.
+func (s *Server) startAsyncPost(w http.ResponseWriter, r *http.Request, client *threads.Client, params threads.PostParams) {
+	id, err := scheduler.NewJobID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to create job: %v", err))
+		return
+	}
+
+	job := jobResponse{ID: id, Kind: "async", Status: jobPending, CreatedAt: time.Now()}
+	s.jobs.put(job)
+
+	// The post must outlive the request, but keeps its request ID for logging
+	ctx := context.WithoutCancel(r.Context())
+	s.background.Go(func() {
+		postID, err := client.CreatePostContext(ctx, params)
+		s.finishJob(ctx, client, job, postID, err)
+	})
+
+	logging.FromContext(r.Context()).Info("Accepted async post", "job_id", id)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// completeScheduledJob records the outcome of a scheduled post; it is the scheduler's OnComplete.
+func (s *Server) completeScheduledJob(ctx context.Context, job scheduler.Job, postID string, err error) {
+	s.finishJob(ctx, s.Client, jobResponse{ID: job.ID, Kind: "scheduled", CreatedAt: job.CreatedAt}, postID, err)
+}
+
+// finishJob stores the final state of job, which posted through client, and reports it
+// to CALLBACK_URL.
+func (s *Server) finishJob(ctx context.Context, client *threads.Client, job jobResponse, postID string, err error) {
+	logger := logging.FromContext(ctx).With("job_id", job.ID)
+
+	now := time.Now()
+	job.CompletedAt = &now
+	if err != nil {
+		logger.Error("Job failed", "error", err)
+		_, detail := createPostError(err)
+		job.Status = jobFailed
+		job.Error = &detail
+	} else {
+		job.Status = jobSucceeded
+		job.PostID = postID
+		if post, err := client.GetPostContext(ctx, postID); err != nil {
+			logger.Warn("Error fetching permalink", "post_id", postID, "error", err)
+		} else {
+			job.Permalink = post.Permalink
+		}
+	}
+	s.jobs.put(job)
+
+	if s.Config.CallbackURL != "" {
+		// A scheduled job finishing during shutdown has a canceled ctx, but its
+		// callback is still owed; Start waits for it
+		if err := sendCallback(context.WithoutCancel(ctx), s.Config.CallbackURL, job); err != nil {
+			logger.Error("Error sending job callback", "error", err)
+		}
+	}
+}
+
+func sendCallback(ctx context.Context, callbackURL string, job jobResponse) error {
+	body, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode callback: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, callbackTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build callback request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send callback: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("callback returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.jobs.get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, codeNotFound, "Job not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
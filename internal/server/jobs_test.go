@@ -0,0 +1,192 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/think-root/threads-connector/internal/scheduler"
+	"github.com/think-root/threads-connector/internal/threads"
+	"github.com/think-root/threads-connector/internal/threads/threadstest"
+)
+
+// waitForPolls waits until the first container has been polled.
+func waitForPolls(t *testing.T, api *threadstest.Server) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if containers := api.Containers(); len(containers) > 0 && containers[0].Polls > 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for a container status check")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStartWaitsForAsyncPosts(t *testing.T) {
+	tests := []struct {
+		name string
+		// release lets the container finish this long after shutdown began; 0 never does
+		release  time.Duration
+		grace    time.Duration
+		wantErr  bool
+		wantPost bool
+	}{
+		{"post finishes within the grace period", 20 * time.Millisecond, 5 * time.Second, false, true},
+		{"post outlives the grace period", 0, 20 * time.Millisecond, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, api := newTestServer(t)
+			s.Config.Port = "0"
+			s.Config.ShutdownTimeout = tt.grace
+
+			var released atomic.Bool
+			api.Status = func(c threadstest.Container) string {
+				if released.Load() {
+					return "FINISHED"
+				}
+				return "IN_PROGRESS"
+			}
+			// Don't leave the post running against a closed fake API
+			t.Cleanup(func() {
+				released.Store(true)
+				s.waitBackground(context.Background())
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			errc := make(chan error, 1)
+			go func() { errc <- s.Start(ctx) }()
+
+			if w := post(s, "/threads/post?async=true", "default", "", `{"text":"hello"}`); w.Code != http.StatusAccepted {
+				t.Fatalf("status %d, want %d: %s", w.Code, http.StatusAccepted, w.Body)
+			}
+			waitForPolls(t, api)
+
+			cancel()
+			if tt.release > 0 {
+				time.AfterFunc(tt.release, func() { released.Store(true) })
+			}
+
+			err := <-errc
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Start = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Start = %v, want context.DeadlineExceeded", err)
+			}
+			if n := len(api.Published()); (n == 1) != tt.wantPost {
+				t.Errorf("published %d posts when Start returned, want published %v", n, tt.wantPost)
+			}
+		})
+	}
+}
+
+func TestJobCallback(t *testing.T) {
+	tests := []struct {
+		name string
+		// receiverStatus is what the callback receiver answers
+		receiverStatus int
+		failPost       bool
+		wantStatus     string
+	}{
+		{"success", http.StatusOK, false, jobSucceeded},
+		{"failed post", http.StatusNoContent, true, jobFailed},
+		{"receiver error", http.StatusInternalServerError, false, jobSucceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu        sync.Mutex
+				callbacks []jobResponse
+			)
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var job jobResponse
+				if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
+					t.Errorf("decoding callback: %v", err)
+				}
+				mu.Lock()
+				callbacks = append(callbacks, job)
+				mu.Unlock()
+				w.WriteHeader(tt.receiverStatus)
+			}))
+			defer receiver.Close()
+
+			s, api := newTestServer(t)
+			s.Config.CallbackURL = receiver.URL
+			if tt.failPost {
+				api.Fail = func(r *http.Request) *threads.APIError {
+					return &threads.APIError{StatusCode: http.StatusBadRequest, Message: "bad", Code: threads.ErrorCodeInvalidParameter}
+				}
+			}
+
+			w := post(s, "/threads/post?async=true", "default", "", `{"text":"hello"}`)
+			if w.Code != http.StatusAccepted {
+				t.Fatalf("status %d, want %d: %s", w.Code, http.StatusAccepted, w.Body)
+			}
+			var accepted jobResponse
+			json.NewDecoder(w.Body).Decode(&accepted)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := s.waitBackground(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(callbacks) != 1 {
+				t.Fatalf("received %d callbacks, want 1", len(callbacks))
+			}
+			got := callbacks[0]
+			if got.ID != accepted.ID || got.Status != tt.wantStatus {
+				t.Errorf("callback = %s %s, want %s %s", got.ID, got.Status, accepted.ID, tt.wantStatus)
+			}
+			if tt.wantStatus == jobSucceeded && (got.PostID == "" || got.Permalink == "") {
+				t.Errorf("callback = %+v, want a post ID and permalink", got)
+			}
+			if tt.wantStatus == jobFailed && got.Error == nil {
+				t.Errorf("callback = %+v, want an error", got)
+			}
+
+			// A failed callback doesn't change the job's outcome
+			if job, ok := s.jobs.get(accepted.ID); !ok || job.Status != tt.wantStatus {
+				t.Errorf("stored job = %+v, want status %s", job, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestJobCallbackDuringShutdown(t *testing.T) {
+	received := make(chan jobResponse, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var job jobResponse
+		json.NewDecoder(r.Body).Decode(&job)
+		received <- job
+	}))
+	defer receiver.Close()
+
+	s, _ := newTestServer(t)
+	s.Config.CallbackURL = receiver.URL
+
+	// A scheduled post interrupted by shutdown completes with a canceled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.completeScheduledJob(ctx, scheduler.Job{ID: "job-1", CreatedAt: time.Now()}, "", context.Canceled)
+
+	select {
+	case job := <-received:
+		if job.ID != "job-1" || job.Status != jobFailed {
+			t.Errorf("callback = %s %s, want job-1 %s", job.ID, job.Status, jobFailed)
+		}
+	default:
+		t.Fatal("no callback was sent")
+	}
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// wordFilter finds banned terms in post content. Terms match case-insensitively and
+// only as whole words, so banning "ass" doesn't block "class".
+type wordFilter struct {
+	terms    []string
+	patterns []*regexp.Regexp
+}
+
+func newWordFilter(terms []string) *wordFilter {
+	f := &wordFilter{terms: terms}
+	for _, term := range terms {
+		// Words of a phrase may be separated by any whitespace
+		words := strings.Fields(term)
+		for i, word := range words {
+			words[i] = regexp.QuoteMeta(word)
+		}
+		// \b only knows ASCII word characters, so word edges are spelled out
+		pattern := `(?i)(?:^|[^\p{L}\p{N}_])` + strings.Join(words, `\s+`) + `(?:[^\p{L}\p{N}_]|$)`
+		f.patterns = append(f.patterns, regexp.MustCompile(pattern))
+	}
+	return f
+}
+
+// match returns the first banned term found in text, or "" if there is none.
+func (f *wordFilter) match(text string) string {
+	for i, pattern := range f.patterns {
+		if pattern.MatchString(text) {
+			return f.terms[i]
+		}
+	}
+	return ""
+}
+
+// checkBannedWords rejects a post whose text, alt text or poll options contain a
+// term from BANNED_WORDS or BANNED_WORDS_FILE.
+func (s *Server) checkBannedWords(req postRequest) *validationError {
+	if s.bannedWords == nil {
+		return nil
+	}
+
+	fields := []struct{ name, text string }{
+		{"text", req.Text},
+		{"alt_text", req.AltText},
+	}
+	if req.Poll != nil {
+		for _, option := range req.Poll.Options {
+			fields = append(fields, struct{ name, text string }{"poll.options", option})
+		}
+	}
+	for _, field := range fields {
+		if term := s.bannedWords.match(field.text); term != "" {
+			return &validationError{field.name, fmt.Sprintf("%s contains the banned term %q", field.name, term)}
+		}
+	}
+	return nil
+}
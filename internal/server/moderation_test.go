@@ -0,0 +1,67 @@
+package server
+
+import "testing"
+
+func TestWordFilter(t *testing.T) {
+	f := newWordFilter([]string{"ass", "spam link", "c++", "хрін"})
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"whole word", "what an ass", "ass"},
+		{"different case", "ASS!", "ass"},
+		{"inside a word", "first class", ""},
+		{"word with an underscore", "my_ass", ""},
+		{"phrase", "click this spam link", "spam link"},
+		{"phrase across whitespace", "spam\n\tlink", "spam link"},
+		{"phrase split by a word", "spam and link", ""},
+		{"term with punctuation", "I write (c++) daily", "c++"},
+		{"cyrillic word", "та ну, хрін!", "хрін"},
+		{"cyrillic inside a word", "хріновина", ""},
+		{"no match", "nothing to see here", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := f.match(tt.text); got != tt.want {
+				t.Errorf("match(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckBannedWords(t *testing.T) {
+	s := &Server{bannedWords: newWordFilter([]string{"spam"})}
+
+	tests := []struct {
+		name      string
+		req       postRequest
+		wantField string
+	}{
+		{"clean post", postRequest{Text: "hello", AltText: "a cat", Poll: &pollRequest{Options: []string{"yes", "no"}}}, ""},
+		{"text", postRequest{Text: "buy spam now"}, "text"},
+		{"alt text", postRequest{Text: "hello", AltText: "spam"}, "alt_text"},
+		{"poll option", postRequest{Text: "hello", Poll: &pollRequest{Options: []string{"yes", "Spam"}}}, "poll.options"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verr := s.checkBannedWords(tt.req)
+			if tt.wantField == "" {
+				if verr != nil {
+					t.Fatalf("checkBannedWords = %v, want nil", verr)
+				}
+				return
+			}
+			if verr == nil || verr.Field != tt.wantField {
+				t.Fatalf("checkBannedWords = %v, want an error for %s", verr, tt.wantField)
+			}
+		})
+	}
+
+	// Without banned words nothing is checked
+	s.bannedWords = nil
+	if verr := s.checkBannedWords(postRequest{Text: "spam"}); verr != nil {
+		t.Errorf("checkBannedWords without a filter = %v, want nil", verr)
+	}
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/think-root/threads-connector/internal/logging"
+)
+
+// rateLimiter is a token bucket per client: each holds up to perMinute tokens and
+// refills continuously at perMinute tokens per minute.
+type rateLimiter struct {
+	perMinute int
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func newRateLimiter(perMinute int) *rateLimiter {
+	return &rateLimiter{
+		perMinute: perMinute,
+		buckets:   make(map[string]*tokenBucket),
+	}
+}
+
+// allow takes a token from key's bucket. When the bucket is empty it returns false
+// and how long until the next token is available.
+func (l *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	return l.allowN(key, 1, now)
+}
+
+// allowN is like allow but takes n tokens at once, or none when fewer are available.
+func (l *rateLimiter) allowN(key string, n int, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	capacity := float64(l.perMinute)
+	perSecond := capacity / 60
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: capacity, updated: now}
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens = min(capacity, bucket.tokens+now.Sub(bucket.updated).Seconds()*perSecond)
+	bucket.updated = now
+
+	if bucket.tokens < float64(n) {
+		wait := time.Duration((float64(n) - bucket.tokens) / perSecond * float64(time.Second))
+		return false, wait
+	}
+	bucket.tokens -= float64(n)
+	return true, 0
+}
+
+// rateLimitMiddleware limits each API key to RATE_LIMIT_PER_MINUTE requests, answering
+// 429 with Retry-After once the limit is reached. It must run after authMiddleware.
+func (s *Server) rateLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	if s.rateLimiter == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		allowed, wait := s.rateLimiter.allow(logging.APIKeyName(r.Context()), time.Now())
+		if !allowed {
+			writeRateLimited(w, wait)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// chargeBatch takes a rate limit token for every post in a batch of n beyond the first,
+// which rateLimitMiddleware already charged for. It writes the error response and
+// returns false when the API key's bucket can't cover them.
+func (s *Server) chargeBatch(w http.ResponseWriter, r *http.Request, n int) bool {
+	if s.rateLimiter == nil || n <= 1 {
+		return true
+	}
+	if n > s.rateLimiter.perMinute {
+		writeValidationError(w, &validationError{"posts", fmt.Sprintf("posts supports at most %d items with RATE_LIMIT_PER_MINUTE=%d", s.rateLimiter.perMinute, s.rateLimiter.perMinute)})
+		return false
+	}
+	allowed, wait := s.rateLimiter.allowN(logging.APIKeyName(r.Context()), n-1, time.Now())
+	if !allowed {
+		writeRateLimited(w, wait)
+		return false
+	}
+	return true
+}
+
+func writeRateLimited(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	writeError(w, http.StatusTooManyRequests, codeRateLimited, "Rate limit exceeded")
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/think-root/threads-connector/internal/logging"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		// requests are made at these offsets from start, all with the same key
+		requests []time.Duration
+		want     []bool
+		// wantWait is the wait reported for the last request
+		wantWait time.Duration
+	}{
+		{"within the burst", []time.Duration{0, 0, 0}, []bool{true, true, true}, 0},
+		{"burst exhausted", []time.Duration{0, 0, 0, 0}, []bool{true, true, true, false}, 20 * time.Second},
+		{"refilled after a while", []time.Duration{0, 0, 0, 0, 20 * time.Second}, []bool{true, true, true, false, true}, 0},
+		{"partly refilled", []time.Duration{0, 0, 0, 10 * time.Second}, []bool{true, true, true, false}, 10 * time.Second},
+		{"refill caps at the limit", []time.Duration{0, time.Hour, time.Hour, time.Hour, time.Hour}, []bool{true, true, true, true, false}, 20 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newRateLimiter(3)
+			var wait time.Duration
+			for i, offset := range tt.requests {
+				var allowed bool
+				allowed, wait = l.allow("default", start.Add(offset))
+				if allowed != tt.want[i] {
+					t.Fatalf("request %d: allowed = %v, want %v", i, allowed, tt.want[i])
+				}
+			}
+			if wait.Round(time.Millisecond) != tt.wantWait {
+				t.Errorf("wait = %v, want %v", wait, tt.wantWait)
+			}
+		})
+	}
+}
+
+func TestRateLimiterKeys(t *testing.T) {
+	now := time.Now()
+	l := newRateLimiter(1)
+	if allowed, _ := l.allow("a", now); !allowed {
+		t.Fatal("first request for a was rejected")
+	}
+	if allowed, _ := l.allow("a", now); allowed {
+		t.Error("second request for a was allowed")
+	}
+	if allowed, _ := l.allow("b", now); !allowed {
+		t.Error("b shares a's bucket")
+	}
+}
+
+func TestChargeBatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		spent  int // tokens already taken from the bucket
+		items  int
+		want   bool
+		status int
+	}{
+		{"single item is charged by the middleware", 10, 1, true, http.StatusOK},
+		{"covered", 1, 5, true, http.StatusOK},
+		{"whole bucket", 1, 10, true, http.StatusOK},
+		{"not covered", 6, 6, false, http.StatusTooManyRequests},
+		{"larger than the limit", 0, 11, false, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t)
+			s.rateLimiter = newRateLimiter(10)
+
+			r := httptest.NewRequest(http.MethodPost, "/threads/batch", strings.NewReader("{}"))
+			r = r.WithContext(logging.WithAPIKeyName(r.Context(), "default"))
+			if tt.spent > 0 {
+				s.rateLimiter.allowN("default", tt.spent, time.Now())
+			}
+
+			w := httptest.NewRecorder()
+			if got := s.chargeBatch(w, r, tt.items); got != tt.want {
+				t.Fatalf("chargeBatch = %v, want %v", got, tt.want)
+			}
+			if w.Code != tt.status {
+				t.Errorf("status %d, want %d", w.Code, tt.status)
+			}
+			if tt.status == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+				t.Error("missing Retry-After")
+			}
+		})
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.rateLimiter = newRateLimiter(1)
+	handler := s.rateLimitMiddleware(func(w http.ResponseWriter, r *http.Request) {})
+
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		r := httptest.NewRequest(http.MethodGet, "/threads/posts", nil)
+		r = r.WithContext(logging.WithAPIKeyName(r.Context(), "default"))
+		w := httptest.NewRecorder()
+		handler(w, r)
+		if w.Code != want {
+			t.Fatalf("request %d: status %d, want %d", i, w.Code, want)
+		}
+		if want == http.StatusTooManyRequests && w.Header().Get("Retry-After") != "60" {
+			t.Errorf("Retry-After = %q, want 60", w.Header().Get("Retry-After"))
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/think-root/threads-connector/internal/logging"
+	"github.com/think-root/threads-connector/internal/threads"
+)
+
+// resumeRequest is the body of POST /threads/resume.
+type resumeRequest struct {
+	// ParentID is the last post of the thread that went live
+	ParentID string `json:"parent_id"`
+	// Chunks are the posts still to publish, as returned by POST /threads/split
+	Chunks []string `json:"chunks"`
+	// UserID is the account the thread belongs to, as in postRequest
+	UserID string `json:"user_id"`
+}
+
+// handleResumeThread continues a thread that failed part way, posting the chunks that
+// didn't go live as replies under the last one that did, so nothing is posted twice.
+func (s *Server) handleResumeThread(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context())
+
+	var req resumeRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+	if strings.TrimSpace(req.ParentID) == "" {
+		writeValidationError(w, &validationError{"parent_id", "parent_id is required"})
+		return
+	}
+	if len(req.Chunks) == 0 {
+		writeValidationError(w, &validationError{"chunks", "chunks must contain at least one chunk"})
+		return
+	}
+	client, verr := s.accountClient(req.UserID)
+	if verr != nil {
+		writeValidationError(w, verr)
+		return
+	}
+	if s.bannedWords != nil {
+		for _, chunk := range req.Chunks {
+			if term := s.bannedWords.match(chunk); term != "" {
+				writeValidationError(w, &validationError{"chunks", fmt.Sprintf("chunks contains the banned term %q", term)})
+				return
+			}
+		}
+	}
+
+	// A resumed thread can't wait in the queue, since its chunks aren't a post of their own
+	if opensAt, paused := s.postingPaused(); paused {
+		writeError(w, http.StatusConflict, codeOutsidePostingWindow, pausedMessage(opensAt))
+		return
+	}
+
+	logger.Info("Resuming thread", "parent_id", req.ParentID, "chunks", len(req.Chunks))
+
+	result, err := client.ContinueThread(r.Context(), req.ParentID, req.Chunks)
+	if err != nil {
+		logger.Error("Error resuming thread", "parent_id", req.ParentID, "error", err)
+		if errors.Is(err, threads.ErrPostNotFound) {
+			writeValidationError(w, &validationError{"parent_id", "Parent post not found"})
+			return
+		}
+		status, detail := createPostError(err)
+		if detail.Code == codeValidationFailed && detail.Field == "" {
+			detail.Field = "chunks"
+		}
+		writeErrorDetail(w, status, detail)
+		return
+	}
+	logger.Info("Resumed thread", "parent_id", req.ParentID, "post_ids", result.IDs)
+
+	// post_id is the first new post; its permalink leads into the rest of the thread
+	resp := newPostResponse(result, verboseResponse(r))
+	if post, err := client.GetPostContext(r.Context(), result.ID); err != nil {
+		logger.Warn("Error fetching permalink", "post_id", result.ID, "error", err)
+	} else {
+		resp.Permalink = post.Permalink
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
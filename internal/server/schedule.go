@@ -0,0 +1,113 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/think-root/threads-connector/internal/logging"
+	"github.com/think-root/threads-connector/internal/scheduler"
+)
+
+type scheduleRequest struct {
+	postRequest
+	PublishAt string `json:"publish_at"`
+}
+
+type scheduledJobResponse struct {
+	ID        string    `json:"id"`
+	PublishAt time.Time `json:"publish_at"`
+	CreatedAt time.Time `json:"created_at"`
+	Text      string    `json:"text"`
+	ImageURL  string    `json:"image_url,omitempty"`
+	ImageURLs []string  `json:"image_urls,omitempty"`
+	VideoURL  string    `json:"video_url,omitempty"`
+	URL       string    `json:"url,omitempty"`
+}
+
+func newScheduledJobResponse(job scheduler.Job) scheduledJobResponse {
+	return scheduledJobResponse{
+		ID:        job.ID,
+		PublishAt: job.PublishAt,
+		CreatedAt: job.CreatedAt,
+		Text:      job.Params.Text,
+		ImageURL:  job.Params.ImageURL,
+		ImageURLs: job.Params.ImageURLs,
+		VideoURL:  job.Params.VideoURL,
+		URL:       job.Params.URL,
+	}
+}
+
+func (s *Server) handleSchedulePost(w http.ResponseWriter, r *http.Request) {
+	var req scheduleRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	publishAt, err := time.Parse(time.RFC3339, req.PublishAt)
+	if err != nil {
+		writeValidationError(w, &validationError{"publish_at", "publish_at must be an RFC3339 timestamp"})
+		return
+	}
+	if !publishAt.After(time.Now()) {
+		writeValidationError(w, &validationError{"publish_at", "publish_at must be in the future"})
+		return
+	}
+
+	params, verr := s.postParams(r.Context(), req.postRequest)
+	if verr != nil {
+		writeValidationError(w, verr)
+		return
+	}
+	if client, verr := s.accountClient(req.UserID); verr != nil {
+		writeValidationError(w, verr)
+		return
+	} else if client != s.Client {
+		writeValidationError(w, &validationError{"user_id", scheduledAccountMessage})
+		return
+	}
+
+	// Catch bad media URLs now rather than when the post is due
+	if err := s.Client.ValidateMedia(r.Context(), params); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, codeInvalidMediaURL, err.Error())
+		return
+	}
+
+	job, err := s.Scheduler.Add(publishAt, params)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error scheduling post", "error", err)
+		writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to schedule post: %v", err))
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("Scheduled post", "job_id", job.ID, "publish_at", publishAt.Format(time.RFC3339))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newScheduledJobResponse(*job))
+}
+
+func (s *Server) handleListScheduled(w http.ResponseWriter, r *http.Request) {
+	jobs := s.Scheduler.List()
+
+	resp := make([]scheduledJobResponse, 0, len(jobs))
+	for _, job := range jobs {
+		resp = append(resp, newScheduledJobResponse(job))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) handleCancelScheduled(w http.ResponseWriter, r *http.Request) {
+	jobID := r.PathValue("id")
+
+	if !s.Scheduler.Cancel(jobID) {
+		writeError(w, http.StatusNotFound, codeNotFound, "Scheduled post not found")
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("Canceled scheduled post", "job_id", jobID)
+	w.WriteHeader(http.StatusNoContent)
+}
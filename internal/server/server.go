@@ -1,108 +1,878 @@
 package server
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"mime"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/think-root/threads-connector/internal/config"
+	"github.com/think-root/threads-connector/internal/logging"
+	"github.com/think-root/threads-connector/internal/metrics"
+	"github.com/think-root/threads-connector/internal/scheduler"
+	"github.com/think-root/threads-connector/internal/storage"
 	"github.com/think-root/threads-connector/internal/threads"
+	"github.com/think-root/threads-connector/internal/version"
 )
 
 type Server struct {
 	Config *config.Config
+	// Client posts as THREADS_USER_ID, the default account
 	Client *threads.Client
+	// Accounts holds a client for each of THREADS_ACCOUNTS by user ID; requests pick
+	// one with user_id
+	Accounts  map[string]*threads.Client
+	Scheduler *scheduler.Scheduler
+
+	idempotency *idempotencyStore
+	tokenStatus tokenStatusCache
+	jobs        *jobStore
+	rateLimiter *rateLimiter
+	uploader    *storage.S3
+	bannedWords *wordFilter
+	// postingWindows restricts when posts go out; the zero value allows any time
+	postingWindows scheduler.Windows
+	// background tracks work that outlives its request, such as async posts, the
+	// scheduler and job callbacks, so Start can wait for it on shutdown
+	background sync.WaitGroup
 }
 
 func New(cfg *config.Config, client *threads.Client) *Server {
-	return &Server{
-		Config: cfg,
-		Client: client,
+	s := &Server{
+		Config:    cfg,
+		Client:    client,
+		Scheduler: scheduler.New(client.CreatePostContext),
+
+		idempotency: newIdempotencyStore(cfg.IdempotencyTTL),
+		jobs:        newJobStore(),
+	}
+	s.Scheduler.OnComplete = s.completeScheduledJob
+	if cfg.RateLimitPerMinute > 0 {
+		s.rateLimiter = newRateLimiter(cfg.RateLimitPerMinute)
+	}
+	s.uploader = newUploader(s)
+	if len(cfg.BannedWords) > 0 {
+		s.bannedWords = newWordFilter(cfg.BannedWords)
 	}
+	// Config.Validate has already rejected malformed windows
+	s.postingWindows, _ = scheduler.ParseWindows(cfg.PostingWindows, cfg.PostingTimezone)
+	return s
 }
 
-func (s *Server) Start() error {
+// Start serves HTTP until ctx is canceled, then stops accepting connections and waits up
+// to Config.ShutdownTimeout for in-flight requests and background jobs to finish.
+func (s *Server) Start(ctx context.Context) error {
 	mux := http.NewServeMux()
 
 	// Health check - no auth, no logging
 	mux.HandleFunc("/health", s.handleHealth)
+	// Readiness check - verifies the Threads API and token are usable
+	mux.HandleFunc("/ready", s.handleReady)
+	// Build info - no auth so operators can check which build is running
+	mux.HandleFunc("GET /version", s.handleVersion)
+
+	// Prometheus metrics - no auth so scrapers can reach them; METRICS_ADDR moves
+	// them off the public port
+	var metricsServer *http.Server
+	if s.Config.MetricsAddr == "" {
+		mux.Handle("GET /metrics", metrics.Handler())
+	} else {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("GET /metrics", metrics.Handler())
+		metricsServer = &http.Server{
+			Addr:    s.Config.MetricsAddr,
+			Handler: metricsMux,
+		}
+	}
+
+	// Wrap with logging, auth, rate limiting and timeout middleware
+	mux.HandleFunc("/threads/post", s.protected(s.handlePost))
+	mux.HandleFunc("POST /threads/post/upload", s.protected(s.handleUploadPost))
+	mux.HandleFunc("POST /threads/batch", s.protected(s.handleBatchPost))
+	mux.HandleFunc("POST /threads/split", s.protected(s.handleSplit))
+	mux.HandleFunc("POST /threads/resume", s.protected(s.handleResumeThread))
+	mux.HandleFunc("GET /threads/post/{id}", s.protected(s.handleGetPost))
+	mux.HandleFunc("DELETE /threads/post/{id}", s.protected(s.handleDeletePost))
+	mux.HandleFunc("PATCH /threads/post/{id}", s.protected(s.handleEditPost))
+	mux.HandleFunc("GET /threads/post/{id}/insights", s.protected(s.handleGetPostInsights))
+	mux.HandleFunc("GET /threads/posts", s.protected(s.handleListPosts))
+	mux.HandleFunc("GET /threads/insights", s.protected(s.handleGetUserInsights))
+	mux.HandleFunc("GET /threads/locations", s.protected(s.handleSearchLocations))
+	mux.HandleFunc("GET /token/status", s.protected(s.handleTokenStatus))
+	mux.HandleFunc("POST /threads/schedule", s.protected(s.handleSchedulePost))
+	mux.HandleFunc("GET /threads/schedule", s.protected(s.handleListScheduled))
+	mux.HandleFunc("DELETE /threads/schedule/{id}", s.protected(s.handleCancelScheduled))
+	mux.HandleFunc("GET /threads/jobs/{id}", s.protected(s.handleGetJob))
+
+	// Scheduled posts outlive the request that created them
+	s.background.Go(func() { s.Scheduler.Run(ctx) })
+
+	if s.Config.TokenRefreshDays > 0 {
+		go s.refreshTokenPeriodically(ctx, s.Config.TokenRefreshInterval, s.Config.TokenRefreshDays)
+	}
+
+	httpServer := &http.Server{
+		Addr:    fmt.Sprintf(":%s", s.Config.Port),
+		Handler: s.corsMiddleware(mux),
+	}
+
+	serveErr := make(chan error, 2)
+	go func() {
+		slog.Info("Starting server", "port", s.Config.Port, "version", version.Version, "commit", version.Commit)
+		serveErr <- httpServer.ListenAndServe()
+	}()
+	if metricsServer != nil {
+		go func() {
+			slog.Info("Starting metrics server", "addr", metricsServer.Addr)
+			serveErr <- metricsServer.ListenAndServe()
+		}()
+	}
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down server", "grace_period", s.Config.ShutdownTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.Config.ShutdownTimeout)
+	defer cancel()
+
+	if metricsServer != nil {
+		metricsServer.Shutdown(shutdownCtx)
+	}
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("graceful shutdown failed: %w", err)
+	}
+	if err := s.waitBackground(shutdownCtx); err != nil {
+		return fmt.Errorf("graceful shutdown failed: %w", err)
+	}
+
+	slog.Info("Server stopped")
+	return nil
+}
+
+// waitBackground waits for async posts, scheduled posts and job callbacks to finish, or
+// until ctx is done.
+func (s *Server) waitBackground(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.background.Wait()
+		close(done)
+	}()
 
-	// Wrap with logging and auth middleware
-	handler := s.loggingMiddleware(s.authMiddleware(s.handlePost))
-	mux.HandleFunc("/threads/post", handler)
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("background jobs still running: %w", ctx.Err())
+	}
+}
 
-	log.Printf("Starting server on port %s", s.Config.Port)
-	return http.ListenAndServe(fmt.Sprintf(":%s", s.Config.Port), mux)
+// healthResponse is the body of GET /health.
+type healthResponse struct {
+	Status         string               `json:"status"`
+	CircuitBreaker threads.BreakerState `json:"circuit_breaker"`
+	PostsInFlight  int                  `json:"posts_in_flight"`
 }
 
+// handleHealth reports liveness. It stays 200 while the circuit breaker is open, since
+// restarting the process wouldn't bring the Threads API back.
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(healthResponse{
+		Status:         "ok",
+		CircuitBreaker: s.Client.BreakerState(),
+		PostsInFlight:  s.Client.PostsInFlight(),
+	})
+}
+
+// versionResponse is the body of GET /version.
+type versionResponse struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+}
+
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versionResponse{
+		Version:   version.Version,
+		Commit:    version.Commit,
+		BuildDate: version.BuildDate,
+	})
+}
+
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	info, _, err := s.tokenInfo(r.Context())
+	if err != nil {
+		slog.Warn("Readiness check failed", "error", err)
+		writeError(w, http.StatusServiceUnavailable, codeUnavailable, "Threads API unreachable")
+		return
+	}
+	if !info.IsValid {
+		writeError(w, http.StatusServiceUnavailable, codeUnavailable, "Threads access token is invalid")
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
 }
 
+// protected wraps an API handler with logging, authentication and rate limiting.
+func (s *Server) protected(h http.HandlerFunc) http.HandlerFunc {
+	return s.loggingMiddleware(s.authMiddleware(s.rateLimitMiddleware(s.timeoutMiddleware(h))))
+}
+
+// timeoutMiddleware bounds the handler by REQUEST_TIMEOUT. Client calls give up once the
+// deadline passes, and the handler reports 504 through upstreamError.
+func (s *Server) timeoutMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	if s.Config.RequestTimeout <= 0 {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), s.Config.RequestTimeout)
+		defer cancel()
+		next(w, r.WithContext(ctx))
+	}
+}
+
 func (s *Server) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		apiKey := r.Header.Get("X-API-Key")
-		if apiKey == "" || apiKey != s.Config.APIKey {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		name, ok := s.apiKeyName(r.Header.Get("X-API-Key"))
+		if !ok {
+			writeError(w, http.StatusUnauthorized, codeUnauthorized, "Unauthorized")
 			return
 		}
-		next(w, r)
+		// Later log lines name the key so requests can be traced to their consumer
+		next(w, r.WithContext(logging.WithAPIKeyName(r.Context(), name)))
+	}
+}
+
+// apiKeyName returns the name of the configured API key equal to key. Keys are compared
+// as SHA-256 digests in constant time, and every key is checked, so the response time
+// reveals neither how much of a key matched nor its length.
+func (s *Server) apiKeyName(key string) (string, bool) {
+	given := sha256.Sum256([]byte(key))
+
+	var name string
+	found := false
+	for k, n := range s.Config.APIKeys {
+		want := sha256.Sum256([]byte(k))
+		if subtle.ConstantTimeCompare(given[:], want[:]) == 1 {
+			name, found = n, true
+		}
 	}
+	return name, found
 }
 
 type postRequest struct {
-	Text     string `json:"text"`
-	ImageURL string `json:"image_url"`
-	URL      string `json:"url"`
+	Text      string   `json:"text"`
+	ImageURL  string   `json:"image_url"`
+	ImageURLs []string `json:"image_urls"`
+	VideoURL  string   `json:"video_url"`
+	AltText   string   `json:"alt_text"`
+	URL       string   `json:"url"`
+	URLMode   string   `json:"url_mode"`
+
+	ReplyControl string `json:"reply_control"`
+	QuotePostID  string `json:"quote_post_id"`
+	// ReplyToID is a pointer so an explicitly empty value can be rejected
+	ReplyToID *string `json:"reply_to_id"`
+
+	Poll       *pollRequest `json:"poll"`
+	LocationID string       `json:"location_id"`
+	TopicTag   string       `json:"topic_tag"`
+	GIFID      string       `json:"gif_id"`
+
+	// Markdown overrides the MARKDOWN setting for this request
+	Markdown *bool `json:"markdown"`
+	// Truncate overrides the TRUNCATE setting for this request
+	Truncate *bool `json:"truncate"`
+	// UserID posts as one of THREADS_ACCOUNTS instead of THREADS_USER_ID
+	UserID string `json:"user_id"`
+}
+
+// pollRequest is a poll attached to the first post; the post text is the question.
+type pollRequest struct {
+	Options []string `json:"options"`
 }
 
 type postResponse struct {
-	PostID string `json:"post_id"`
+	PostID    string `json:"post_id"`
+	Permalink string `json:"permalink"`
+	// PostIDs lists every post of the thread in order, starting with PostID
+	PostIDs []string `json:"post_ids"`
+	// URLReplyPosted is only reported when the URL was to be posted as a reply
+	URLReplyPosted *bool `json:"url_reply_posted,omitempty"`
+	// Details is only reported when the request asked for ?verbose=true
+	Details *postDetails `json:"details,omitempty"`
+}
+
+// postDetails is the extra information in a verbose post response.
+type postDetails struct {
+	Chunks         int   `json:"chunks"`
+	URLReplyPosted bool  `json:"url_reply_posted"`
+	DurationMS     int64 `json:"duration_ms"`
+}
+
+// newPostResponse describes result, with details if verbose is set. The permalink is
+// left for the caller to fetch.
+func newPostResponse(result *threads.PostResult, verbose bool) postResponse {
+	resp := postResponse{PostID: result.ID, PostIDs: result.IDs, URLReplyPosted: urlReplyPosted(result)}
+	if verbose {
+		resp.Details = &postDetails{
+			Chunks:         result.Chunks,
+			URLReplyPosted: result.URLReplyPosted,
+			DurationMS:     result.Duration.Milliseconds(),
+		}
+	}
+	return resp
+}
+
+// urlReplyPosted reports whether the URL reply of result went out, or nil if there was none.
+func urlReplyPosted(result *threads.PostResult) *bool {
+	if !result.URLReplyAttempted {
+		return nil
+	}
+	return &result.URLReplyPosted
+}
+
+// verboseResponse reports whether the request asked for a verbose response.
+func verboseResponse(r *http.Request) bool {
+	verbose, _ := strconv.ParseBool(r.URL.Query().Get("verbose"))
+	return verbose
+}
+
+// snippet shortens text to its first n characters for logging, cutting between runes
+// so multi-byte characters stay intact.
+func snippet(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n]) + "..."
 }
 
 func (s *Server) handlePost(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed")
 		return
 	}
 
+	logger := logging.FromContext(r.Context())
+
 	var req postRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+
+	params, verr := s.postParams(r.Context(), req)
+	if verr != nil {
+		writeValidationError(w, verr)
+		return
+	}
+	client, verr := s.accountClient(req.UserID)
+	if verr != nil {
+		writeValidationError(w, verr)
 		return
 	}
 
-	// Basic validation: must have text OR image
-	if req.Text == "" && req.ImageURL == "" {
-		http.Error(w, "Content (text or image_url) is required", http.StatusBadRequest)
+	// A retried request with a known Idempotency-Key gets the original response, whether
+	// the post was published, queued or accepted for async publishing
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		id := idempotencyID{apiKey: logging.APIKeyName(r.Context()), key: key}
+		cached, inProgress := s.idempotency.begin(id)
+		if inProgress {
+			writeError(w, http.StatusConflict, codeConflict, "A request with this Idempotency-Key is still in progress")
+			return
+		}
+		if cached != nil {
+			logger.Info("Returning cached result for Idempotency-Key", "idempotency_key", key, "status", cached.status)
+			cached.write(w)
+			return
+		}
+
+		rec := &responseCapture{ResponseWriter: w, status: http.StatusOK}
+		w = rec
+		defer s.idempotency.finish(id, rec)
+	}
+
+	if opensAt, paused := s.postingPaused(); paused {
+		s.holdPost(w, r, client, params, opensAt)
 		return
 	}
 
-	textSnippet := req.Text
-	if len(textSnippet) > 50 {
-		textSnippet = textSnippet[:50] + "..."
+	// Async posts return a job ID right away; the result arrives via callback or polling
+	if async, _ := strconv.ParseBool(r.URL.Query().Get("async")); async {
+		s.startAsyncPost(w, r, client, params)
+		return
 	}
-	log.Printf("Processing post request. Text: %q (len=%d), Image: %v, URL: %s", 
-		textSnippet, len(req.Text), req.ImageURL != "", req.URL)
 
-	postID, err := s.Client.CreatePost(req.Text, req.ImageURL, req.URL)
+	logger.Info("Processing post request",
+		"text", snippet(req.Text, 50),
+		"text_len", len(req.Text),
+		"has_image", req.ImageURL != "",
+		"carousel_items", len(req.ImageURLs),
+		"has_video", req.VideoURL != "",
+		"url", req.URL,
+		"user_id", client.UserID)
+
+	result, err := client.Publish(r.Context(), params)
 	if err != nil {
-		log.Printf("Error creating post: %v", err)
-		http.Error(w, fmt.Sprintf("Failed to create post: %v", err), http.StatusInternalServerError)
+		logger.Error("Error creating post", "error", err)
+		status, detail := createPostError(err)
+		writeErrorDetail(w, status, detail)
 		return
 	}
 
-	log.Printf("Successfully created post: %s", postID)
+	postID := result.ID
+	logger.Info("Successfully created post", "post_id", postID)
+
+	// The post is already published, so a failed permalink lookup only leaves it empty
+	resp := newPostResponse(result, verboseResponse(r))
+	if post, err := client.GetPostContext(r.Context(), postID); err != nil {
+		logger.Warn("Error fetching permalink", "post_id", postID, "error", err)
+	} else {
+		resp.Permalink = post.Permalink
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(postResponse{PostID: postID})
+	json.NewEncoder(w).Encode(resp)
+}
+
+// decodeJSON decodes the request body into v, reading at most MAX_BODY_BYTES. The body
+// must be sent as application/json, and unknown fields are rejected so a misspelled field
+// name isn't silently ignored. On failure it writes the error response and returns false.
+func (s *Server) decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, codeUnsupportedMediaType, "Content-Type must be application/json")
+		return false
+	}
+
+	body := http.MaxBytesReader(w, r.Body, s.Config.MaxBodyBytes)
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, codePayloadTooLarge, fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit))
+			return false
+		}
+		// encoding/json has no typed error for unknown fields
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			field = strings.Trim(field, `"`)
+			writeErrorDetail(w, http.StatusBadRequest, errorDetail{
+				Code:    codeInvalidJSON,
+				Field:   field,
+				Message: fmt.Sprintf("Unknown field %q", field),
+			})
+			return false
+		}
+		writeError(w, http.StatusBadRequest, codeInvalidJSON, "Invalid request body")
+		return false
+	}
+	return true
+}
+
+// postParams validates a post request and converts it into client parameters.
+func (s *Server) postParams(ctx context.Context, req postRequest) (threads.PostParams, *validationError) {
+	req.Text, req.TopicTag = s.postText(req.Text, req.Markdown, req.TopicTag)
+
+	// Basic validation: must have text, media or a URL; a URL on its own becomes the post
+	if req.Text == "" && req.ImageURL == "" && req.VideoURL == "" && len(req.ImageURLs) == 0 && req.URL == "" {
+		return threads.PostParams{}, &validationError{"text", "Content (text, image_url, image_urls, video_url or url) is required"}
+	}
+	mediaFields := 0
+	for _, set := range []bool{req.ImageURL != "", req.VideoURL != "", len(req.ImageURLs) > 0} {
+		if set {
+			mediaFields++
+		}
+	}
+	if mediaFields > 1 {
+		return threads.PostParams{}, &validationError{"image_url", "Only one of image_url, image_urls or video_url can be set"}
+	}
+	if len(req.ImageURLs) > threads.MaxCarouselItems {
+		return threads.PostParams{}, &validationError{"image_urls", fmt.Sprintf("image_urls supports at most %d items", threads.MaxCarouselItems)}
+	}
+
+	urlMode := threads.URLMode(req.URLMode)
+	if urlMode == "" {
+		urlMode = threads.URLMode(s.Config.URLMode)
+	}
+	if !urlMode.Valid() {
+		return threads.PostParams{}, &validationError{"url_mode", fmt.Sprintf("Invalid url_mode %q (expected reply, attachment, prepend, append or none)", urlMode)}
+	}
+	if urlMode == threads.URLModeNone && req.Text == "" && mediaFields == 0 {
+		return threads.PostParams{}, &validationError{"url_mode", "url_mode none leaves nothing to post; add text or media"}
+	}
+
+	replyControl := threads.ReplyControl(req.ReplyControl)
+	if replyControl != "" && !replyControl.Valid() {
+		return threads.PostParams{}, &validationError{"reply_control", fmt.Sprintf("Invalid reply_control %q (expected everyone, accounts_you_follow or mentioned_only)", replyControl)}
+	}
+
+	if hashtags := threads.CountHashtags(req.Text); hashtags > 1 {
+		switch threads.HashtagPolicy(s.Config.HashtagPolicy) {
+		case threads.HashtagPolicyWarn:
+			logging.FromContext(ctx).Warn("Post has more than one hashtag; only the first will be clickable", "hashtags", hashtags)
+		case threads.HashtagPolicyReject:
+			return threads.PostParams{}, &validationError{"text", fmt.Sprintf("text has %d hashtags, but Threads only supports one per post", hashtags)}
+		}
+	}
+
+	if verr := s.checkBannedWords(req); verr != nil {
+		return threads.PostParams{}, verr
+	}
+
+	if req.GIFID != "" {
+		if req.Text == "" {
+			return threads.PostParams{}, &validationError{"text", "A GIF needs text to go with it"}
+		}
+		if mediaFields > 0 {
+			return threads.PostParams{}, &validationError{"gif_id", "gif_id cannot be combined with image_url, image_urls or video_url"}
+		}
+	}
+
+	if req.TopicTag != "" {
+		if err := threads.ValidateTopicTag(req.TopicTag); err != nil {
+			return threads.PostParams{}, &validationError{"topic_tag", fmt.Sprintf("Invalid topic_tag: %v", err)}
+		}
+	}
+
+	var replyToID string
+	if req.ReplyToID != nil {
+		replyToID = strings.TrimSpace(*req.ReplyToID)
+		if replyToID == "" {
+			return threads.PostParams{}, &validationError{"reply_to_id", "reply_to_id must not be empty"}
+		}
+	}
+
+	var pollOptions []string
+	if req.Poll != nil {
+		if len(req.Poll.Options) < threads.MinPollOptions || len(req.Poll.Options) > threads.MaxPollOptions {
+			return threads.PostParams{}, &validationError{"poll.options", fmt.Sprintf("poll.options must have %d to %d options", threads.MinPollOptions, threads.MaxPollOptions)}
+		}
+		for _, option := range req.Poll.Options {
+			if strings.TrimSpace(option) == "" {
+				return threads.PostParams{}, &validationError{"poll.options", "poll.options must not contain empty options"}
+			}
+		}
+		if req.Text == "" {
+			return threads.PostParams{}, &validationError{"text", "A poll needs text for its question"}
+		}
+		if mediaFields > 0 {
+			return threads.PostParams{}, &validationError{"poll", "A poll cannot be combined with media"}
+		}
+		pollOptions = req.Poll.Options
+	}
+
+	// Text posts without media of their own carry the configured default image; it
+	// can't go with a GIF or a poll
+	imageURL := req.ImageURL
+	if s.Config.DefaultImageURL != "" && req.Text != "" && mediaFields == 0 && req.GIFID == "" && req.Poll == nil {
+		imageURL = s.Config.DefaultImageURL
+	}
+
+	truncate := s.Config.Truncate
+	if req.Truncate != nil {
+		truncate = *req.Truncate
+	}
+
+	return threads.PostParams{
+		Text:      req.Text,
+		ImageURL:  imageURL,
+		ImageURLs: req.ImageURLs,
+		VideoURL:  req.VideoURL,
+		AltText:   req.AltText,
+		URL:       req.URL,
+		URLMode:   urlMode,
+
+		ReplyControl: replyControl,
+		QuotePostID:  req.QuotePostID,
+		ReplyToID:    replyToID,
+		PollOptions:  pollOptions,
+		LocationID:   req.LocationID,
+		TopicTag:     req.TopicTag,
+		GIFID:        req.GIFID,
+		Truncate:     truncate,
+	}, nil
+}
+
+// postText prepares the text of a post before it is validated and split: Markdown is
+// flattened and, with HASHTAG_TOPIC, a hashtag becomes the topic tag. An explicit
+// topicTag wins over one taken from the text.
+func (s *Server) postText(text string, markdown *bool, topicTag string) (string, string) {
+	text = s.plainText(text, markdown)
+	if mode := threads.HashtagTopic(s.Config.HashtagTopic); mode != threads.HashtagTopicOff && topicTag == "" {
+		if stripped, topic := threads.HashtagToTopic(text, mode == threads.HashtagTopicAll); topic != "" && stripped != "" {
+			return stripped, topic
+		}
+	}
+	return text, topicTag
+}
+
+// plainText flattens Markdown in text when the request's markdown flag, or MARKDOWN if
+// the request doesn't set it, asks for it. Threads would show the markup literally.
+func (s *Server) plainText(text string, markdown *bool) string {
+	enabled := s.Config.Markdown
+	if markdown != nil {
+		enabled = *markdown
+	}
+	if !enabled {
+		return text
+	}
+	return threads.MarkdownToText(text)
+}
+
+func (s *Server) handleGetPost(w http.ResponseWriter, r *http.Request) {
+	postID := r.PathValue("id")
+	client, ok := s.queryAccountClient(w, r)
+	if !ok {
+		return
+	}
+
+	post, err := client.GetPostContext(r.Context(), postID)
+	if err != nil {
+		if errors.Is(err, threads.ErrPostNotFound) {
+			writeError(w, http.StatusNotFound, codeNotFound, "Post not found")
+			return
+		}
+		logging.FromContext(r.Context()).Error("Error getting post", "post_id", postID, "error", err)
+		writeUpstreamError(w, err, "get post")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(post)
+}
+
+// Page sizes accepted by GET /threads/posts.
+const (
+	defaultListLimit = 25
+	maxListLimit     = 100
+)
+
+type listPostsResponse struct {
+	Posts      []threads.Post `json:"posts"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+func (s *Server) handleListPosts(w http.ResponseWriter, r *http.Request) {
+	client, ok := s.queryAccountClient(w, r)
+	if !ok {
+		return
+	}
+
+	limit := defaultListLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxListLimit {
+			writeValidationError(w, &validationError{"limit", fmt.Sprintf("limit must be between 1 and %d", maxListLimit)})
+			return
+		}
+		limit = parsed
+	}
+
+	posts, nextCursor, err := client.ListPostsContext(r.Context(), limit, r.URL.Query().Get("cursor"))
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error listing posts", "error", err)
+		writeUpstreamError(w, err, "list posts")
+		return
+	}
+	if posts == nil {
+		posts = []threads.Post{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(listPostsResponse{Posts: posts, NextCursor: nextCursor})
+}
+
+func (s *Server) handleGetPostInsights(w http.ResponseWriter, r *http.Request) {
+	postID := r.PathValue("id")
+	client, ok := s.queryAccountClient(w, r)
+	if !ok {
+		return
+	}
+
+	insights, err := client.GetPostInsightsContext(r.Context(), postID)
+	if err != nil {
+		if errors.Is(err, threads.ErrPostNotFound) {
+			writeError(w, http.StatusNotFound, codeNotFound, "Post not found")
+			return
+		}
+		logging.FromContext(r.Context()).Error("Error getting post insights", "post_id", postID, "error", err)
+		writeUpstreamError(w, err, "get post insights")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(insights)
+}
+
+func (s *Server) handleGetUserInsights(w http.ResponseWriter, r *http.Request) {
+	client, ok := s.queryAccountClient(w, r)
+	if !ok {
+		return
+	}
+
+	var since, until time.Time
+	for _, param := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &since}, {"until", &until}} {
+		value := r.URL.Query().Get(param.name)
+		if value == "" {
+			continue
+		}
+		t, err := parseDateParam(value)
+		if err != nil {
+			writeValidationError(w, &validationError{param.name, param.name + " must be a date (2006-01-02) or an RFC3339 timestamp"})
+			return
+		}
+		*param.dst = t
+	}
+	if !since.IsZero() && !until.IsZero() && until.Before(since) {
+		writeValidationError(w, &validationError{"until", "until must not be before since"})
+		return
+	}
+
+	insights, err := client.GetUserInsightsContext(r.Context(), since, until)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error getting user insights", "error", err)
+		writeUpstreamError(w, err, "get user insights")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(insights)
+}
+
+// parseDateParam accepts a plain date (midnight UTC) or an RFC3339 timestamp.
+func parseDateParam(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// handleEditPost answers edit attempts explicitly: the Threads API has no endpoint for
+// editing a published post, so clients would otherwise only see 405.
+func (s *Server) handleEditPost(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotImplemented, codeNotImplemented, "Threads doesn't support editing published posts through the API; delete the post and publish it again")
+}
+
+func (s *Server) handleDeletePost(w http.ResponseWriter, r *http.Request) {
+	postID := r.PathValue("id")
+
+	if err := s.Client.DeletePostContext(r.Context(), postID); err != nil {
+		if errors.Is(err, threads.ErrPostNotFound) {
+			writeError(w, http.StatusNotFound, codeNotFound, "Post not found")
+			return
+		}
+		logging.FromContext(r.Context()).Error("Error deleting post", "post_id", postID, "error", err)
+		writeUpstreamError(w, err, "delete post")
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("Successfully deleted post", "post_id", postID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleSearchLocations(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeValidationError(w, &validationError{"q", "Query parameter q is required"})
+		return
+	}
+
+	locations, err := s.Client.SearchLocationsContext(r.Context(), query)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error searching locations", "query", query, "error", err)
+		writeUpstreamError(w, err, "search locations")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(locations)
+}
+
+// splitRequest is the body of POST /threads/split.
+type splitRequest struct {
+	Text     string `json:"text"`
+	Markdown *bool  `json:"markdown"`
+	TopicTag string `json:"topic_tag"`
+}
+
+// splitResponse lists the posts a text would be published as.
+type splitResponse struct {
+	Count  int      `json:"count"`
+	Chunks []string `json:"chunks"`
+	// TopicTag is the topic the post would get, e.g. one taken from the text with HASHTAG_TOPIC
+	TopicTag string `json:"topic_tag,omitempty"`
+}
+
+func (s *Server) handleSplit(w http.ResponseWriter, r *http.Request) {
+	var req splitRequest
+	if !s.decodeJSON(w, r, &req) {
+		return
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		writeValidationError(w, &validationError{"text", "Text is required"})
+		return
+	}
+
+	text, topicTag := s.postText(req.Text, req.Markdown, req.TopicTag)
+	chunks := s.Client.SplitText(text)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(splitResponse{Count: len(chunks), Chunks: chunks, TopicTag: topicTag})
 }
 
 func (s *Server) loggingMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("Received %s request for %s", r.Method, r.URL.Path)
-		next(w, r)
+		// Keep the ID a gateway in front of us assigned, so logs correlate across systems
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = logging.NewRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		// Tag everything logged while handling the request, including client calls
+		ctx := logging.WithRequestID(r.Context(), id)
+		logging.FromContext(ctx).Info("Received request", "method", r.Method, "path", r.URL.Path)
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r.WithContext(ctx))
+		metrics.HTTPRequests.WithLabelValues(r.Pattern, r.Method, strconv.Itoa(rec.status)).Inc()
 	}
 }
+
+// requestIDHeader carries the request ID in both directions.
+const requestIDHeader = "X-Request-ID"
+
+// validRequestID limits incoming request IDs to a safe length and character set, since
+// they end up in every log line of the request.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// statusRecorder remembers the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,1385 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/think-root/threads-connector/internal/config"
+	"github.com/think-root/threads-connector/internal/logging"
+	"github.com/think-root/threads-connector/internal/metrics"
+	"github.com/think-root/threads-connector/internal/scheduler"
+	"github.com/think-root/threads-connector/internal/threads"
+	"github.com/think-root/threads-connector/internal/threads/threadstest"
+	"github.com/think-root/threads-connector/internal/version"
+)
+
+// newTestServer returns a Server posting to a fake Threads API.
+func newTestServer(t *testing.T) (*Server, *threadstest.Server) {
+	t.Helper()
+	api := threadstest.NewServer()
+	t.Cleanup(api.Close)
+	return New(config.Load(), api.Client()), api
+}
+
+// post sends body to handlePost as the API key named apiKey.
+func post(s *Server, target, apiKey, idempotencyKey, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r = r.WithContext(logging.WithAPIKeyName(r.Context(), apiKey))
+	if idempotencyKey != "" {
+		r.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+	w := httptest.NewRecorder()
+	s.handlePost(w, r)
+	return w
+}
+
+func TestHandlePostIdempotencyKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		status int
+	}{
+		{"sync", "/threads/post", http.StatusOK},
+		{"async", "/threads/post?async=true", http.StatusAccepted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, api := newTestServer(t)
+
+			first := post(s, tt.target, "default", "retry-1", `{"text":"hello"}`)
+			if first.Code != tt.status {
+				t.Fatalf("first request: status %d, want %d: %s", first.Code, tt.status, first.Body)
+			}
+			// Let an async post finish so a second one would show up
+			waitForContainers(t, api, 1)
+
+			retry := post(s, tt.target, "default", "retry-1", `{"text":"hello"}`)
+			if retry.Code != tt.status {
+				t.Fatalf("retry: status %d, want %d", retry.Code, tt.status)
+			}
+			if retry.Body.String() != first.Body.String() {
+				t.Errorf("retry body = %s, want %s", retry.Body, first.Body)
+			}
+			if n := len(api.Containers()); n != 1 {
+				t.Errorf("created %d containers, want 1", n)
+			}
+
+			// The same Idempotency-Key from another API key is a new request
+			other := post(s, tt.target, "other", "retry-1", `{"text":"hello"}`)
+			if other.Code != tt.status {
+				t.Fatalf("other API key: status %d, want %d", other.Code, tt.status)
+			}
+			waitForContainers(t, api, 2)
+		})
+	}
+}
+
+func TestHandlePostIdempotencyKeyHeldPost(t *testing.T) {
+	s, api := newTestServer(t)
+	s.Config.OutsideWindow = outsideWindowQueue
+	// A short window a couple of hours away holds the post
+	opens := time.Now().UTC().Add(2 * time.Hour)
+	windows, err := scheduler.ParseWindows(opens.Format("15:04")+"-"+opens.Add(time.Minute).Format("15:04"), "UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.postingWindows = windows
+
+	first := post(s, "/threads/post", "default", "held-1", `{"text":"later"}`)
+	if first.Code != http.StatusAccepted {
+		t.Fatalf("first request: status %d, want %d: %s", first.Code, http.StatusAccepted, first.Body)
+	}
+	retry := post(s, "/threads/post", "default", "held-1", `{"text":"later"}`)
+	if retry.Body.String() != first.Body.String() {
+		t.Errorf("retry body = %s, want %s", retry.Body, first.Body)
+	}
+
+	if n := len(s.Scheduler.List()); n != 1 {
+		t.Errorf("scheduled %d jobs, want 1", n)
+	}
+	if n := len(api.Containers()); n != 0 {
+		t.Errorf("created %d containers, want 0", n)
+	}
+}
+
+func waitForContainers(t *testing.T, api *threadstest.Server, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(api.Containers()) < n || !published(api) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d published containers", n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func published(api *threadstest.Server) bool {
+	for _, c := range api.Containers() {
+		if c.PublishedID == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func TestHandlePostValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		status    int
+		wantCode  string
+		wantField string
+	}{
+		{"reply control", `{"text":"hello","reply_control":"mentioned_only"}`, http.StatusOK, "", ""},
+		{"invalid reply control", `{"text":"hello","reply_control":"followers_only"}`, http.StatusUnprocessableEntity, codeValidationFailed, "reply_control"},
+		{"no content", `{}`, http.StatusUnprocessableEntity, codeValidationFailed, "text"},
+		{"image and video", `{"text":"hello","image_url":"https://example.com/a.jpg","video_url":"https://example.com/a.mp4"}`, http.StatusUnprocessableEntity, codeValidationFailed, "image_url"},
+		{"too many images", `{"text":"hello","image_urls":["` + strings.Repeat(`https://example.com/a.jpg","`, threads.MaxCarouselItems) + `https://example.com/a.jpg"]}`, http.StatusUnprocessableEntity, codeValidationFailed, "image_urls"},
+		{"malformed JSON", `{"text":`, http.StatusBadRequest, codeInvalidJSON, ""},
+		{"wrong type", `{"text":5}`, http.StatusBadRequest, codeInvalidJSON, ""},
+		{"unknown field", `{"txt":"hello"}`, http.StatusBadRequest, codeInvalidJSON, "txt"},
+		{"unknown nested field", `{"text":"Tabs or spaces?","poll":{"choices":["tabs","spaces"]}}`, http.StatusBadRequest, codeInvalidJSON, "choices"},
+		{"reply", `{"text":"hello","reply_to_id":"post-1"}`, http.StatusOK, "", ""},
+		{"empty reply_to_id", `{"text":"hello","reply_to_id":"  "}`, http.StatusUnprocessableEntity, codeValidationFailed, "reply_to_id"},
+		{"poll", `{"text":"Tabs or spaces?","poll":{"options":["tabs","spaces"]}}`, http.StatusOK, "", ""},
+		{"poll with one option", `{"text":"Tabs or spaces?","poll":{"options":["tabs"]}}`, http.StatusUnprocessableEntity, codeValidationFailed, "poll.options"},
+		{"poll with five options", `{"text":"Pick one","poll":{"options":["a","b","c","d","e"]}}`, http.StatusUnprocessableEntity, codeValidationFailed, "poll.options"},
+		{"poll with an empty option", `{"text":"Tabs or spaces?","poll":{"options":["tabs"," "]}}`, http.StatusUnprocessableEntity, codeValidationFailed, "poll.options"},
+		{"poll without a question", `{"url":"https://example.com","poll":{"options":["tabs","spaces"]}}`, http.StatusUnprocessableEntity, codeValidationFailed, "text"},
+		{"poll with media", `{"text":"Cute?","image_url":"https://example.com/a.jpg","poll":{"options":["yes","no"]}}`, http.StatusUnprocessableEntity, codeValidationFailed, "poll"},
+		{"topic tag", `{"text":"hello","topic_tag":"golang"}`, http.StatusOK, "", ""},
+		{"invalid topic tag", `{"text":"hello","topic_tag":"#golang"}`, http.StatusUnprocessableEntity, codeValidationFailed, "topic_tag"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, api := newTestServer(t)
+
+			w := post(s, "/threads/post", "default", "", tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			var resp errorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Error.Code != tt.wantCode || resp.Error.Field != tt.wantField || resp.Error.Message == "" {
+				t.Errorf("error = %+v, want code %q with a message for field %q", resp.Error, tt.wantCode, tt.wantField)
+			}
+			if n := len(api.Containers()); n != 0 {
+				t.Errorf("created %d containers, want none", n)
+			}
+		})
+	}
+}
+
+func TestHandlePostURLMode(t *testing.T) {
+	const link = "https://example.com/article"
+
+	tests := []struct {
+		name   string
+		config threads.URLMode
+		body   string
+		status int
+		// wantPosts counts the URL reply; wantAttachment is the root's link_attachment
+		wantPosts      int
+		wantAttachment string
+	}{
+		{"URL_MODE", threads.URLModeAttachment, `{"text":"read","url":"` + link + `"}`, http.StatusOK, 1, link},
+		{"request overrides URL_MODE", threads.URLModeAttachment, `{"text":"read","url":"` + link + `","url_mode":"reply"}`, http.StatusOK, 2, ""},
+		{"invalid url_mode", threads.URLModeReply, `{"text":"read","url":"` + link + `","url_mode":"sideways"}`, http.StatusUnprocessableEntity, 0, ""},
+		{"URL only", threads.URLModeReply, `{"url":"` + link + `"}`, http.StatusOK, 1, ""},
+		{"URL only as attachment", threads.URLModeAttachment, `{"url":"` + link + `"}`, http.StatusOK, 1, link},
+		{"URL_MODE prepend", threads.URLModePrepend, `{"text":"read","url":"` + link + `"}`, http.StatusOK, 1, ""},
+		{"append", threads.URLModeReply, `{"text":"read","url":"` + link + `","url_mode":"append"}`, http.StatusOK, 1, ""},
+		{"URL_MODE none", threads.URLModeNone, `{"text":"read","url":"` + link + `"}`, http.StatusOK, 1, ""},
+		{"none with an image", threads.URLModeReply, `{"image_url":"https://example.com/a.jpg","url":"` + link + `","url_mode":"none"}`, http.StatusOK, 1, ""},
+		{"nothing left to post", threads.URLModeReply, `{"url":"` + link + `","url_mode":"none"}`, http.StatusUnprocessableEntity, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, api := newTestServer(t)
+			s.Config.URLMode = string(tt.config)
+
+			w := post(s, "/threads/post", "default", "", tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				var resp errorResponse
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatal(err)
+				}
+				if resp.Error.Field != "url_mode" {
+					t.Errorf("error = %+v, want one for url_mode", resp.Error)
+				}
+				return
+			}
+			published := api.Published()
+			if len(published) != tt.wantPosts {
+				t.Fatalf("published %d posts, want %d", len(published), tt.wantPosts)
+			}
+			if got := published[0].Params.Get("link_attachment"); got != tt.wantAttachment {
+				t.Errorf("link_attachment = %q, want %q", got, tt.wantAttachment)
+			}
+		})
+	}
+}
+
+func TestHandlePostPostIDs(t *testing.T) {
+	s, api := newTestServer(t)
+
+	w := post(s, "/threads/post", "default", "", `{"text":"`+strings.Repeat("word ", 150)+`","url":"https://example.com"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var resp postResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	var want []string
+	for _, p := range api.Published() {
+		want = append(want, p.PublishedID)
+	}
+	if len(want) != 3 || !reflect.DeepEqual(resp.PostIDs, want) || resp.PostID != want[0] {
+		t.Errorf("post_id %q and post_ids %q, want %q and the whole thread with its URL reply %q", resp.PostID, resp.PostIDs, want[0], want)
+	}
+}
+
+func TestHandlePostVerbose(t *testing.T) {
+	long := strings.Repeat("word ", 150)
+
+	tests := []struct {
+		name   string
+		target string
+		body   string
+		// want is the expected details, or nil when none should be reported
+		want *postDetails
+	}{
+		{"not asked", "/threads/post", `{"text":"hello"}`, nil},
+		{"verbose false", "/threads/post?verbose=false", `{"text":"hello"}`, nil},
+		{"unparsable", "/threads/post?verbose=loud", `{"text":"hello"}`, nil},
+		{"single post", "/threads/post?verbose=true", `{"text":"hello"}`, &postDetails{Chunks: 1}},
+		{"thread with a URL reply", "/threads/post?verbose=1", `{"text":"` + long + `","url":"https://example.com"}`, &postDetails{Chunks: 2, URLReplyPosted: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t)
+			s.Client.InterPostDelay = 20 * time.Millisecond
+
+			w := post(s, tt.target, "default", "", tt.body)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+			var resp postResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == nil {
+				if resp.Details != nil {
+					t.Errorf("details = %+v, want none", *resp.Details)
+				}
+				return
+			}
+			if resp.Details == nil {
+				t.Fatal("details missing")
+			}
+			if resp.Details.Chunks != tt.want.Chunks || resp.Details.URLReplyPosted != tt.want.URLReplyPosted {
+				t.Errorf("details = %+v, want %d chunks and url_reply_posted %v", *resp.Details, tt.want.Chunks, tt.want.URLReplyPosted)
+			}
+			// Every post after the first waits InterPostDelay
+			if minMS := int64(tt.want.Chunks-1) * 20; resp.Details.DurationMS < minMS {
+				t.Errorf("duration_ms = %d, want at least %d", resp.Details.DurationMS, minMS)
+			}
+		})
+	}
+}
+
+func TestHandlePostURLReplyPosted(t *testing.T) {
+	const link = "https://example.com/article"
+
+	tests := []struct {
+		name      string
+		body      string
+		failReply bool
+		// wantField is whether url_reply_posted is reported, and want its value
+		wantField bool
+		want      bool
+	}{
+		{"posted", `{"text":"read","url":"` + link + `"}`, false, true, true},
+		{"failed", `{"text":"read","url":"` + link + `"}`, true, true, false},
+		{"no URL", `{"text":"read"}`, false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, api := newTestServer(t)
+			api.Fail = func(r *http.Request) *threads.APIError {
+				if tt.failReply && r.Method == http.MethodPost && r.Form.Get("text") == link {
+					return &threads.APIError{StatusCode: http.StatusBadRequest, Message: "Invalid parameter", Code: threads.ErrorCodeInvalidParameter}
+				}
+				return nil
+			}
+
+			w := post(s, "/threads/post", "default", "", tt.body)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+			var resp map[string]any
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			got, ok := resp["url_reply_posted"]
+			if ok != tt.wantField {
+				t.Fatalf("url_reply_posted present %v, want %v", ok, tt.wantField)
+			}
+			if ok && got != tt.want {
+				t.Errorf("url_reply_posted = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashtagPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy threads.HashtagPolicy
+		text   string
+		status int
+	}{
+		{"ignore", threads.HashtagPolicyIgnore, "#go #dev", http.StatusOK},
+		{"warn", threads.HashtagPolicyWarn, "#go #dev", http.StatusOK},
+		{"reject", threads.HashtagPolicyReject, "#go #dev", http.StatusUnprocessableEntity},
+		{"reject with one hashtag", threads.HashtagPolicyReject, "#go and example.com/#dev", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, api := newTestServer(t)
+			s.Config.HashtagPolicy = string(tt.policy)
+
+			w := post(s, "/threads/post", "default", "", `{"text":"`+tt.text+`"}`)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				var resp errorResponse
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatal(err)
+				}
+				if resp.Error.Code != codeValidationFailed || resp.Error.Field != "text" {
+					t.Errorf("error = %+v, want %s for text", resp.Error, codeValidationFailed)
+				}
+				return
+			}
+			if got := api.Published(); len(got) != 1 || got[0].Params.Get("text") != tt.text {
+				t.Errorf("published %v, want one post of %q", got, tt.text)
+			}
+		})
+	}
+}
+
+func TestDefaultImageURL(t *testing.T) {
+	const logo = "https://example.com/logo.png"
+
+	tests := []struct {
+		name         string
+		defaultImage string
+		body         string
+		// wantImage is the image_url of the first post, and later posts must have none
+		wantImage string
+	}{
+		{"text post", logo, `{"text":"` + strings.Repeat("word ", 150) + `"}`, logo},
+		{"not configured", "", `{"text":"hello"}`, ""},
+		{"own image", logo, `{"text":"hello","image_url":"https://example.com/a.jpg"}`, "https://example.com/a.jpg"},
+		{"video", logo, `{"text":"hello","video_url":"https://example.com/a.mp4"}`, ""},
+		{"poll", logo, `{"text":"Tabs or spaces?","poll":{"options":["tabs","spaces"]}}`, ""},
+		{"URL only", logo, `{"url":"https://example.com"}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, api := newTestServer(t)
+			s.Config.DefaultImageURL = tt.defaultImage
+
+			if w := post(s, "/threads/post", "default", "", tt.body); w.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+			for i, p := range api.Published() {
+				want := tt.wantImage
+				if i > 0 {
+					want = ""
+				}
+				if got := p.Params.Get("image_url"); got != want {
+					t.Errorf("post %d image_url = %q, want %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestHandlePostHashtagTopic(t *testing.T) {
+	tests := []struct {
+		name         string
+		hashtagTopic string
+		body         string
+		wantText     string
+		wantTopic    string
+	}{
+		{"off", "off", `{"text":"Release notes #golang"}`, "Release notes #golang", ""},
+		{"first", "first", `{"text":"Release notes #golang #dev"}`, "Release notes #dev", "golang"},
+		{"all", "all", `{"text":"Release notes #golang #dev"}`, "Release notes", "golang"},
+		{"explicit topic wins", "first", `{"text":"Release notes #golang","topic_tag":"go"}`, "Release notes #golang", "go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, api := newTestServer(t)
+			s.Config.HashtagTopic = tt.hashtagTopic
+
+			if w := post(s, "/threads/post", "default", "", tt.body); w.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+			root := api.Published()[0]
+			if got := root.Params.Get("text"); got != tt.wantText {
+				t.Errorf("text = %q, want %q", got, tt.wantText)
+			}
+			if got := root.Params.Get("topic_tag"); got != tt.wantTopic {
+				t.Errorf("topic_tag = %q, want %q", got, tt.wantTopic)
+			}
+		})
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	on, off := true, false
+	tests := []struct {
+		name     string
+		config   bool
+		markdown *bool
+		want     string
+	}{
+		{"off by default", false, nil, "**hi**"},
+		{"MARKDOWN", true, nil, "hi"},
+		{"enabled by the request", false, &on, "hi"},
+		{"disabled by the request", true, &off, "**hi**"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{Config: &config.Config{Markdown: tt.config}}
+			if got := s.plainText("**hi**", tt.markdown); got != tt.want {
+				t.Errorf("plainText = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlePostBodyLimit(t *testing.T) {
+	const limit = 64
+	// bodyOf returns a post request body of exactly n bytes
+	bodyOf := func(n int) string {
+		return `{"text":"` + strings.Repeat("a", n-len(`{"text":""}`)) + `"}`
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		status   int
+		wantCode string
+	}{
+		{"at the limit", bodyOf(limit), http.StatusOK, ""},
+		{"one byte over", bodyOf(limit + 1), http.StatusRequestEntityTooLarge, codePayloadTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, api := newTestServer(t)
+			s.Config.MaxBodyBytes = limit
+
+			w := post(s, "/threads/post", "default", "", tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			if !strings.Contains(w.Body.String(), `"code":"`+tt.wantCode+`"`) {
+				t.Errorf("body = %s, want code %s", w.Body, tt.wantCode)
+			}
+			if n := len(api.Containers()); n != 0 {
+				t.Errorf("created %d containers, want none", n)
+			}
+		})
+	}
+}
+
+func TestContentType(t *testing.T) {
+	tests := []struct {
+		name        string
+		upload      bool
+		contentType string
+		status      int
+	}{
+		{"JSON", false, "application/json", http.StatusOK},
+		{"JSON with charset", false, "application/json; charset=utf-8", http.StatusOK},
+		{"form instead of JSON", false, "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType},
+		{"missing content type", false, "", http.StatusUnsupportedMediaType},
+		{"JSON instead of an upload", true, "application/json", http.StatusUnsupportedMediaType},
+		// Past the content type check, the form is rejected for lacking the image
+		{"multipart upload", true, "multipart/form-data; boundary=x", http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("S3_BUCKET", "uploads")
+			s, api := newTestServer(t)
+
+			target, body, handler := "/threads/post", `{"text":"hello"}`, s.handlePost
+			if tt.upload {
+				target, handler = "/threads/post/upload", s.handleUploadPost
+				if strings.HasPrefix(tt.contentType, "multipart/") {
+					body = "--x\r\nContent-Disposition: form-data; name=\"post\"\r\n\r\n{}\r\n--x--\r\n"
+				}
+			}
+			r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status == http.StatusUnsupportedMediaType {
+				if !strings.Contains(w.Body.String(), `"code":"unsupported_media_type"`) {
+					t.Errorf("body = %s, want code unsupported_media_type", w.Body)
+				}
+				if n := len(api.Containers()); n != 0 {
+					t.Errorf("created %d containers, want none", n)
+				}
+			}
+		})
+	}
+}
+
+func TestHandlePostPermalink(t *testing.T) {
+	tests := []struct {
+		name string
+		// lookupFails fails fetching the published post
+		lookupFails   bool
+		wantPermalink bool
+	}{
+		{"permalink", false, true},
+		{"lookup fails", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, api := newTestServer(t)
+			if tt.lookupFails {
+				api.Fail = func(r *http.Request) *threads.APIError {
+					if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/post-") {
+						return &threads.APIError{StatusCode: http.StatusInternalServerError, Message: "lookup failed"}
+					}
+					return nil
+				}
+			}
+
+			w := post(s, "/threads/post", "default", "", `{"text":"hello"}`)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+			}
+			var resp postResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			published := api.Published()
+			if len(published) != 1 || resp.PostID != published[0].PublishedID {
+				t.Fatalf("post_id = %q, want the one published post of %v", resp.PostID, published)
+			}
+			want := ""
+			if tt.wantPermalink {
+				want = "https://www.threads.net/@test/post/" + resp.PostID
+			}
+			if resp.Permalink != want {
+				t.Errorf("permalink = %q, want %q", resp.Permalink, want)
+			}
+		})
+	}
+}
+
+func TestHandlePostPartialFailure(t *testing.T) {
+	s, api := newTestServer(t)
+	var mu sync.Mutex
+	creates := 0
+	api.Fail = func(r *http.Request) *threads.APIError {
+		if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/threads") {
+			return nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if creates++; creates == 3 {
+			return &threads.APIError{StatusCode: http.StatusBadRequest, Message: "bad", Code: threads.ErrorCodeInvalidParameter}
+		}
+		return nil
+	}
+
+	w := post(s, "/threads/post", "default", "", `{"text":"`+strings.Repeat("word ", 250)+`"}`)
+	var resp errorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	var want []string
+	for _, c := range api.Published() {
+		want = append(want, c.PublishedID)
+	}
+	if len(want) != 2 || !reflect.DeepEqual(resp.Error.PublishedPostIDs, want) {
+		t.Errorf("published_post_ids = %v, want the 2 published posts %v", resp.Error.PublishedPostIDs, want)
+	}
+}
+
+func TestHandleSplit(t *testing.T) {
+	tests := []struct {
+		name         string
+		hashtagTopic string
+		body         string
+		want         splitResponse
+	}{
+		{"plain", "off", `{"text":"Release notes #golang"}`, splitResponse{Count: 1, Chunks: []string{"Release notes #golang"}}},
+		{"hashtag becomes topic", "first", `{"text":"Release notes #golang"}`, splitResponse{Count: 1, Chunks: []string{"Release notes"}, TopicTag: "golang"}},
+		{"explicit topic wins", "first", `{"text":"Release notes #golang","topic_tag":"go"}`, splitResponse{Count: 1, Chunks: []string{"Release notes #golang"}, TopicTag: "go"}},
+		{"markdown", "off", `{"text":"**Release** notes","markdown":true}`, splitResponse{Count: 1, Chunks: []string{"Release notes"}}},
+		{"all hashtags", "all", `{"text":"Release notes #golang #dev"}`, splitResponse{Count: 1, Chunks: []string{"Release notes"}, TopicTag: "golang"}},
+		{"nothing but a hashtag", "first", `{"text":"#golang"}`, splitResponse{Count: 1, Chunks: []string{"#golang"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t)
+			s.Config.HashtagTopic = tt.hashtagTopic
+
+			r := httptest.NewRequest(http.MethodPost, "/threads/split", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			s.handleSplit(w, r)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+
+			var got splitResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleEditPost(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	w := httptest.NewRecorder()
+	s.handleEditPost(w, httptest.NewRequest(http.MethodPatch, "/threads/post/1", strings.NewReader(`{"text":"fixed"}`)))
+	if w.Code != http.StatusNotImplemented {
+		t.Fatalf("status %d, want %d", w.Code, http.StatusNotImplemented)
+	}
+	if !strings.Contains(w.Body.String(), `"code":"not_implemented"`) {
+		t.Errorf("body = %s, want code not_implemented", w.Body)
+	}
+}
+
+func TestHandleDeletePost(t *testing.T) {
+	tests := []struct {
+		name   string
+		postID string
+		status int
+	}{
+		{"published post", "", http.StatusNoContent},
+		{"unknown post", "post-404", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t)
+			postID := tt.postID
+			if postID == "" {
+				var err error
+				if postID, err = s.Client.CreatePost(threads.PostParams{Text: "hello"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			r := httptest.NewRequest(http.MethodDelete, "/threads/post/"+postID, nil)
+			r.SetPathValue("id", postID)
+			w := httptest.NewRecorder()
+			s.handleDeletePost(w, r)
+			if w.Code != tt.status {
+				t.Errorf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+}
+
+// otherUserID is the THREADS_ACCOUNTS entry added by addAccount.
+const otherUserID = "5555"
+
+// addAccount adds otherUserID to s.Accounts, served by a fake Threads API of its own.
+// The fake only knows threadstest.UserID, so requests for otherUserID are rewritten to it.
+func addAccount(t *testing.T, s *Server) *threadstest.Server {
+	t.Helper()
+	api := threadstest.NewServer()
+	t.Cleanup(api.Close)
+	httpClient := api.Server.Client()
+	httpClient.Transport = accountTransport{httpClient.Transport}
+	client := api.Client()
+	client.UserID = otherUserID
+	s.Accounts = map[string]*threads.Client{otherUserID: client}
+	return api
+}
+
+// accountTransport sends requests for otherUserID to threadstest.UserID.
+type accountTransport struct {
+	next http.RoundTripper
+}
+
+func (t accountTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Path = strings.Replace(r.URL.Path, "/"+otherUserID+"/", "/"+threadstest.UserID+"/", 1)
+	return t.next.RoundTrip(r)
+}
+
+func TestHandleGetPost(t *testing.T) {
+	tests := []struct {
+		name string
+		// other publishes the post on the account from addAccount
+		other  bool
+		postID string
+		userID string
+		status int
+	}{
+		{"published post", false, "", "", http.StatusOK},
+		{"other account", true, "", otherUserID, http.StatusOK},
+		{"other account's post without user_id", true, "", "", http.StatusNotFound},
+		{"unknown post", false, "post-404", "", http.StatusNotFound},
+		{"unknown user_id", false, "", "404", http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, api := newTestServer(t)
+			other := addAccount(t, s)
+
+			publisher := api
+			if tt.other {
+				publisher = other
+			}
+			postID, err := publisher.Client().CreatePost(threads.PostParams{Text: "hello"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.postID != "" {
+				postID = tt.postID
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/threads/post/"+postID+"?user_id="+tt.userID, nil)
+			r.SetPathValue("id", postID)
+			w := httptest.NewRecorder()
+			s.handleGetPost(w, r)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var got threads.Post
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != postID || got.Text != "hello" || got.Permalink == "" {
+				t.Errorf("post = %+v, want %s with its text and permalink", got, postID)
+			}
+		})
+	}
+}
+
+func TestHandlePostUserID(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID string
+		status int
+		// wantOther is whether the post went out on the account from addAccount
+		wantOther bool
+	}{
+		{"default account", "", http.StatusOK, false},
+		{"default account by ID", threadstest.UserID, http.StatusOK, false},
+		{"other account", otherUserID, http.StatusOK, true},
+		{"unknown user_id", "404", http.StatusUnprocessableEntity, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, api := newTestServer(t)
+			other := addAccount(t, s)
+
+			w := post(s, "/threads/post", "default", "", `{"text":"hello","user_id":"`+tt.userID+`"}`)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				var resp errorResponse
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatal(err)
+				}
+				if resp.Error.Field != "user_id" {
+					t.Errorf("error = %+v, want one for user_id", resp.Error)
+				}
+				if n := len(api.Published()) + len(other.Published()); n != 0 {
+					t.Errorf("published %d posts, want none", n)
+				}
+				return
+			}
+
+			publisher, idle := api, other
+			if tt.wantOther {
+				publisher, idle = other, api
+			}
+			if n := len(idle.Published()); n != 0 {
+				t.Errorf("published %d posts on the wrong account", n)
+			}
+			published := publisher.Published()
+			if len(published) != 1 {
+				t.Fatalf("published %d posts, want 1", len(published))
+			}
+			var resp postResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			// The permalink is looked up on the account that published the post
+			if resp.PostID != published[0].PublishedID || resp.Permalink == "" {
+				t.Errorf("response = %+v, want post %s with its permalink", resp, published[0].PublishedID)
+			}
+		})
+	}
+}
+
+func TestHandleSchedulePostUserID(t *testing.T) {
+	s, _ := newTestServer(t)
+	addAccount(t, s)
+
+	publishAt := time.Now().Add(time.Hour).Format(time.RFC3339)
+	body := `{"text":"later","publish_at":"` + publishAt + `","user_id":"` + otherUserID + `"}`
+	r := httptest.NewRequest(http.MethodPost, "/threads/schedule", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	s.handleSchedulePost(w, r)
+
+	var resp errorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusUnprocessableEntity || resp.Error.Field != "user_id" || resp.Error.Message != scheduledAccountMessage {
+		t.Errorf("status %d, error %+v, want %d for user_id: %q", w.Code, resp.Error, http.StatusUnprocessableEntity, scheduledAccountMessage)
+	}
+	if n := len(s.Scheduler.List()); n != 0 {
+		t.Errorf("scheduled %d posts, want none", n)
+	}
+}
+
+// listPosts sends GET /threads/posts with query to handleListPosts.
+func TestHandleSearchLocations(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		status  int
+		wantIDs []string
+	}{
+		{"match", "?q=opera", http.StatusOK, []string{"102"}},
+		{"no match", "?q=nowhere", http.StatusOK, []string{}},
+		{"missing q", "", http.StatusUnprocessableEntity, nil},
+		{"blank q", "?q=%20", http.StatusUnprocessableEntity, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, api := newTestServer(t)
+			api.Locations = []threads.Location{{ID: "101", Name: "Kyiv Pechersk Lavra"}, {ID: "102", Name: "Lviv Opera"}}
+
+			w := httptest.NewRecorder()
+			s.handleSearchLocations(w, httptest.NewRequest(http.MethodGet, "/threads/locations"+tt.query, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				var resp errorResponse
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatal(err)
+				}
+				if resp.Error.Field != "q" {
+					t.Errorf("error field = %q, want q", resp.Error.Field)
+				}
+				return
+			}
+			var locations []threads.Location
+			if err := json.NewDecoder(w.Body).Decode(&locations); err != nil {
+				t.Fatal(err)
+			}
+			ids := []string{}
+			for _, l := range locations {
+				ids = append(ids, l.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("found %q, want %q", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func listPosts(s *Server, query string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.handleListPosts(w, httptest.NewRequest(http.MethodGet, "/threads/posts"+query, nil))
+	return w
+}
+
+func TestHandleListPosts(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		status    int
+		wantField string
+		wantTexts []string
+		wantMore  bool
+	}{
+		{"all posts", "", http.StatusOK, "", []string{"third", "second", "first"}, false},
+		{"first page", "?limit=2", http.StatusOK, "", []string{"third", "second"}, true},
+		{"other account", "?user_id=" + otherUserID, http.StatusOK, "", []string{"elsewhere"}, false},
+		{"zero limit", "?limit=0", http.StatusUnprocessableEntity, "limit", nil, false},
+		{"limit too high", "?limit=101", http.StatusUnprocessableEntity, "limit", nil, false},
+		{"limit not a number", "?limit=ten", http.StatusUnprocessableEntity, "limit", nil, false},
+		{"unknown user_id", "?user_id=404", http.StatusUnprocessableEntity, "user_id", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t)
+			other := addAccount(t, s)
+			for _, text := range []string{"first", "second", "third"} {
+				if _, err := s.Client.CreatePost(threads.PostParams{Text: text}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := other.Client().CreatePost(threads.PostParams{Text: "elsewhere"}); err != nil {
+				t.Fatal(err)
+			}
+
+			w := listPosts(s, tt.query)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				var resp errorResponse
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatal(err)
+				}
+				if resp.Error.Code != codeValidationFailed || resp.Error.Field != tt.wantField {
+					t.Errorf("error = %+v, want %s for %s", resp.Error, codeValidationFailed, tt.wantField)
+				}
+				return
+			}
+			var resp listPostsResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			var texts []string
+			for _, p := range resp.Posts {
+				texts = append(texts, p.Text)
+			}
+			if !reflect.DeepEqual(texts, tt.wantTexts) {
+				t.Errorf("posts = %q, want %q", texts, tt.wantTexts)
+			}
+			if (resp.NextCursor != "") != tt.wantMore {
+				t.Errorf("next_cursor = %q, want one %v", resp.NextCursor, tt.wantMore)
+			}
+		})
+	}
+}
+
+func TestHandleListPostsPages(t *testing.T) {
+	s, _ := newTestServer(t)
+	for i := range 5 {
+		if _, err := s.Client.CreatePost(threads.PostParams{Text: strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var texts []string
+	query := "?limit=2"
+	for pages := 1; ; pages++ {
+		w := listPosts(s, query)
+		if w.Code != http.StatusOK {
+			t.Fatalf("page %d: status %d: %s", pages, w.Code, w.Body)
+		}
+		var resp listPostsResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range resp.Posts {
+			texts = append(texts, p.Text)
+		}
+		if resp.NextCursor == "" {
+			if pages != 3 {
+				t.Errorf("got %d pages, want 3", pages)
+			}
+			break
+		}
+		if pages == 3 {
+			t.Fatal("third page has a next_cursor")
+		}
+		query = "?limit=2&cursor=" + url.QueryEscape(resp.NextCursor)
+	}
+	if want := []string{"4", "3", "2", "1", "0"}; !reflect.DeepEqual(texts, want) {
+		t.Errorf("posts = %q, want %q", texts, want)
+	}
+}
+
+func TestHandleGetUserInsights(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		status    int
+		wantField string
+		// wantSince and wantUntil are the Unix times sent to the Threads API, "" for none
+		wantSince, wantUntil string
+		wantFollowers        int64
+	}{
+		{"default period", "", http.StatusOK, "", "", "", 120},
+		{"dates", "?since=2025-01-01&until=2025-01-31", http.StatusOK, "", "1735689600", "1738281600", 120},
+		{"timestamps", "?since=2025-01-01T12:00:00%2B02:00", http.StatusOK, "", "1735725600", "", 120},
+		{"other account", "?user_id=" + otherUserID, http.StatusOK, "", "", "", 7},
+		{"invalid since", "?since=yesterday", http.StatusUnprocessableEntity, "since", "", "", 0},
+		{"until before since", "?since=2025-02-01&until=2025-01-01", http.StatusUnprocessableEntity, "until", "", "", 0},
+		{"unknown user_id", "?user_id=404", http.StatusUnprocessableEntity, "user_id", "", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, api := newTestServer(t)
+			other := addAccount(t, s)
+			api.Insights = map[string]int64{"views": 10, "followers_count": 120}
+			other.Insights = map[string]int64{"views": 1, "followers_count": 7}
+
+			var since, until string
+			api.Fail = func(r *http.Request) *threads.APIError {
+				since, until = r.Form.Get("since"), r.Form.Get("until")
+				return nil
+			}
+
+			w := httptest.NewRecorder()
+			s.handleGetUserInsights(w, httptest.NewRequest(http.MethodGet, "/threads/insights"+tt.query, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				var resp errorResponse
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatal(err)
+				}
+				if resp.Error.Field != tt.wantField {
+					t.Errorf("error field = %q, want %q", resp.Error.Field, tt.wantField)
+				}
+				return
+			}
+			var insights threads.UserInsights
+			if err := json.NewDecoder(w.Body).Decode(&insights); err != nil {
+				t.Fatal(err)
+			}
+			if insights.FollowersCount != tt.wantFollowers {
+				t.Errorf("followers_count = %d, want %d", insights.FollowersCount, tt.wantFollowers)
+			}
+			if since != tt.wantSince || until != tt.wantUntil {
+				t.Errorf("sent since %q and until %q, want %q and %q", since, until, tt.wantSince, tt.wantUntil)
+			}
+		})
+	}
+}
+
+func TestHandleGetPostInsights(t *testing.T) {
+	tests := []struct {
+		name string
+		// other publishes the post on the account from addAccount
+		other     bool
+		userID    string
+		status    int
+		wantViews int64
+	}{
+		{"default account", false, "", http.StatusOK, 10},
+		{"default account by ID", false, threadstest.UserID, http.StatusOK, 10},
+		{"other account", true, otherUserID, http.StatusOK, 99},
+		{"other account's post without user_id", true, "", http.StatusNotFound, 0},
+		{"unknown user_id", false, "404", http.StatusUnprocessableEntity, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, api := newTestServer(t)
+			other := addAccount(t, s)
+			api.Insights = map[string]int64{"views": 10, "likes": 2}
+			other.Insights = map[string]int64{"views": 99}
+
+			publisher := api
+			if tt.other {
+				publisher = other
+			}
+			postID, err := publisher.Client().CreatePost(threads.PostParams{Text: "hello"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/threads/post/"+postID+"/insights?user_id="+tt.userID, nil)
+			r.SetPathValue("id", postID)
+			w := httptest.NewRecorder()
+			s.handleGetPostInsights(w, r)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var insights threads.Insights
+			if err := json.NewDecoder(w.Body).Decode(&insights); err != nil {
+				t.Fatal(err)
+			}
+			if insights.Views != tt.wantViews {
+				t.Errorf("views = %d, want %d", insights.Views, tt.wantViews)
+			}
+		})
+	}
+}
+
+func TestHandleVersion(t *testing.T) {
+	defer func(v, c, d string) { version.Version, version.Commit, version.BuildDate = v, c, d }(version.Version, version.Commit, version.BuildDate)
+	version.Version, version.Commit, version.BuildDate = "v1.2.0", "abc1234", "2025-01-02T03:04:05Z"
+
+	s, _ := newTestServer(t)
+	w := httptest.NewRecorder()
+	s.handleVersion(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var got map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"version": "v1.2.0", "commit": "abc1234", "build_date": "2025-01-02T03:04:05Z"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestHandlePostTooManyPosts(t *testing.T) {
+	s, api := newTestServer(t)
+	s.Client.MaxConcurrentPosts = 1
+	s.Client.RejectWhenBusy = true
+
+	// Hold the first post in container creation while the second arrives
+	entered := make(chan struct{}, 1)
+	gate := make(chan struct{})
+	api.Fail = func(r *http.Request) *threads.APIError {
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/threads") && r.Form.Get("text") == "first" {
+			entered <- struct{}{}
+			<-gate
+		}
+		return nil
+	}
+	done := make(chan *httptest.ResponseRecorder, 1)
+	go func() { done <- post(s, "/threads/post", "default", "", `{"text":"first"}`) }()
+	<-entered
+
+	w := httptest.NewRecorder()
+	s.handleHealth(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	var health healthResponse
+	if err := json.NewDecoder(w.Body).Decode(&health); err != nil {
+		t.Fatal(err)
+	}
+	if health.PostsInFlight != 1 {
+		t.Errorf("posts_in_flight = %d, want 1", health.PostsInFlight)
+	}
+
+	w = post(s, "/threads/post", "default", "", `{"text":"second"}`)
+	var resp errorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusTooManyRequests || resp.Error.Code != codeRateLimited {
+		t.Errorf("second post = %d %q, want %d %q", w.Code, resp.Error.Code, http.StatusTooManyRequests, codeRateLimited)
+	}
+
+	close(gate)
+	if w := <-done; w.Code != http.StatusOK {
+		t.Errorf("first post = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+}
+
+func TestHTTPRequestsMetric(t *testing.T) {
+	s, _ := newTestServer(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /items/{id}", s.loggingMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "missing" {
+			http.NotFound(w, r)
+		}
+	}))
+
+	tests := []struct {
+		target string
+		status string
+	}{
+		{"/items/1", "200"},
+		{"/items/missing", "404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			// Requests are counted by route pattern, not by path
+			counter := metrics.HTTPRequests.WithLabelValues("GET /items/{id}", http.MethodGet, tt.status)
+			before := testutil.ToFloat64(counter)
+
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if got := testutil.ToFloat64(counter) - before; got != 1 {
+				t.Errorf("http_requests_total rose by %v, want 1", got)
+			}
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		// wantKept says whether the incoming ID is used rather than a new one
+		wantKept bool
+	}{
+		{"none", "", false},
+		{"kept", "gw-1234:abc.def_9", true},
+		{"too long", strings.Repeat("a", 129), false},
+		{"longest kept", strings.Repeat("a", 128), true},
+		{"unsafe characters", "id\nforged log line", false},
+		{"spaces", "two words", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t)
+			var logged string
+			handler := s.loggingMiddleware(func(w http.ResponseWriter, r *http.Request) {
+				logged = logging.RequestID(r.Context())
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "/threads/posts", nil)
+			if tt.incoming != "" {
+				r.Header.Set("X-Request-ID", tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+
+			echoed := w.Header().Get("X-Request-ID")
+			if echoed == "" || echoed != logged {
+				t.Fatalf("response X-Request-ID %q, logged %q, want the same non-empty ID", echoed, logged)
+			}
+			if kept := echoed == tt.incoming; kept != tt.wantKept {
+				t.Errorf("request ID %q for incoming %q, want it kept %v", echoed, tt.incoming, tt.wantKept)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		status   int
+		wantName string
+	}{
+		{"default key", "secret", http.StatusOK, "default"},
+		{"named key", "abc123", http.StatusOK, "dashboard"},
+		{"unknown key", "abc124", http.StatusUnauthorized, ""},
+		{"no key", "", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t)
+			s.Config.APIKeys = map[string]string{"secret": "default", "abc123": "dashboard"}
+
+			var name string
+			handler := s.authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+				name = logging.APIKeyName(r.Context())
+			})
+			r := httptest.NewRequest(http.MethodGet, "/threads/posts", nil)
+			if tt.key != "" {
+				r.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if name != tt.wantName {
+				t.Errorf("key name %q, want %q", name, tt.wantName)
+			}
+			if tt.status == http.StatusUnauthorized {
+				var resp errorResponse
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatal(err)
+				}
+				if resp.Error.Code != codeUnauthorized {
+					t.Errorf("error code %q, want %q", resp.Error.Code, codeUnauthorized)
+				}
+			}
+		})
+	}
+}
+
+func TestAPIKeyName(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.Config.APIKeys = map[string]string{"abc123": "dashboard", "abc1234567890": "cron", "x": "short"}
+
+	tests := []struct {
+		key      string
+		wantName string
+		wantOK   bool
+	}{
+		{"abc123", "dashboard", true},
+		{"abc1234567890", "cron", true},
+		{"x", "short", true},
+		{"abc", "", false},
+		{"abc1234", "", false},
+		{"ABC123", "", false},
+		{"abc123 ", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		name, ok := s.apiKeyName(tt.key)
+		if name != tt.wantName || ok != tt.wantOK {
+			t.Errorf("apiKeyName(%q) = %q, %v, want %q, %v", tt.key, name, ok, tt.wantName, tt.wantOK)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	tests := []struct {
+		text string
+		n    int
+		want string
+	}{
+		{"hello", 50, "hello"},
+		{"hello world", 5, "hello..."},
+		{"привіт світ", 6, "привіт..."},
+		{"ab😀cd", 3, "ab😀..."},
+		{"", 5, ""},
+	}
+	for _, tt := range tests {
+		if got := snippet(tt.text, tt.n); got != tt.want {
+			t.Errorf("snippet(%q, %d) = %q, want %q", tt.text, tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/think-root/threads-connector/internal/logging"
+	"github.com/think-root/threads-connector/internal/threads"
+)
+
+// tokenStatusCacheTTL keeps /token/status from calling debug_token on every request
+const tokenStatusCacheTTL = time.Minute
+
+// tokenErrorCacheTTL is how long a failed validation or an invalid token is cached, so
+// probes against a broken token don't hammer debug_token while a fixed one shows up soon.
+const tokenErrorCacheTTL = 5 * time.Second
+
+// tokenStatusCache holds the most recent token validation.
+type tokenStatusCache struct {
+	mu        sync.Mutex
+	info      *threads.TokenInfo
+	err       error
+	fetchedAt time.Time
+	// fetching is closed once the validation in flight finishes; nil when there is none
+	fetching chan struct{}
+}
+
+// fresh reports whether the cached result may still be used; c.mu must be held.
+func (c *tokenStatusCache) fresh() bool {
+	switch {
+	case c.info != nil && c.info.IsValid:
+		return time.Since(c.fetchedAt) < tokenStatusCacheTTL
+	case c.info != nil || c.err != nil:
+		return time.Since(c.fetchedAt) < tokenErrorCacheTTL
+	}
+	return false
+}
+
+// reset drops the cached result; c.mu must be held.
+func (c *tokenStatusCache) reset() {
+	c.info = nil
+	c.err = nil
+}
+
+type tokenStatusResponse struct {
+	IsValid       bool   `json:"is_valid"`
+	ExpiresAt     int64  `json:"expires_at"`
+	DaysRemaining int    `json:"days_remaining"`
+	CheckedAt     string `json:"checked_at"`
+}
+
+// tokenInfo returns the cached token info, validating the token again once the cache
+// expires. The lock isn't held during validation; concurrent callers wait for the one
+// validation in flight instead of starting their own.
+func (s *Server) tokenInfo(ctx context.Context) (*threads.TokenInfo, time.Time, error) {
+	cache := &s.tokenStatus
+	for {
+		cache.mu.Lock()
+		if cache.fresh() {
+			info, fetchedAt, err := cache.info, cache.fetchedAt, cache.err
+			cache.mu.Unlock()
+			if err != nil {
+				return nil, time.Time{}, err
+			}
+			return info, fetchedAt, nil
+		}
+		if cache.fetching == nil {
+			break
+		}
+		fetching := cache.fetching
+		cache.mu.Unlock()
+
+		select {
+		case <-fetching:
+		case <-ctx.Done():
+			return nil, time.Time{}, ctx.Err()
+		}
+	}
+	fetching := make(chan struct{})
+	cache.fetching = fetching
+	cache.mu.Unlock()
+
+	info, err := s.Client.ValidateTokenContext(ctx)
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.fetching = nil
+	close(fetching)
+	// The caller giving up says nothing about the token
+	if err != nil && ctx.Err() != nil {
+		return nil, time.Time{}, err
+	}
+	cache.info, cache.err, cache.fetchedAt = info, err, time.Now()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return info, cache.fetchedAt, nil
+}
+
+func (s *Server) handleTokenStatus(w http.ResponseWriter, r *http.Request) {
+	info, checkedAt, err := s.tokenInfo(r.Context())
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error validating access token", "error", err)
+		writeUpstreamError(w, err, "validate token")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokenStatusResponse{
+		IsValid:       info.IsValid,
+		ExpiresAt:     info.ExpiresAt,
+		DaysRemaining: info.DaysRemaining(),
+		CheckedAt:     checkedAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// refreshTokenPeriodically checks the token every interval and refreshes it once fewer
+// than minDays remain, until ctx is canceled.
+func (s *Server) refreshTokenPeriodically(ctx context.Context, interval time.Duration, minDays int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.refreshTokenIfExpiring(minDays)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshTokenIfExpiring refreshes the access token of every account when it expires in
+// fewer than minDays.
+func (s *Server) refreshTokenIfExpiring(minDays int) {
+	s.refreshAccountToken(s.Client, minDays, "THREADS_ACCESS_TOKEN")
+	for _, client := range s.Accounts {
+		s.refreshAccountToken(client, minDays, "THREADS_ACCOUNTS")
+	}
+}
+
+// refreshAccountToken refreshes the access token of client when it expires in fewer than
+// minDays. setting names where the token is configured, for the reminder to update it.
+func (s *Server) refreshAccountToken(client *threads.Client, minDays int, setting string) {
+	logger := slog.With("user_id", client.UserID)
+
+	info, err := client.ValidateToken()
+	if err != nil {
+		logger.Error("Token refresh check failed", "error", err)
+		return
+	}
+	if !info.IsValid || info.ExpiresAt == 0 || info.DaysRemaining() >= minDays {
+		return
+	}
+
+	logger.Info("Threads access token expires soon, refreshing", "days_remaining", info.DaysRemaining())
+
+	_, expiresIn, err := client.RefreshToken()
+	if err != nil {
+		logger.Error("Error refreshing access token", "error", err)
+		return
+	}
+
+	// Drop the cached status so /token/status reports the new expiry
+	if client == s.Client {
+		s.tokenStatus.mu.Lock()
+		s.tokenStatus.reset()
+		s.tokenStatus.mu.Unlock()
+	}
+
+	expiresAt := time.Now().Add(time.Duration(expiresIn) * time.Second)
+	logger.Info("Threads access token refreshed, update "+setting+" before the next restart",
+		"expires", expiresAt.Format("2006-01-02"))
+}
@@ -0,0 +1,222 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/think-root/threads-connector/internal/threads"
+	"github.com/think-root/threads-connector/internal/threads/threadstest"
+)
+
+func TestHandleTokenStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		invalid   bool
+		apiDown   bool
+		status    int
+		wantValid bool
+	}{
+		{"valid token", false, false, http.StatusOK, true},
+		{"invalid token", true, false, http.StatusOK, false},
+		{"debug_token fails", false, true, http.StatusServiceUnavailable, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, api := newTestServer(t)
+
+			var calls atomic.Int32
+			api.TokenInfo = func() threads.TokenInfo {
+				calls.Add(1)
+				return threads.TokenInfo{IsValid: !tt.invalid, ExpiresAt: time.Now().Add(49 * time.Hour).Unix()}
+			}
+			if tt.apiDown {
+				api.Fail = func(r *http.Request) *threads.APIError {
+					calls.Add(1)
+					return &threads.APIError{StatusCode: http.StatusServiceUnavailable, Message: "down", Code: 2}
+				}
+				s.Client.MaxAttempts = 1
+			}
+
+			// The second request is answered from the cache, including a failure
+			for range 2 {
+				w := httptest.NewRecorder()
+				s.handleTokenStatus(w, httptest.NewRequest(http.MethodGet, "/token/status", nil))
+				if w.Code != tt.status {
+					t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+				}
+				if tt.status != http.StatusOK {
+					continue
+				}
+				var got tokenStatusResponse
+				if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+					t.Fatal(err)
+				}
+				if got.IsValid != tt.wantValid || got.DaysRemaining != 2 {
+					t.Errorf("response = %+v, want is_valid %v and 2 days remaining", got, tt.wantValid)
+				}
+			}
+			if n := calls.Load(); n != 1 {
+				t.Errorf("debug_token called %d times, want 1", n)
+			}
+		})
+	}
+}
+
+func TestTokenInfoCache(t *testing.T) {
+	s, api := newTestServer(t)
+
+	var calls atomic.Int32
+	api.TokenInfo = func() threads.TokenInfo {
+		calls.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		return threads.TokenInfo{IsValid: false}
+	}
+
+	// Concurrent callers share one validation
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			if _, _, err := s.tokenInfo(context.Background()); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("debug_token called %d times by concurrent callers, want 1", n)
+	}
+
+	// An invalid token is only cached briefly
+	s.tokenStatus.mu.Lock()
+	s.tokenStatus.fetchedAt = time.Now().Add(-tokenErrorCacheTTL)
+	s.tokenStatus.mu.Unlock()
+	if _, _, err := s.tokenInfo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("debug_token called %d times after the invalid token expired from the cache, want 2", n)
+	}
+
+	// A caller giving up doesn't cache its error
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.tokenStatus.mu.Lock()
+	s.tokenStatus.reset()
+	s.tokenStatus.mu.Unlock()
+	if _, _, err := s.tokenInfo(ctx); err == nil || !strings.Contains(err.Error(), "canceled") {
+		t.Fatalf("tokenInfo with a canceled context = %v, want context canceled", err)
+	}
+	if _, _, err := s.tokenInfo(context.Background()); err != nil {
+		t.Errorf("tokenInfo after a canceled call = %v, want nil", err)
+	}
+}
+
+func TestHandleReady(t *testing.T) {
+	tests := []struct {
+		name    string
+		invalid bool
+		apiDown bool
+		status  int
+	}{
+		{"valid token", false, false, http.StatusOK},
+		{"invalid token", true, false, http.StatusServiceUnavailable},
+		{"Threads API unreachable", false, true, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, api := newTestServer(t)
+			if tt.invalid {
+				api.TokenInfo = func() threads.TokenInfo { return threads.TokenInfo{IsValid: false} }
+			}
+			if tt.apiDown {
+				api.Close()
+				s.Client.MaxAttempts = 1
+			}
+
+			w := httptest.NewRecorder()
+			s.handleReady(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+			if w.Code != tt.status {
+				t.Errorf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+}
+
+func TestRefreshAccountToken(t *testing.T) {
+	tests := []struct {
+		name string
+		info threads.TokenInfo
+		// minDays is TOKEN_REFRESH_DAYS
+		minDays     int
+		wantRefresh bool
+	}{
+		{"expires soon", threads.TokenInfo{IsValid: true, ExpiresAt: time.Now().Add(3 * 24 * time.Hour).Unix()}, 7, true},
+		{"plenty of time left", threads.TokenInfo{IsValid: true, ExpiresAt: time.Now().Add(30 * 24 * time.Hour).Unix()}, 7, false},
+		{"never expires", threads.TokenInfo{IsValid: true}, 7, false},
+		{"invalid token", threads.TokenInfo{IsValid: false, ExpiresAt: time.Now().Add(24 * time.Hour).Unix()}, 7, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, api := newTestServer(t)
+			api.TokenInfo = func() threads.TokenInfo { return tt.info }
+
+			// A cached status would hide the new expiry
+			if _, _, err := s.tokenInfo(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			s.refreshAccountToken(s.Client, tt.minDays, "THREADS_ACCESS_TOKEN")
+
+			refreshed := api.Token() != threadstest.AccessToken
+			if refreshed != tt.wantRefresh {
+				t.Fatalf("refreshed %v, want %v", refreshed, tt.wantRefresh)
+			}
+			if s.Client.AccessToken() != api.Token() {
+				t.Errorf("client token = %q, want the API's %q", s.Client.AccessToken(), api.Token())
+			}
+			s.tokenStatus.mu.Lock()
+			cached := s.tokenStatus.info != nil
+			s.tokenStatus.mu.Unlock()
+			if cached == tt.wantRefresh {
+				t.Errorf("token status cached %v after refresh %v, want it dropped only on refresh", cached, refreshed)
+			}
+		})
+	}
+}
+
+func TestRefreshTokenPeriodically(t *testing.T) {
+	s, api := newTestServer(t)
+	api.TokenInfo = func() threads.TokenInfo {
+		return threads.TokenInfo{IsValid: true, ExpiresAt: time.Now().Add(24 * time.Hour).Unix()}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.refreshTokenPeriodically(ctx, time.Hour, 7)
+		close(done)
+	}()
+
+	// The first check runs right away rather than after the interval
+	deadline := time.Now().Add(5 * time.Second)
+	for api.Token() == threadstest.AccessToken {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the token to be refreshed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("refreshTokenPeriodically didn't return after its context was canceled")
+	}
+}
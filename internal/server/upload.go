@@ -0,0 +1,178 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/think-root/threads-connector/internal/logging"
+	"github.com/think-root/threads-connector/internal/storage"
+)
+
+// uploadExtensions maps the image types Threads accepts to the extension of the stored object.
+var uploadExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// newUploader returns the S3 uploader configured by the S3_* settings, or nil when
+// S3_BUCKET is empty and uploads are disabled.
+func newUploader(s *Server) *storage.S3 {
+	if s.Config.S3Bucket == "" {
+		return nil
+	}
+
+	endpoint := s.Config.S3Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", s.Config.S3Region)
+	}
+	return &storage.S3{
+		Endpoint:        endpoint,
+		Region:          s.Config.S3Region,
+		Bucket:          s.Config.S3Bucket,
+		AccessKeyID:     s.Config.S3AccessKeyID,
+		SecretAccessKey: s.Config.S3SecretAccessKey,
+		PublicURL:       s.Config.S3PublicURL,
+		HTTPClient:      s.Client.HTTPClient,
+	}
+}
+
+// handleUploadPost publishes a post whose image is uploaded with the request. Threads
+// only fetches media from public URLs, so the file is stored in the S3 bucket first and
+// its URL is posted as image_url. The multipart form has the file in "image" and an
+// optional "post" field holding the other post fields as JSON.
+func (s *Server) handleUploadPost(w http.ResponseWriter, r *http.Request) {
+	if s.uploader == nil {
+		writeError(w, http.StatusServiceUnavailable, codeUnavailable, "Uploads are not configured; set S3_BUCKET")
+		return
+	}
+
+	logger := logging.FromContext(r.Context())
+
+	// Leave room for the post field and the multipart framing around the file
+	r.Body = http.MaxBytesReader(w, r.Body, s.Config.MaxUploadBytes+s.Config.MaxBodyBytes)
+	if err := r.ParseMultipartForm(s.Config.MaxUploadBytes); err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.Is(err, http.ErrNotMultipart):
+			writeError(w, http.StatusUnsupportedMediaType, codeUnsupportedMediaType, "Content-Type must be multipart/form-data")
+		case errors.As(err, &tooLarge):
+			writeError(w, http.StatusRequestEntityTooLarge, codePayloadTooLarge, fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit))
+		default:
+			writeError(w, http.StatusBadRequest, codeInvalidForm, "Invalid multipart form")
+		}
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		writeValidationError(w, &validationError{"image", "An image file is required"})
+		return
+	}
+	defer file.Close()
+
+	if header.Size > s.Config.MaxUploadBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, codePayloadTooLarge, fmt.Sprintf("Image exceeds %d bytes", s.Config.MaxUploadBytes))
+		return
+	}
+	image, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidForm, "Failed to read image")
+		return
+	}
+
+	// The declared type can't be trusted, so sniff the content
+	contentType := http.DetectContentType(image)
+	ext, ok := uploadExtensions[contentType]
+	if !ok {
+		writeValidationError(w, &validationError{"image", fmt.Sprintf("Image must be JPEG or PNG, got %s", contentType)})
+		return
+	}
+
+	var req postRequest
+	if raw := r.FormValue("post"); raw != "" {
+		decoder := json.NewDecoder(strings.NewReader(raw))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			writeErrorDetail(w, http.StatusBadRequest, errorDetail{Code: codeInvalidJSON, Field: "post", Message: fmt.Sprintf("Invalid post field: %v", err)})
+			return
+		}
+	}
+	if req.ImageURL != "" || len(req.ImageURLs) > 0 || req.VideoURL != "" || req.GIFID != "" {
+		writeValidationError(w, &validationError{"post", "The uploaded image is the post's media; image_url, image_urls, video_url and gif_id cannot be set"})
+		return
+	}
+
+	key, err := newUploadKey(ext)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to name upload: %v", err))
+		return
+	}
+
+	// Validate before uploading so rejected requests don't leave objects behind
+	req.ImageURL = s.uploader.URL(key)
+	params, verr := s.postParams(r.Context(), req)
+	if verr != nil {
+		writeValidationError(w, verr)
+		return
+	}
+	client, verr := s.accountClient(req.UserID)
+	if verr != nil {
+		writeValidationError(w, verr)
+		return
+	}
+
+	// A rejected post isn't worth uploading; a queued one needs its image in place
+	opensAt, paused := s.postingPaused()
+	if paused && (s.Config.OutsideWindow != outsideWindowQueue || client != s.Client) {
+		s.holdPost(w, r, client, params, opensAt)
+		return
+	}
+
+	if _, err := s.uploader.Put(r.Context(), key, contentType, image); err != nil {
+		logger.Error("Error uploading image", "key", key, "error", err)
+		writeError(w, http.StatusBadGateway, codeUpstreamError, fmt.Sprintf("Failed to upload image: %v", err))
+		return
+	}
+	logger.Info("Uploaded image", "url", params.ImageURL, "bytes", len(image))
+	if paused {
+		s.holdPost(w, r, client, params, opensAt)
+		return
+	}
+
+	result, err := client.Publish(r.Context(), params)
+	if err != nil {
+		logger.Error("Error creating post", "error", err)
+		status, detail := createPostError(err)
+		writeErrorDetail(w, status, detail)
+		return
+	}
+
+	postID := result.ID
+	logger.Info("Successfully created post", "post_id", postID)
+
+	resp := newPostResponse(result, verboseResponse(r))
+	if post, err := client.GetPostContext(r.Context(), postID); err != nil {
+		logger.Warn("Error fetching permalink", "post_id", postID, "error", err)
+	} else {
+		resp.Permalink = post.Permalink
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// newUploadKey returns a random, unguessable object key with the given extension.
+func newUploadKey(ext string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "uploads/" + hex.EncodeToString(b) + ext, nil
+}
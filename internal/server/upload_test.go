@@ -0,0 +1,117 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/think-root/threads-connector/internal/storage"
+)
+
+// pngImage is the start of a PNG file, enough for content sniffing.
+const pngImage = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+// uploadForm builds a multipart body with image as the "image" file, unless it is
+// empty, and post as the "post" field, unless it is empty.
+func uploadForm(t *testing.T, image, post string) (body *bytes.Buffer, contentType string) {
+	t.Helper()
+	body = &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	if image != "" {
+		fw, err := mw.CreateFormFile("image", "photo.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(fw, image)
+	}
+	if post != "" {
+		mw.WriteField("post", post)
+	}
+	mw.Close()
+	return body, mw.FormDataContentType()
+}
+
+func TestHandleUploadPost(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured bool
+		image      string
+		post       string
+		// plain sends the form as JSON instead of multipart
+		plain     bool
+		status    int
+		wantField string
+	}{
+		{"image only", true, pngImage, "", false, http.StatusOK, ""},
+		{"image with text", true, pngImage, `{"text":"sunset"}`, false, http.StatusOK, ""},
+		{"not configured", false, pngImage, "", false, http.StatusServiceUnavailable, ""},
+		{"not multipart", true, pngImage, "", true, http.StatusUnsupportedMediaType, ""},
+		{"no image", true, "", `{"text":"sunset"}`, false, http.StatusUnprocessableEntity, "image"},
+		{"not an image", true, "just some text", "", false, http.StatusUnprocessableEntity, "image"},
+		{"media in the post", true, pngImage, `{"image_url":"https://example.com/a.jpg"}`, false, http.StatusUnprocessableEntity, "post"},
+		{"invalid post", true, pngImage, `{"txt":"sunset"}`, false, http.StatusBadRequest, "post"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, api := newTestServer(t)
+
+			var mu sync.Mutex
+			var uploaded []string
+			bucket := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				uploaded = append(uploaded, r.URL.Path)
+				mu.Unlock()
+			}))
+			t.Cleanup(bucket.Close)
+			if tt.configured {
+				s.uploader = &storage.S3{Endpoint: bucket.URL, Region: "us-east-1", Bucket: "media", PublicURL: "https://cdn.example.com"}
+			}
+
+			body, contentType := uploadForm(t, tt.image, tt.post)
+			if tt.plain {
+				body, contentType = bytes.NewBufferString(`{"text":"sunset"}`), "application/json"
+			}
+			r := httptest.NewRequest(http.MethodPost, "/threads/post/upload", body)
+			r.Header.Set("Content-Type", contentType)
+			w := httptest.NewRecorder()
+			s.handleUploadPost(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if tt.status != http.StatusOK {
+				var resp errorResponse
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+					t.Fatal(err)
+				}
+				if resp.Error.Field != tt.wantField {
+					t.Errorf("error field = %q, want %q", resp.Error.Field, tt.wantField)
+				}
+				if len(uploaded) != 0 || len(api.Containers()) != 0 {
+					t.Errorf("uploaded %d images and created %d containers, want none", len(uploaded), len(api.Containers()))
+				}
+				return
+			}
+
+			if len(uploaded) != 1 || !strings.HasPrefix(uploaded[0], "/media/uploads/") || !strings.HasSuffix(uploaded[0], ".png") {
+				t.Fatalf("uploaded %q, want one PNG under uploads/", uploaded)
+			}
+			published := api.Published()
+			if len(published) != 1 {
+				t.Fatalf("published %d posts, want 1", len(published))
+			}
+			want := "https://cdn.example.com/" + strings.TrimPrefix(uploaded[0], "/media/")
+			if got := published[0].Params.Get("image_url"); got != want {
+				t.Errorf("image_url = %q, want the uploaded image %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/think-root/threads-connector/internal/logging"
+	"github.com/think-root/threads-connector/internal/threads"
+)
+
+// outsideWindowQueue is the OUTSIDE_WINDOW value that defers posts instead of rejecting them.
+const outsideWindowQueue = "queue"
+
+// postingPaused reports whether POSTING_WINDOWS forbids posting right now and, if so,
+// when the next window opens.
+func (s *Server) postingPaused() (opensAt time.Time, paused bool) {
+	now := time.Now()
+	if s.postingWindows.Open(now) {
+		return time.Time{}, false
+	}
+	return s.postingWindows.NextOpen(now), true
+}
+
+// pausedMessage tells the caller when posting resumes.
+func pausedMessage(opensAt time.Time) string {
+	return fmt.Sprintf("Posting is paused outside POSTING_WINDOWS until %s", opensAt.Format(time.RFC3339))
+}
+
+// holdPost answers a post that arrived outside POSTING_WINDOWS. It is rejected with
+// 409, or with OUTSIDE_WINDOW=queue scheduled for opensAt and accepted with 202 and
+// the scheduled job. Posts as another account than the default are always rejected,
+// since the scheduler can't publish them.
+func (s *Server) holdPost(w http.ResponseWriter, r *http.Request, client *threads.Client, params threads.PostParams, opensAt time.Time) {
+	logger := logging.FromContext(r.Context())
+
+	if s.Config.OutsideWindow != outsideWindowQueue || client != s.Client {
+		logger.Info("Rejected post outside posting windows", "opens_at", opensAt.Format(time.RFC3339))
+		writeError(w, http.StatusConflict, codeOutsidePostingWindow, pausedMessage(opensAt))
+		return
+	}
+
+	job, err := s.Scheduler.Add(opensAt, params)
+	if err != nil {
+		logger.Error("Error queuing post", "error", err)
+		writeError(w, http.StatusInternalServerError, codeInternalError, fmt.Sprintf("Failed to queue post: %v", err))
+		return
+	}
+	logger.Info("Queued post until the posting window opens", "job_id", job.ID, "publish_at", opensAt.Format(time.RFC3339))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(newScheduledJobResponse(*job))
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestS3Put(t *testing.T) {
+	var mu sync.Mutex
+	var last *http.Request
+	var lastBody []byte
+	bucket := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		last, lastBody = r, body
+		mu.Unlock()
+		if r.URL.Path == "/media/denied.png" {
+			http.Error(w, "AccessDenied", http.StatusForbidden)
+		}
+	}))
+	defer bucket.Close()
+
+	s := &S3{Endpoint: bucket.URL + "/", Region: "eu-west-1", Bucket: "media", AccessKeyID: "AKID", SecretAccessKey: "secret"}
+	publicURL, err := s.Put(context.Background(), "uploads/a.png", "image/png", []byte("png data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := bucket.URL + "/media/uploads/a.png"; publicURL != want {
+		t.Errorf("Put = %q, want %q", publicURL, want)
+	}
+
+	mu.Lock()
+	got, gotBody := last, lastBody
+	mu.Unlock()
+	if got.Method != http.MethodPut || got.URL.Path != "/media/uploads/a.png" || string(gotBody) != "png data" {
+		t.Errorf("request %s %s with %q, want PUT /media/uploads/a.png with the body", got.Method, got.URL.Path, gotBody)
+	}
+	if ct := got.Header.Get("Content-Type"); ct != "image/png" {
+		t.Errorf("Content-Type = %q, want image/png", ct)
+	}
+	if h := got.Header.Get("X-Amz-Content-Sha256"); h != sha256Hex([]byte("png data")) {
+		t.Errorf("X-Amz-Content-Sha256 = %q, want the body's hash", h)
+	}
+	date := time.Now().UTC().Format("20060102")
+	wantAuth := "AWS4-HMAC-SHA256 Credential=AKID/" + date + "/eu-west-1/s3/aws4_request, SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, Signature="
+	if auth := got.Header.Get("Authorization"); !strings.HasPrefix(auth, wantAuth) {
+		t.Errorf("Authorization = %q, want it to start with %q", auth, wantAuth)
+	}
+
+	if _, err := s.Put(context.Background(), "denied.png", "image/png", nil); err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("Put refused by the bucket = %v, want an error with its response", err)
+	}
+}
+
+func TestS3Sign(t *testing.T) {
+	s := &S3{Region: "us-east-1", AccessKeyID: "AKID", SecretAccessKey: "secret"}
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	sign := func(key, secret string) string {
+		req := httptest.NewRequest(http.MethodPut, "https://s3.example.com/media/"+key, nil)
+		req.Header.Set("Content-Type", "image/png")
+		signer := *s
+		signer.SecretAccessKey = secret
+		signer.sign(req, []byte("data"), now)
+		if d := req.Header.Get("X-Amz-Date"); d != "20250102T030405Z" {
+			t.Errorf("X-Amz-Date = %q, want 20250102T030405Z", d)
+		}
+		return req.Header.Get("Authorization")
+	}
+
+	first := sign("a.png", "secret")
+	if first != sign("a.png", "secret") {
+		t.Error("signing the same request twice gave different signatures")
+	}
+	if first == sign("b.png", "secret") {
+		t.Error("different keys got the same signature")
+	}
+	if first == sign("a.png", "other") {
+		t.Error("different secrets gave the same signature")
+	}
+}
+
+func TestCanonicalURI(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/media/uploads/a.png", "/media/uploads/a.png"},
+		{"/media/a b.png", "/media/a%20b.png"},
+		{"/media/ї.png", "/media/%D1%97.png"},
+		{"/media/a+b~c_d-e.png", "/media/a%2Bb~c_d-e.png"},
+	}
+	for _, tt := range tests {
+		if got := canonicalURI(&url.URL{Path: tt.path}); got != tt.want {
+			t.Errorf("canonicalURI(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestS3URL(t *testing.T) {
+	tests := []struct {
+		name      string
+		publicURL string
+		want      string
+	}{
+		{"endpoint", "", "https://s3.eu-west-1.amazonaws.com/media/uploads/a.png"},
+		{"public URL", "https://cdn.example.com/", "https://cdn.example.com/uploads/a.png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &S3{Endpoint: "https://s3.eu-west-1.amazonaws.com", Bucket: "media", PublicURL: tt.publicURL}
+			if got := s.URL("uploads/a.png"); got != tt.want {
+				t.Errorf("URL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
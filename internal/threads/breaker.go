@@ -0,0 +1,122 @@
+package threads
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting the Threads API while the circuit
+// breaker is open after repeated failures.
+var ErrCircuitOpen = errors.New("circuit breaker is open: Threads API calls are failing")
+
+// BreakerState is the state of the client's circuit breaker.
+type BreakerState string
+
+const (
+	// BreakerClosed lets every post through
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen fails posts right away until the cooldown has passed
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a single post through to test whether the API has recovered
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// circuitBreaker counts consecutive failed posts. Once BreakerThreshold is reached it
+// opens for BreakerCooldown, then half-opens to let one trial post decide whether to
+// close again or reopen.
+type circuitBreaker struct {
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	open     bool
+	probing  bool
+}
+
+// BreakerState reports the current state of the circuit breaker. It is always
+// BreakerClosed when BreakerThreshold is 0.
+func (c *Client) BreakerState() BreakerState {
+	b := &c.breaker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case !b.open:
+		return BreakerClosed
+	case b.probing || time.Since(b.openedAt) >= c.BreakerCooldown:
+		return BreakerHalfOpen
+	default:
+		return BreakerOpen
+	}
+}
+
+// breakerAllow reports whether a post may go ahead. After the cooldown only one trial
+// post is let through at a time.
+func (c *Client) breakerAllow() error {
+	if c.BreakerThreshold <= 0 {
+		return nil
+	}
+
+	b := &c.breaker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return nil
+	}
+	if b.probing || time.Since(b.openedAt) < c.BreakerCooldown {
+		return ErrCircuitOpen
+	}
+	b.probing = true
+	return nil
+}
+
+// breakerRecord updates the breaker with the outcome of a post that was let through.
+func (c *Client) breakerRecord(err error) {
+	if c.BreakerThreshold <= 0 {
+		return
+	}
+
+	b := &c.breaker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasProbing := b.probing
+	b.probing = false
+
+	if err == nil {
+		b.failures = 0
+		b.open = false
+		return
+	}
+	// A post that was rejected or canceled before proving anything about the API
+	// leaves the breaker as it was; an open one lets the next post probe again
+	if !breakerFailure(err) {
+		return
+	}
+
+	b.failures++
+	if wasProbing || b.failures >= c.BreakerThreshold {
+		b.open = true
+		b.openedAt = time.Now()
+	}
+}
+
+// breakerFailure reports whether err says the Threads API is unhealthy. Rejected
+// input and canceled requests say nothing about the API, so they don't count.
+func breakerFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var apiErr *APIError
+	switch {
+	case errors.Is(err, ErrInvalidPost), errors.Is(err, ErrInvalidMediaURL), errors.Is(err, ErrDuplicateContent):
+		return false
+	case errors.Is(err, context.Canceled):
+		return false
+	case errors.As(err, &apiErr) && apiErr.Code == ErrorCodeInvalidParameter:
+		return false
+	}
+	return true
+}
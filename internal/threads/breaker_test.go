@@ -0,0 +1,134 @@
+package threads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	apiDown := &APIError{StatusCode: http.StatusInternalServerError, Code: 2}
+	invalid := fmt.Errorf("%w: no content", ErrInvalidPost)
+
+	// Each step is one of:
+	//   "ok", "fail", "invalid", "canceled": record a post with that outcome
+	//   "probe": breakerAllow must let a post through
+	//   "blocked": breakerAllow must reject the post
+	//   "cooldown": BreakerCooldown passes
+	outcomes := map[string]error{"ok": nil, "fail": apiDown, "invalid": invalid, "canceled": context.Canceled}
+
+	tests := []struct {
+		name  string
+		steps []string
+		want  []BreakerState
+	}{
+		{"below the threshold", []string{"fail"}, []BreakerState{BreakerClosed}},
+		{"threshold opens", []string{"fail", "fail"}, []BreakerState{BreakerClosed, BreakerOpen}},
+		{"success resets the count", []string{"fail", "ok", "fail"}, []BreakerState{BreakerClosed, BreakerClosed, BreakerClosed}},
+		{
+			"rejected input neither counts nor resets",
+			[]string{"fail", "invalid", "canceled", "fail"},
+			[]BreakerState{BreakerClosed, BreakerClosed, BreakerClosed, BreakerOpen},
+		},
+		{"open rejects posts", []string{"fail", "fail", "blocked"}, []BreakerState{BreakerClosed, BreakerOpen, BreakerOpen}},
+		{
+			"half-open after the cooldown",
+			[]string{"fail", "fail", "cooldown"},
+			[]BreakerState{BreakerClosed, BreakerOpen, BreakerHalfOpen},
+		},
+		{
+			"one probe at a time",
+			[]string{"fail", "fail", "cooldown", "probe", "blocked"},
+			[]BreakerState{BreakerClosed, BreakerOpen, BreakerHalfOpen, BreakerHalfOpen, BreakerHalfOpen},
+		},
+		{
+			"successful probe closes",
+			[]string{"fail", "fail", "cooldown", "probe", "ok"},
+			[]BreakerState{BreakerClosed, BreakerOpen, BreakerHalfOpen, BreakerHalfOpen, BreakerClosed},
+		},
+		{
+			"failed probe reopens",
+			[]string{"fail", "fail", "cooldown", "probe", "fail", "blocked"},
+			[]BreakerState{BreakerClosed, BreakerOpen, BreakerHalfOpen, BreakerHalfOpen, BreakerOpen, BreakerOpen},
+		},
+		{
+			"rejected probe lets the next post probe",
+			[]string{"fail", "fail", "cooldown", "probe", "invalid", "probe", "ok"},
+			[]BreakerState{BreakerClosed, BreakerOpen, BreakerHalfOpen, BreakerHalfOpen, BreakerHalfOpen, BreakerHalfOpen, BreakerClosed},
+		},
+		{
+			"canceled probe doesn't close",
+			[]string{"fail", "fail", "cooldown", "probe", "canceled", "probe", "fail"},
+			[]BreakerState{BreakerClosed, BreakerOpen, BreakerHalfOpen, BreakerHalfOpen, BreakerHalfOpen, BreakerHalfOpen, BreakerOpen},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient("user", "token")
+			c.BreakerThreshold = 2
+			c.BreakerCooldown = time.Hour
+
+			for i, step := range tt.steps {
+				switch step {
+				case "probe":
+					if err := c.breakerAllow(); err != nil {
+						t.Fatalf("step %d: breakerAllow = %v, want nil", i, err)
+					}
+				case "blocked":
+					if err := c.breakerAllow(); !errors.Is(err, ErrCircuitOpen) {
+						t.Fatalf("step %d: breakerAllow = %v, want ErrCircuitOpen", i, err)
+					}
+				case "cooldown":
+					c.breaker.openedAt = c.breaker.openedAt.Add(-c.BreakerCooldown)
+				default:
+					c.breakerRecord(outcomes[step])
+				}
+				if got := c.BreakerState(); got != tt.want[i] {
+					t.Fatalf("after step %d (%s): state %s, want %s", i, step, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	c := NewClient("user", "token")
+	c.BreakerThreshold = 0
+	for range 10 {
+		c.breakerRecord(errors.New("down"))
+	}
+	if err := c.breakerAllow(); err != nil {
+		t.Errorf("breakerAllow = %v, want nil", err)
+	}
+	if got := c.BreakerState(); got != BreakerClosed {
+		t.Errorf("state %s, want %s", got, BreakerClosed)
+	}
+}
+
+func TestBreakerFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"server error", &APIError{StatusCode: http.StatusInternalServerError}, true},
+		{"network", errors.New("connection reset"), true},
+		{"deadline", context.DeadlineExceeded, true},
+		{"invalid post", fmt.Errorf("%w: empty", ErrInvalidPost), false},
+		{"invalid media", ErrInvalidMediaURL, false},
+		{"duplicate", &APIError{Code: ErrorCodeDuplicateContent}, false},
+		{"invalid parameter", &APIError{StatusCode: http.StatusBadRequest, Code: ErrorCodeInvalidParameter}, false},
+		{"canceled", fmt.Errorf("wait: %w", context.Canceled), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := breakerFailure(tt.err); got != tt.want {
+				t.Errorf("breakerFailure(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
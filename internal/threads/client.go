@@ -1,157 +1,784 @@
 package threads
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/think-root/threads-connector/internal/logging"
+	"github.com/think-root/threads-connector/internal/metrics"
+)
+
+var (
+	// ErrInvalidPost is returned when the post parameters are inconsistent or incomplete.
+	ErrInvalidPost = errors.New("invalid post")
+
+	errContainerTimeout = errors.New("timeout waiting for container to be ready")
+	errContainerExpired = errors.New("container expired before publishing")
+	errResponseTooLarge = errors.New("response body too large")
+)
+
+// MaxCarouselItems is the largest number of items the Threads API accepts in a carousel
+const MaxCarouselItems = 20
+
+// MinPollOptions and MaxPollOptions bound the number of options in a poll
+const (
+	MinPollOptions = 2
+	MaxPollOptions = 4
 )
 
 const (
-	baseURL                = "https://graph.threads.net/v1.0"
-	maxCharLimit           = 500
-	containerReadyTimeout  = 30 * time.Second
-	containerCheckInterval = 2 * time.Second
+	graphHost                    = "https://graph.threads.net"
+	apiVersion                   = "v1.0"
+	maxCharLimit                 = 500
+	defaultHTTPTimeout           = 60 * time.Second
+	defaultContainerTimeout      = 30 * time.Second
+	defaultVideoContainerTimeout = 5 * time.Minute
+	defaultContainerPollInterval = 2 * time.Second
+	defaultMaxAttempts           = 3
+	defaultRetryBaseDelay        = 1 * time.Second
+	defaultInterPostDelay        = 1 * time.Second
+	defaultPostDelayJitter       = 0.2
+	defaultURLReplyTimeout       = 30 * time.Second
+	defaultMaxThreadChunks       = 10
+	defaultMaxResponseBytes      = 1 << 20
+	// maxContainerPollInterval caps the growing pause between container status checks
+	maxContainerPollInterval = 10 * time.Second
+	// postAvailablePollInterval is the first pause between checks that a published post
+	// can be fetched; it grows up to maxPostAvailablePollInterval
+	postAvailablePollInterval    = 500 * time.Millisecond
+	maxPostAvailablePollInterval = 4 * time.Second
+	// maxUnknownStatuses is how many unrecognized container statuses in a row are tolerated
+	maxUnknownStatuses = 3
+	// carouselConcurrency is how many carousel items are created and awaited at once
+	carouselConcurrency = 4
 )
 
+// DefaultBaseURL is the versioned Threads Graph API root used by NewClient.
+const DefaultBaseURL = graphHost + "/" + apiVersion
+
+// DefaultUserAgent identifies the connector in outgoing requests unless UserAgent is changed.
+const DefaultUserAgent = "threads-connector"
+
 type Client struct {
-	UserID      string
-	AccessToken string
-	HTTPClient  *http.Client
+	UserID     string
+	HTTPClient *http.Client
+	// BaseURL is the versioned API root, e.g. DefaultBaseURL or a mock server for testing
+	BaseURL string
+	// MaxAttempts is how many times a POST is tried when the API reports a transient error
+	MaxAttempts int
+	// RetryBaseDelay is the backoff before the first retry; it doubles on each further attempt
+	RetryBaseDelay time.Duration
+	// InterPostDelay is the pause between consecutive posts of a thread
+	InterPostDelay time.Duration
+	// InterPostDelays overrides InterPostDelay by position: index i is the pause after
+	// publishing post i of a thread. Later posts fall back to InterPostDelay.
+	InterPostDelays []time.Duration
+	// PostDelayJitter randomizes every pause between posts by up to this fraction in
+	// either direction, so several instances don't hit the API in lockstep
+	PostDelayJitter float64
+	// Rand is the source of delay and retry jitter; nil uses the global source
+	Rand *rand.Rand
+	// URLReplyTimeout bounds the wait for the thread to become retrievable before the URL reply
+	URLReplyTimeout time.Duration
+	// ContainerTimeout bounds the wait for a text or image container to become ready
+	ContainerTimeout time.Duration
+	// VideoContainerTimeout bounds the wait for a video container, which processes much longer
+	VideoContainerTimeout time.Duration
+	// ContainerPollInterval is the pause between container status checks
+	ContainerPollInterval time.Duration
+	// FastPublish publishes text posts right after creating their container and only polls
+	// the container status if the API reports it isn't ready yet
+	FastPublish bool
+	// RecreateExpired replaces a container that expires before it is published with a new
+	// one, once, instead of failing the post
+	RecreateExpired bool
+	// CheckMediaURLs probes media URLs with a HEAD request before posting
+	CheckMediaURLs bool
+	// MaxImageBytes and MaxVideoBytes reject media whose HEAD response reports a larger
+	// Content-Length; 0 means no limit. Only checked with CheckMediaURLs.
+	MaxImageBytes int64
+	MaxVideoBytes int64
+	// NumberChunks appends a " (1/3)" style suffix to each post of an auto-split thread
+	NumberChunks bool
+	// SmartSplit prefers breaking long text at sentence ends instead of the last word that fits
+	SmartSplit bool
+	// MaxThreadChunks rejects text that would split into more posts than this; 0 means no limit
+	MaxThreadChunks int
+	// CheckMentions looks up every @handle in the text before posting and rejects unknown ones
+	CheckMentions bool
+	// Footer is a signature added to the text of every post request, e.g. "— via AutoBot"
+	Footer string
+	// FooterPlacement puts the Footer on the first or the last post of a thread; empty means last
+	FooterPlacement FooterPlacement
+	// MaxResponseBytes caps the size of API response bodies read into memory; 0 means no limit
+	MaxResponseBytes int64
+	// UserAgent is sent with every request so the traffic can be told apart in Meta's logs
+	UserAgent string
+	// AutoLinkPreview attaches the first link found in the text as a preview card when no URL is given
+	AutoLinkPreview bool
+	// MaxConcurrentPosts caps how many posts Publish works on at once; 0 means no limit.
+	// Further posts wait for a free slot, or fail with ErrTooManyPosts if RejectWhenBusy is set.
+	MaxConcurrentPosts int
+	RejectWhenBusy     bool
+	// BreakerThreshold opens the circuit breaker after this many consecutive failed posts;
+	// 0 disables it
+	BreakerThreshold int
+	// BreakerCooldown is how long an open breaker fails posts before letting a trial one through
+	BreakerCooldown time.Duration
+
+	tokenMu     sync.RWMutex
+	accessToken string
+
+	rateMu    sync.Mutex
+	rateLimit RateLimitStatus
+
+	randMu sync.Mutex
+
+	breaker circuitBreaker
+	posts   postLimiter
 }
 
 func NewClient(userID, accessToken string) *Client {
+	return NewClientWithHTTP(userID, accessToken, &http.Client{Timeout: defaultHTTPTimeout})
+}
+
+// NewClientWithHTTP is like NewClient but sends all requests through httpClient, whose
+// Timeout bounds every single API call, including each container status check.
+func NewClientWithHTTP(userID, accessToken string, httpClient *http.Client) *Client {
 	return &Client{
-		UserID:      userID,
-		AccessToken: accessToken,
-		HTTPClient:  &http.Client{Timeout: 60 * time.Second},
+		UserID:                userID,
+		accessToken:           accessToken,
+		HTTPClient:            httpClient,
+		BaseURL:               DefaultBaseURL,
+		UserAgent:             DefaultUserAgent,
+		MaxResponseBytes:      defaultMaxResponseBytes,
+		MaxAttempts:           defaultMaxAttempts,
+		RetryBaseDelay:        defaultRetryBaseDelay,
+		InterPostDelay:        defaultInterPostDelay,
+		PostDelayJitter:       defaultPostDelayJitter,
+		MaxThreadChunks:       defaultMaxThreadChunks,
+		URLReplyTimeout:       defaultURLReplyTimeout,
+		ContainerTimeout:      defaultContainerTimeout,
+		VideoContainerTimeout: defaultVideoContainerTimeout,
+		ContainerPollInterval: defaultContainerPollInterval,
+	}
+}
+
+// get sends an authorized GET request to endpoint through do.
+func (c *Client) get(endpoint string) (*http.Response, error) {
+	return c.getContext(context.Background(), endpoint)
+}
+
+// getContext is like get, with the request bound to ctx.
+func (c *Client) getContext(ctx context.Context, endpoint string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.authorize(req)
+	return c.do(req)
+}
+
+// authorize passes the access token in the Authorization header, which unlike the
+// access_token query parameter doesn't end up in proxy and server access logs.
+func (c *Client) authorize(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+c.AccessToken())
+}
+
+// do sends req with the client's User-Agent. Transport errors quote the request URL,
+// so the access token in it is redacted before the error is returned.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	resp, err := c.HTTPClient.Do(req)
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = logging.Redact(urlErr.URL)
+	}
+	return resp, err
+}
+
+// readBody reads a response body of at most MaxResponseBytes, so a misbehaving
+// endpoint can't exhaust memory with an endless body.
+func (c *Client) readBody(body io.Reader) ([]byte, error) {
+	if c.MaxResponseBytes <= 0 {
+		return io.ReadAll(body)
+	}
+	data, err := io.ReadAll(io.LimitReader(body, c.MaxResponseBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > c.MaxResponseBytes {
+		return nil, fmt.Errorf("%w: more than %d bytes", errResponseTooLarge, c.MaxResponseBytes)
+	}
+	return data, nil
+}
+
+// PostParams describes the content of a post created by CreatePost.
+type PostParams struct {
+	Text     string
+	ImageURL string
+	VideoURL string
+	// AltText describes the image or video for screen readers
+	AltText string
+	// ImageURLs turns the first post into a carousel of 2 to 20 images
+	ImageURLs []string
+	// URL is an external link published according to URLMode
+	URL string
+	// URLMode selects how URL is published; empty means URLModeReply
+	URLMode URLMode
+	// ReplyControl limits who can reply to the root post; replies in the thread inherit it
+	ReplyControl ReplyControl
+	// QuotePostID embeds an existing post in the root post
+	QuotePostID string
+	// ReplyToID publishes the root post as a reply to an existing post, which may belong
+	// to another account; the rest of the thread chains off it as usual
+	ReplyToID string
+	// PollOptions attaches a poll with 2 to 4 options to the root post; its text is the question
+	PollOptions []string
+	// LocationID tags the root post with a place found via SearchLocations
+	LocationID string
+	// TopicTag files the root post under a topic, see ValidateTopicTag
+	TopicTag string
+	// GIFID attaches an animated GIF from Tenor to the root post, which must be a text
+	// post. GIF image URLs are not supported by the API.
+	GIFID string
+	// Truncate cuts text that doesn't fit into one post at a word boundary instead of
+	// splitting it into a thread; URL then follows the "…" as a "read more" link
+	Truncate bool
+}
+
+// ReplyControl is the reply_control setting of a post.
+type ReplyControl string
+
+const (
+	ReplyControlEveryone          ReplyControl = "everyone"
+	ReplyControlAccountsYouFollow ReplyControl = "accounts_you_follow"
+	ReplyControlMentionedOnly     ReplyControl = "mentioned_only"
+)
+
+// Valid reports whether r is a reply_control value accepted by the Threads API.
+func (r ReplyControl) Valid() bool {
+	switch r {
+	case ReplyControlEveryone, ReplyControlAccountsYouFollow, ReplyControlMentionedOnly:
+		return true
+	}
+	return false
+}
+
+// URLMode controls how PostParams.URL is published.
+type URLMode string
+
+const (
+	// URLModeReply posts the URL as a separate reply once the thread is published
+	URLModeReply URLMode = "reply"
+	// URLModeAttachment attaches the URL to the first post as a link preview card
+	URLModeAttachment URLMode = "attachment"
+	// URLModePrepend puts the URL at the start of the text, above a blank line
+	URLModePrepend URLMode = "prepend"
+	// URLModeAppend puts the URL at the end of the text, below a blank line
+	URLModeAppend URLMode = "append"
+	// URLModeNone doesn't publish the URL at all
+	URLModeNone URLMode = "none"
+)
+
+// Valid reports whether m is a known URL mode.
+func (m URLMode) Valid() bool {
+	switch m {
+	case URLModeReply, URLModeAttachment, URLModePrepend, URLModeAppend, URLModeNone:
+		return true
+	}
+	return false
+}
+
+// CreatePost publishes p as a single post or, when the text is too long, as a thread.
+// It is shorthand for CreatePostContext with a background context.
+func (c *Client) CreatePost(p PostParams) (string, error) {
+	return c.CreatePostContext(context.Background(), p)
+}
+
+// CreatePostContext is like CreatePost but aborts as soon as ctx is canceled,
+// including while waiting for containers or between posts.
+func (c *Client) CreatePostContext(ctx context.Context, p PostParams) (string, error) {
+	result, err := c.Publish(ctx, p)
+	if err != nil {
+		return "", err
 	}
+	return result.ID, nil
 }
 
-func (c *Client) CreatePost(text string, imageURL string, externalURL string) (string, error) {
-	chunks := splitText(text, maxCharLimit)
+// PostResult describes a post published by Publish.
+type PostResult struct {
+	// ID is the root post of the thread
+	ID string
+	// IDs lists every published post in thread order, starting with ID and ending with
+	// the URL reply if there was one
+	IDs []string
+	// URLReplyAttempted is set when the URL was to be posted as a reply
+	URLReplyAttempted bool
+	// URLReplyPosted reports whether that reply was published. A failed URL reply
+	// doesn't fail the post, since the thread itself is already live.
+	URLReplyPosted bool
+	// Chunks is the number of posts the text and media were published in, not counting
+	// a URL post or reply
+	Chunks int
+	// Duration is how long publishing took, including waits for containers and between posts
+	Duration time.Duration
+}
+
+// Publish is like CreatePostContext but reports more about the outcome than the root post ID.
+func (c *Client) Publish(ctx context.Context, p PostParams) (*PostResult, error) {
+	return c.publish(ctx, func(ctx context.Context) (*PostResult, error) {
+		return c.createPost(ctx, p)
+	})
+}
+
+// publish runs post under the concurrency limit and circuit breaker, and records
+// its outcome in the metrics.
+func (c *Client) publish(ctx context.Context, post func(context.Context) (*PostResult, error)) (*PostResult, error) {
+	release, err := c.acquirePost(ctx)
+	if err != nil {
+		metrics.PostsFailed.WithLabelValues(errorCategory(err)).Inc()
+		return nil, err
+	}
+	defer release()
+
+	if err := c.breakerAllow(); err != nil {
+		metrics.PostsFailed.WithLabelValues(errorCategory(err)).Inc()
+		return nil, err
+	}
+
+	start := time.Now()
+	result, err := post(ctx)
+	c.breakerRecord(err)
+	if err != nil {
+		metrics.PostsFailed.WithLabelValues(errorCategory(err)).Inc()
+		return nil, err
+	}
+
+	result.Duration = time.Since(start)
+	metrics.PostsCreated.Inc()
+	metrics.PostDuration.Observe(result.Duration.Seconds())
+	return result, nil
+}
 
-	// Note: externalURL will be posted as separate reply at the end
+func (c *Client) createPost(ctx context.Context, p PostParams) (*PostResult, error) {
+	urlMode := p.URLMode
+	if urlMode == "" {
+		urlMode = URLModeReply
+	}
+	if !urlMode.Valid() {
+		return nil, fmt.Errorf("%w: unknown URL mode %q", ErrInvalidPost, urlMode)
+	}
+	readMore := p.URL
+	switch urlMode {
+	case URLModeNone:
+		// In none mode the URL is left out of the post entirely
+		p.URL, readMore = "", ""
+	case URLModePrepend:
+		// Inline URLs become part of the text, where splitting keeps them whole
+		p.Text = strings.TrimSpace(p.URL + "\n\n" + strings.TrimSpace(p.Text))
+		p.URL, readMore = "", ""
+	case URLModeAppend:
+		p.Text = strings.TrimSpace(strings.TrimSpace(p.Text) + "\n\n" + p.URL)
+		p.URL = ""
+	}
 
-	if len(chunks) == 0 && imageURL == "" && externalURL == "" {
-		return "", fmt.Errorf("no content to post")
+	var chunks []string
+	if p.Truncate {
+		var linked bool
+		chunks, linked = c.truncateText(p.Text, readMore)
+		// The URL ends the truncated text as its "read more" link instead of following in a reply
+		if linked && urlMode == URLModeReply {
+			p.URL = ""
+		}
+	} else {
+		chunks = c.chunkText(p.Text)
+	}
+
+	// A single carousel item is just an image post
+	if len(p.ImageURLs) == 1 && p.ImageURL == "" && p.VideoURL == "" {
+		p.ImageURL = p.ImageURLs[0]
+		p.ImageURLs = nil
+	}
+
+	hasMedia := p.ImageURL != "" || p.VideoURL != "" || len(p.ImageURLs) > 0
+	if len(chunks) == 0 && !hasMedia && p.URL == "" {
+		return nil, fmt.Errorf("%w: no content to post", ErrInvalidPost)
+	}
+	if c.MaxThreadChunks > 0 && len(chunks) > c.MaxThreadChunks {
+		return nil, fmt.Errorf("%w: text splits into %d posts, more than the thread limit of %d", ErrInvalidPost, len(chunks), c.MaxThreadChunks)
+	}
+	if p.ImageURL != "" && p.VideoURL != "" {
+		return nil, fmt.Errorf("%w: image and video cannot be combined in one post", ErrInvalidPost)
+	}
+	if len(p.ImageURLs) > 0 && (p.ImageURL != "" || p.VideoURL != "") {
+		return nil, fmt.Errorf("%w: carousel items cannot be combined with a single image or video", ErrInvalidPost)
+	}
+	if len(p.ImageURLs) > MaxCarouselItems {
+		return nil, fmt.Errorf("%w: carousel supports at most %d items, got %d", ErrInvalidPost, MaxCarouselItems, len(p.ImageURLs))
+	}
+
+	if err := c.ValidateMedia(ctx, p); err != nil {
+		return nil, err
+	}
+	if c.CheckMentions {
+		if err := c.ValidateMentionsContext(ctx, p.Text); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.QuotePostID != "" && len(chunks) == 0 && !hasMedia && p.URL == "" {
+		return nil, fmt.Errorf("%w: quote post needs text, media or a URL of its own", ErrInvalidPost)
+	}
+	if p.ReplyControl != "" && !p.ReplyControl.Valid() {
+		return nil, fmt.Errorf("%w: unknown reply control %q", ErrInvalidPost, p.ReplyControl)
+	}
+	if p.GIFID != "" && hasMedia {
+		return nil, fmt.Errorf("%w: a GIF cannot be combined with other media", ErrInvalidPost)
+	}
+	if p.TopicTag != "" {
+		if err := ValidateTopicTag(p.TopicTag); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPost, err)
+		}
+	}
+	if len(p.PollOptions) > 0 {
+		if len(p.PollOptions) < MinPollOptions || len(p.PollOptions) > MaxPollOptions {
+			return nil, fmt.Errorf("%w: poll needs %d to %d options, got %d", ErrInvalidPost, MinPollOptions, MaxPollOptions, len(p.PollOptions))
+		}
+		if len(chunks) == 0 || hasMedia {
+			return nil, fmt.Errorf("%w: poll needs a text post without media", ErrInvalidPost)
+		}
+	}
+	// The Threads API only accepts link_attachment on TEXT containers; image, video and
+	// carousel posts have no way to show a preview card, so they keep the reply
+	if urlMode == URLModeAttachment && hasMedia {
+		logging.FromContext(ctx).Info("Link attachment is not supported on media posts, posting URL as a reply")
+		urlMode = URLModeReply
+	}
+
+	// Without an explicit URL, the first link in the text can provide the preview card
+	var autoLink string
+	if c.AutoLinkPreview && p.URL == "" && !hasMedia {
+		autoLink = FirstURL(p.Text)
+	}
+
+	// Handle case where text was empty but media provided
+	if len(chunks) == 0 && hasMedia {
+		chunks = []string{""}
 	}
 
 	var rootPostID string
-	var previousPostID string
+	var publishedIDs []string
+	previousPostID := p.ReplyToID
 
-	for i, chunk := range chunks {
-		// Use image only for the first chunk
-		currentImageURL := ""
-		if i == 0 {
-			currentImageURL = imageURL
+	// Once part of the thread is live, errors report what was published
+	partial := func(err error) (*PostResult, error) {
+		if len(publishedIDs) == 0 {
+			return nil, err
 		}
+		return nil, &PartialPostError{PublishedIDs: publishedIDs, Err: err}
+	}
 
+	for i, chunk := range chunks {
 		// If it's not the first post, it is a reply to the previous one
-		replyToID := previousPostID
+		params := containerParams{Text: chunk, ReplyToID: previousPostID}
 
-		creationID, err := c.createMediaContainer(chunk, currentImageURL, replyToID, "")
-		if err != nil {
-			return "", fmt.Errorf("failed to create media container for chunk %d: %w", i, err)
-		}
+		// Use media only for the first chunk
+		if i == 0 {
+			params.ImageURL = p.ImageURL
+			params.VideoURL = p.VideoURL
+			params.AltText = p.AltText
+			params.ReplyControl = p.ReplyControl
+			params.QuotePostID = p.QuotePostID
+			params.PollOptions = p.PollOptions
+			params.LocationID = p.LocationID
+			params.TopicTag = p.TopicTag
+			params.GIFID = p.GIFID
+
+			if urlMode == URLModeAttachment {
+				params.LinkAttachment = p.URL
+			}
+			if autoLink != "" {
+				params.LinkAttachment = autoLink
+			}
 
-		// Wait for container to be ready before publishing
-		if err := c.waitForContainerReady(creationID); err != nil {
-			return "", fmt.Errorf("container %d not ready: %w", i, err)
+			if len(p.ImageURLs) > 0 {
+				childIDs, err := c.createCarouselItems(ctx, p.ImageURLs)
+				if err != nil {
+					return nil, err
+				}
+				params.Children = childIDs
+			}
 		}
 
-		publishedID, err := c.publishMediaContainer(creationID)
+		publishedID, err := c.createAndPublish(ctx, params)
 		if err != nil {
-			return "", fmt.Errorf("failed to publish chunk %d: %w", i, err)
+			return partial(fmt.Errorf("chunk %d: %w", i, err))
 		}
 
 		if i == 0 {
 			rootPostID = publishedID
 		}
 		previousPostID = publishedID
+		publishedIDs = append(publishedIDs, publishedID)
 
 		// Delay between posts to ensure order and avoid rate limits
-		time.Sleep(1 * time.Second)
+		if i < len(chunks)-1 {
+			if err := SleepContext(ctx, c.postDelay(c.interPostDelay(i))); err != nil {
+				return partial(err)
+			}
+		}
 	}
 
-	// Handle case where text was empty but imageURL provided
-	if len(chunks) == 0 && imageURL != "" {
-		creationID, err := c.createMediaContainer("", imageURL, "", "")
+	// 3. Post external URL as separate reply for user interaction
+	result := &PostResult{}
+	if p.URL != "" && rootPostID != "" && urlMode == URLModeReply {
+		// Replying to a post that hasn't propagated yet fails, so wait until it can be fetched
+		if err := c.waitForPostAvailable(ctx, previousPostID, c.URLReplyTimeout); err != nil {
+			return partial(err)
+		}
+
+		// The thread itself is live, so a failed URL reply only loses the link
+		result.URLReplyAttempted = true
+		publishedID, err := c.createAndPublish(ctx, containerParams{Text: p.URL, ReplyToID: previousPostID})
 		if err != nil {
-			return "", fmt.Errorf("failed to create media container for image: %w", err)
+			if ctx.Err() != nil {
+				return partial(fmt.Errorf("URL reply: %w", err))
+			}
+			logging.FromContext(ctx).Warn("URL reply failed, returning the published thread", "post_id", rootPostID, "error", err)
+		} else {
+			result.URLReplyPosted = true
+			publishedIDs = append(publishedIDs, publishedID)
+			logging.FromContext(ctx).Info("URL reply published", "post_id", publishedID)
 		}
-		if err := c.waitForContainerReady(creationID); err != nil {
-			return "", fmt.Errorf("image container not ready: %w", err)
+	} else if p.URL != "" && rootPostID == "" {
+		// No parent post, URL is the root post
+		params := containerParams{Text: p.URL, ReplyToID: p.ReplyToID, ReplyControl: p.ReplyControl, QuotePostID: p.QuotePostID, LocationID: p.LocationID, TopicTag: p.TopicTag}
+		if urlMode == URLModeAttachment {
+			params.LinkAttachment = p.URL
 		}
-		publishedID, err := c.publishMediaContainer(creationID)
+
+		publishedID, err := c.createAndPublish(ctx, params)
 		if err != nil {
-			return "", fmt.Errorf("failed to publish image: %w", err)
+			return nil, fmt.Errorf("URL post: %w", err)
 		}
 		rootPostID = publishedID
-		previousPostID = publishedID
+		publishedIDs = append(publishedIDs, publishedID)
 	}
 
-	// 3. Post external URL as separate reply for user interaction
-	if externalURL != "" && previousPostID != "" {
-		// Wait longer to let the parent post propagate in Threads system
-		log.Printf("Waiting 5 seconds before creating URL reply...")
-		time.Sleep(5 * time.Second)
+	result.ID = rootPostID
+	result.IDs = publishedIDs
+	result.Chunks = len(chunks)
+	return result, nil
+}
 
-		replyToID := previousPostID
-		creationID, err := c.createMediaContainer(externalURL, "", replyToID, "")
-		if err != nil {
-			return "", fmt.Errorf("failed to create media container for URL reply: %w", err)
-		}
+// PartialPostError is returned by CreatePost when a thread failed after some of its
+// posts were already published. PublishedIDs lists them in thread order, starting
+// with the root post, so the caller can delete or continue the thread.
+type PartialPostError struct {
+	PublishedIDs []string
+	Err          error
+}
 
-		if err := c.waitForContainerReady(creationID); err != nil {
-			return "", fmt.Errorf("URL container not ready: %w", err)
-		}
+func (e *PartialPostError) Error() string {
+	return fmt.Sprintf("%v (already published: %s)", e.Err, strings.Join(e.PublishedIDs, ", "))
+}
 
-		publishedID, err := c.publishMediaContainer(creationID)
-		if err != nil {
-			return "", fmt.Errorf("failed to publish URL reply: %w", err)
-		}
+func (e *PartialPostError) Unwrap() error {
+	return e.Err
+}
 
-		log.Printf("URL reply published: %s", publishedID)
-	} else if externalURL != "" {
-		// No parent post, URL is the root post
-		creationID, err := c.createMediaContainer(externalURL, "", "", "")
-		if err != nil {
-			return "", fmt.Errorf("failed to create media container for URL: %w", err)
-		}
+// CreateCarousel publishes imageURLs as a single carousel post captioned with text.
+// Text longer than one post continues in replies, as with CreatePost. With fewer than
+// two images it falls back to a normal image or text post.
+func (c *Client) CreateCarousel(text string, imageURLs []string) (string, error) {
+	return c.CreatePost(PostParams{Text: text, ImageURLs: imageURLs})
+}
 
-		if err := c.waitForContainerReady(creationID); err != nil {
-			return "", fmt.Errorf("URL container not ready: %w", err)
-		}
+// createCarouselItems creates a child container for every image and waits until all
+// of them are ready to be referenced by the carousel parent. Up to carouselConcurrency
+// items are processed at once; the returned IDs keep the order of imageURLs. The first
+// failure cancels the items still in progress.
+func (c *Client) createCarouselItems(ctx context.Context, imageURLs []string) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	childIDs := make([]string, len(imageURLs))
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	slots := make(chan struct{}, carouselConcurrency)
+	for i, imageURL := range imageURLs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				return
+			}
 
-		publishedID, err := c.publishMediaContainer(creationID)
+			creationID, err := c.createMediaContainer(ctx, containerParams{ImageURL: imageURL, IsCarouselItem: true})
+			if err != nil {
+				fail(fmt.Errorf("failed to create carousel item %d: %w", i, err))
+				return
+			}
+			if err := c.waitForContainerReady(ctx, creationID, c.ContainerTimeout); err != nil {
+				fail(fmt.Errorf("carousel item %d not ready: %w", i, err))
+				return
+			}
+			childIDs[i] = creationID
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	// Canceled by the caller before any item failed
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return childIDs, nil
+}
+
+// createAndPublish runs the two-step posting process for a single container:
+// create it, wait until it is ready, then publish it. With RecreateExpired an expired
+// container is replaced by a new one, once.
+func (c *Client) createAndPublish(ctx context.Context, params containerParams) (string, error) {
+	if c.FastPublish && params.textOnly() {
+		return c.createAndPublishFast(ctx, params)
+	}
+
+	creationID, err := c.createReadyContainer(ctx, params)
+	if errors.Is(err, errContainerExpired) && c.RecreateExpired {
+		logging.FromContext(ctx).Warn("Container expired, recreating it")
+		creationID, err = c.createReadyContainer(ctx, params)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	publishedID, err := c.publishMediaContainer(ctx, creationID)
+	if err != nil {
+		return "", fmt.Errorf("failed to publish: %w", err)
+	}
+	return publishedID, nil
+}
+
+// createAndPublishFast publishes a text container right after creating it, since those
+// are usually ready at once, and only polls its status when the API says it isn't yet.
+func (c *Client) createAndPublishFast(ctx context.Context, params containerParams) (string, error) {
+	creationID, err := c.createMediaContainer(ctx, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to create media container: %w", err)
+	}
+
+	publishedID, err := c.publishMediaContainer(ctx, creationID)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != ErrorCodeMediaNotReady {
 		if err != nil {
-			return "", fmt.Errorf("failed to publish URL: %w", err)
+			return "", fmt.Errorf("failed to publish: %w", err)
 		}
-		rootPostID = publishedID
+		return publishedID, nil
+	}
+
+	logging.FromContext(ctx).Info("Container not ready yet, waiting for it", "container_id", creationID)
+	if err := c.waitForContainerReady(ctx, creationID, c.ContainerTimeout); err != nil {
+		return "", fmt.Errorf("container not ready: %w", err)
+	}
+	publishedID, err = c.publishMediaContainer(ctx, creationID)
+	if err != nil {
+		return "", fmt.Errorf("failed to publish: %w", err)
+	}
+	return publishedID, nil
+}
+
+// createReadyContainer creates a container and waits until it can be published.
+func (c *Client) createReadyContainer(ctx context.Context, params containerParams) (string, error) {
+	creationID, err := c.createMediaContainer(ctx, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to create media container: %w", err)
+	}
+
+	// Video containers take much longer to process than text or images
+	timeout := c.ContainerTimeout
+	if params.VideoURL != "" {
+		timeout = c.VideoContainerTimeout
+	}
+
+	// Wait for container to be ready before publishing
+	if err := c.waitForContainerReady(ctx, creationID, timeout); err != nil {
+		return "", fmt.Errorf("container not ready: %w", err)
 	}
+	return creationID, nil
+}
 
-	return rootPostID, nil
+// pollAttachment encodes poll options as the poll_attachment JSON object, whose keys
+// are option_a through option_d.
+func pollAttachment(options []string) (string, error) {
+	attachment := make(map[string]string, len(options))
+	for i, option := range options {
+		attachment["option_"+string(rune('a'+i))] = option
+	}
+	encoded, err := json.Marshal(attachment)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode poll: %w", err)
+	}
+	return string(encoded), nil
 }
 
 // waitForContainerReady polls the container status until it's FINISHED or times out
-func (c *Client) waitForContainerReady(containerID string) error {
-	endpoint := fmt.Sprintf("%s/%s?fields=status,error_message&access_token=%s",
-		baseURL, containerID, url.QueryEscape(c.AccessToken))
+func (c *Client) waitForContainerReady(ctx context.Context, containerID string, timeout time.Duration) error {
+	endpoint := fmt.Sprintf("%s/%s?fields=status,error_message", c.BaseURL, containerID)
 
-	deadline := time.Now().Add(containerReadyTimeout)
+	deadline := time.Now().Add(timeout)
+	interval := c.ContainerPollInterval
+	unknownStatuses := 0
 
 	for time.Now().Before(deadline) {
-		resp, err := c.HTTPClient.Get(endpoint)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 		if err != nil {
+			return fmt.Errorf("failed to build status request: %w", err)
+		}
+		c.authorize(req)
+
+		resp, err := c.do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 			return fmt.Errorf("failed to check container status: %w", err)
 		}
+		c.recordUsage(resp.Header)
 
-		bodyBytes, err := io.ReadAll(resp.Body)
+		bodyBytes, err := c.readBody(resp.Body)
 		resp.Body.Close()
 		if err != nil {
 			return fmt.Errorf("failed to read status response: %w", err)
@@ -167,7 +794,7 @@ func (c *Client) waitForContainerReady(containerID string) error {
 			return fmt.Errorf("failed to parse status response: %w", err)
 		}
 
-		log.Printf("[Threads API] Container %s status: %s", containerID, status.Status)
+		logging.FromContext(ctx).Info("Container status", "container_id", containerID, "status", status.Status)
 
 		switch status.Status {
 		case "FINISHED":
@@ -177,63 +804,151 @@ func (c *Client) waitForContainerReady(containerID string) error {
 		case "ERROR":
 			return fmt.Errorf("container processing failed: %s", status.ErrorMessage)
 		case "EXPIRED":
-			return fmt.Errorf("container expired before publishing")
+			return errContainerExpired
 		case "IN_PROGRESS":
-			time.Sleep(containerCheckInterval)
+			unknownStatuses = 0
 		default:
-			// Unknown status, wait a bit and retry
-			time.Sleep(containerCheckInterval)
+			// Tolerate a glitch, but don't poll for the whole timeout on a status we don't understand
+			unknownStatuses++
+			logging.FromContext(ctx).Warn("Unexpected container status",
+				"container_id", containerID, "status", status.Status, "consecutive", unknownStatuses)
+			if unknownStatuses >= maxUnknownStatuses {
+				return fmt.Errorf("container reported unexpected status %q %d times in a row", status.Status, unknownStatuses)
+			}
+		}
+
+		// Back off gradually; long video processing doesn't need a request every interval
+		if err := SleepContext(ctx, min(interval, time.Until(deadline))); err != nil {
+			return err
 		}
+		interval = min(interval*3/2, max(c.ContainerPollInterval, maxContainerPollInterval))
 	}
 
-	return fmt.Errorf("timeout waiting for container to be ready")
+	metrics.ContainerTimeouts.Inc()
+	return errContainerTimeout
 }
 
-func (c *Client) createMediaContainer(text, imageURL, replyToID, linkAttachment string) (string, error) {
-	endpoint := fmt.Sprintf("%s/%s/threads", baseURL, c.UserID)
+// containerParams holds the fields of a single media container.
+type containerParams struct {
+	Text           string
+	ImageURL       string
+	VideoURL       string
+	AltText        string
+	ReplyToID      string
+	LinkAttachment string
+	ReplyControl   ReplyControl
+	QuotePostID    string
+	PollOptions    []string
+	LocationID     string
+	TopicTag       string
+	GIFID          string
+	// Children are the container IDs of carousel items; set only on the carousel parent
+	Children       []string
+	IsCarouselItem bool
+}
+
+// textOnly reports whether the container carries no media, which the API has to fetch
+// and process before it can be published.
+func (p containerParams) textOnly() bool {
+	return p.ImageURL == "" && p.VideoURL == "" && len(p.Children) == 0 && p.GIFID == ""
+}
+
+func (c *Client) createMediaContainer(ctx context.Context, p containerParams) (string, error) {
+	endpoint := fmt.Sprintf("%s/%s/threads", c.BaseURL, c.UserID)
 
 	params := url.Values{}
-	params.Set("access_token", c.AccessToken)
 
 	mediaType := "TEXT"
-	if imageURL != "" {
+	if len(p.Children) > 0 {
+		mediaType = "CAROUSEL"
+		params.Set("children", strings.Join(p.Children, ","))
+	} else if p.ImageURL != "" {
+		// ValidateMedia names the offending field; this catches callers that skip it
+		if err := checkGIF("image_url", p.ImageURL, ""); err != nil {
+			return "", err
+		}
 		mediaType = "IMAGE"
-		params.Set("image_url", imageURL)
+		params.Set("image_url", p.ImageURL)
+	} else if p.VideoURL != "" {
+		mediaType = "VIDEO"
+		params.Set("video_url", p.VideoURL)
 	}
 	params.Set("media_type", mediaType)
 
-	if text != "" {
-		params.Set("text", text)
+	// alt_text is only meaningful for media and is omitted when empty
+	if p.AltText != "" && (mediaType == "IMAGE" || mediaType == "VIDEO") {
+		params.Set("alt_text", p.AltText)
 	}
 
-	if replyToID != "" {
-		params.Set("reply_to_id", replyToID)
+	if p.IsCarouselItem {
+		params.Set("is_carousel_item", "true")
 	}
 
-	// Add link_attachment for URL preview card (only for TEXT posts)
-	if linkAttachment != "" && mediaType == "TEXT" {
-		params.Set("link_attachment", linkAttachment)
+	if p.Text != "" {
+		params.Set("text", p.Text)
 	}
 
-	log.Printf("Creating media container. Type: %s, HasText: %v, HasImage: %v, HasLinkAttachment: %v",
-		mediaType, text != "", imageURL != "", linkAttachment != "")
+	if p.ReplyToID != "" {
+		params.Set("reply_to_id", p.ReplyToID)
+	}
 
-	resp, err := c.HTTPClient.PostForm(endpoint, params)
-	if err != nil {
-		return "", err
+	if p.ReplyControl != "" {
+		params.Set("reply_control", string(p.ReplyControl))
+	}
+
+	if p.QuotePostID != "" {
+		params.Set("quote_post_id", p.QuotePostID)
+	}
+
+	if p.LocationID != "" {
+		params.Set("location_id", p.LocationID)
+	}
+
+	if p.TopicTag != "" {
+		params.Set("topic_tag", p.TopicTag)
+	}
+
+	if p.GIFID != "" && mediaType == "TEXT" {
+		gif, err := json.Marshal(map[string]string{"gif_id": p.GIFID, "provider": "TENOR"})
+		if err != nil {
+			return "", fmt.Errorf("failed to encode GIF attachment: %w", err)
+		}
+		params.Set("gif_attachment", string(gif))
+	}
+
+	if len(p.PollOptions) > 0 && mediaType == "TEXT" {
+		poll, err := pollAttachment(p.PollOptions)
+		if err != nil {
+			return "", err
+		}
+		params.Set("poll_attachment", poll)
 	}
-	defer resp.Body.Close()
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	// Add link_attachment for URL preview card. The API rejects it on media containers,
+	// which createPost already routes to a URL reply instead.
+	if p.LinkAttachment != "" && mediaType == "TEXT" {
+		params.Set("link_attachment", p.LinkAttachment)
+	}
+
+	logging.FromContext(ctx).Info("Creating media container",
+		"media_type", mediaType,
+		"has_text", p.Text != "",
+		"has_image", p.ImageURL != "",
+		"has_video", p.VideoURL != "",
+		"children", len(p.Children),
+		"has_poll", len(p.PollOptions) > 0,
+		"has_link_attachment", p.LinkAttachment != "")
+
+	resp, bodyBytes, err := c.postForm(ctx, endpoint, params, true)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %v", err)
+		return "", err
 	}
 
 	// Log decoded response for readable Unicode
-	c.logDecodedResponse("[Threads API] Create Container Response", resp.Status, bodyBytes)
+	c.logDecodedResponse(ctx, "Threads API create container response", resp.Status, bodyBytes)
 
 	if resp.StatusCode != http.StatusOK {
-		return "", c.parseError(bodyBytes, resp.Status)
+		return "", c.parseError(bodyBytes, resp)
 	}
 
 	var result map[string]string
@@ -244,31 +959,24 @@ func (c *Client) createMediaContainer(text, imageURL, replyToID, linkAttachment
 	return result["id"], nil
 }
 
-func (c *Client) publishMediaContainer(creationID string) (string, error) {
-	endpoint := fmt.Sprintf("%s/%s/threads_publish", baseURL, c.UserID)
+func (c *Client) publishMediaContainer(ctx context.Context, creationID string) (string, error) {
+	endpoint := fmt.Sprintf("%s/%s/threads_publish", c.BaseURL, c.UserID)
 
 	params := url.Values{}
 	params.Set("creation_id", creationID)
-	params.Set("access_token", c.AccessToken)
 
-	log.Printf("Publishing media container: %s", creationID)
+	logging.FromContext(ctx).Info("Publishing media container", "container_id", creationID)
 
-	resp, err := c.HTTPClient.PostForm(endpoint, params)
+	resp, bodyBytes, err := c.postForm(ctx, endpoint, params, false)
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
-
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %v", err)
-	}
 
 	// Log decoded response for readable Unicode
-	c.logDecodedResponse("[Threads API] Publish Response", resp.Status, bodyBytes)
+	c.logDecodedResponse(ctx, "Threads API publish response", resp.Status, bodyBytes)
 
 	if resp.StatusCode != http.StatusOK {
-		return "", c.parseError(bodyBytes, resp.Status)
+		return "", c.parseError(bodyBytes, resp)
 	}
 
 	var result map[string]string
@@ -279,39 +987,204 @@ func (c *Client) publishMediaContainer(creationID string) (string, error) {
 	return result["id"], nil
 }
 
-// logDecodedResponse logs API response with decoded Unicode for readable non-ASCII characters
-func (c *Client) logDecodedResponse(prefix, status string, body []byte) {
-	// Try to parse and re-marshal with indentation for readable JSON
-	var parsed interface{}
-	if err := json.Unmarshal(body, &parsed); err == nil {
-		// Re-marshal without HTML escaping to get readable Unicode
-		encoder := json.NewEncoder(log.Writer())
-		encoder.SetEscapeHTML(false)
-		log.Printf("%s: Status=%s Body=", prefix, status)
-		encoder.Encode(parsed)
-	} else {
-		// Fallback to raw string
-		log.Printf("%s: Status=%s Body=%s", prefix, status, string(body))
+// postForm sends params as an authorized, form-encoded POST request bound to ctx and
+// returns the response together with its fully read body. Failures are retried up to MaxAttempts
+// times with exponential backoff and jitter. With idempotent set, as for container
+// creation where a repeat only leaves an unused container behind, that covers any
+// temporary condition (429, 5xx, a transient Graph API error code or a transport
+// error). Otherwise only responses that say the request was turned away are retried,
+// since a 5xx or a dropped connection may come after the post already went out.
+func (c *Client) postForm(ctx context.Context, endpoint string, params url.Values, idempotent bool) (*http.Response, []byte, error) {
+	retryable := isRejected
+	if idempotent {
+		retryable = isTransient
 	}
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(params.Encode()))
+		if err != nil {
+			return nil, nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		c.authorize(req)
+
+		resp, err := c.do(req)
+		if err != nil {
+			if !idempotent || attempt >= c.MaxAttempts || ctx.Err() != nil {
+				return nil, nil, err
+			}
+			if err := c.retryWait(ctx, attempt, "error", err); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		c.recordUsage(resp.Header)
+
+		bodyBytes, err := c.readBody(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read response body: %v", err)
+		}
+
+		if attempt >= c.MaxAttempts || !retryable(resp.StatusCode, bodyBytes) {
+			return resp, bodyBytes, nil
+		}
+		if err := c.retryWait(ctx, attempt, "status", resp.Status); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// retryWait logs a failed attempt, described by key and value, and sleeps for its backoff delay.
+func (c *Client) retryWait(ctx context.Context, attempt int, key string, value any) error {
+	delay := c.backoffDelay(attempt)
+	logging.FromContext(ctx).Warn("Transient Threads API error, retrying",
+		key, value, "delay", delay, "attempt", attempt, "max_attempts", c.MaxAttempts)
+	return SleepContext(ctx, delay)
 }
 
-func (c *Client) parseError(body []byte, status string) error {
+// transientErrorCodes are Graph API error codes for conditions that clear up on their own,
+// such as rate limiting (4, 17, 32, 613) and temporary service issues (1, 2).
+var transientErrorCodes = map[int]bool{1: true, 2: true, 4: true, 17: true, 32: true, 613: true}
+
+// rateLimitErrorCodes are the transientErrorCodes that turn a request away before it's
+// acted on.
+var rateLimitErrorCodes = map[int]bool{4: true, 17: true, 32: true, 613: true}
+
+// isTransient reports whether a failed response is worth retrying.
+func isTransient(statusCode int, body []byte) bool {
+	if statusCode == http.StatusOK {
+		return false
+	}
+	if statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError {
+		return true
+	}
+
 	var errResp APIErrorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil {
+		return false
+	}
+	return errResp.Error.IsTransient || transientErrorCodes[errResp.Error.Code]
+}
 
+// isRejected reports whether a failed response is a rate limit that turned the request
+// away, so retrying it can't repeat an action that already took effect.
+func isRejected(statusCode int, body []byte) bool {
+	if statusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if statusCode == http.StatusOK || statusCode >= http.StatusInternalServerError {
+		return false
+	}
+
+	var errResp APIErrorResponse
 	if err := json.Unmarshal(body, &errResp); err != nil {
-		// Fallback to raw body if parsing fails
-		return fmt.Errorf("API error: %s - %s", status, string(body))
+		return false
 	}
+	return rateLimitErrorCodes[errResp.Error.Code]
+}
 
-	if errResp.Error.Message != "" {
-		errMsg := fmt.Sprintf("API error: %s - %s", status, errResp.Error.Message)
-		if errResp.Error.ErrorUserTitle != "" {
-			errMsg += fmt.Sprintf(" (%s: %s)", errResp.Error.ErrorUserTitle, errResp.Error.ErrorUserMsg)
-		}
-		return fmt.Errorf("%s", errMsg)
+// backoffDelay returns the wait before the next attempt: base doubled for every attempt
+// already made, with random jitter over the upper half so concurrent clients spread out.
+func (c *Client) backoffDelay(attempt int) time.Duration {
+	delay := c.RetryBaseDelay << (attempt - 1)
+	if delay <= 0 {
+		return 0
 	}
+	half := delay / 2
+	return half + c.randN(half+1)
+}
+
+// jitter spreads d randomly over d ± d*fraction.
+func (c *Client) jitter(d time.Duration, fraction float64) time.Duration {
+	spread := time.Duration(float64(d) * fraction)
+	if spread <= 0 {
+		return d
+	}
+	return d - spread + c.randN(2*spread+1)
+}
+
+// randN returns a random duration in [0, n) from c.Rand, or the global source if unset.
+func (c *Client) randN(n time.Duration) time.Duration {
+	if c.Rand == nil {
+		return rand.N(n)
+	}
+	// *rand.Rand isn't safe for concurrent use
+	c.randMu.Lock()
+	defer c.randMu.Unlock()
+	return time.Duration(c.Rand.Int64N(int64(n)))
+}
+
+// errorCategory classifies a CreatePost error for the posts_failed_total metric.
+func errorCategory(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidPost):
+		return "invalid_post"
+	case errors.Is(err, ErrInvalidMediaURL):
+		return "invalid_media"
+	case errors.Is(err, errContainerTimeout):
+		return "container_timeout"
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, ErrTooManyPosts):
+		return "busy"
+	case errors.Is(err, ErrDuplicateContent):
+		return "duplicate"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	default:
+		return "api"
+	}
+}
 
-	return fmt.Errorf("API error: %s - %s", status, string(body))
+// SleepContext pauses for d or until ctx is done, whichever comes first. It returns ctx's
+// error when it returns early.
+func SleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// logDecodedResponse logs the status of an API response and the ID it returned, if any.
+// The full body, with decoded Unicode for readable non-ASCII characters, is only logged at
+// debug level, since it can echo post content.
+func (c *Client) logDecodedResponse(ctx context.Context, msg, status string, body []byte) {
+	logger := logging.FromContext(ctx)
+
+	var result struct {
+		ID string `json:"id"`
+	}
+	if json.Unmarshal(body, &result) == nil && result.ID != "" {
+		logger.Info(msg, "status", status, "id", result.ID)
+	} else {
+		logger.Info(msg, "status", status)
+	}
+	if logger.Enabled(ctx, slog.LevelDebug) {
+		logger.Debug(msg+" body", "body", decodeBody(body))
+	}
+}
+
+// decodeBody re-encodes a JSON body so escaped non-ASCII characters become readable,
+// falling back to the raw body when it isn't JSON.
+func decodeBody(body []byte) string {
+	var parsed interface{}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return string(body)
+	}
+
+	// Re-marshal without HTML escaping to get readable Unicode
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(parsed); err != nil {
+		return string(body)
+	}
+	return strings.TrimSpace(buf.String())
 }
 
 type APIErrorResponse struct {
@@ -323,9 +1196,25 @@ type APIErrorResponse struct {
 		ErrorUserTitle string `json:"error_user_title"`
 		ErrorUserMsg   string `json:"error_user_msg"`
 		FBTraceID      string `json:"fbtrace_id"`
+		IsTransient    bool   `json:"is_transient"`
 	} `json:"error"`
 }
 
+// AccessToken returns the token used to authenticate API calls.
+func (c *Client) AccessToken() string {
+	c.tokenMu.RLock()
+	defer c.tokenMu.RUnlock()
+	return c.accessToken
+}
+
+// SetAccessToken replaces the token used for subsequent API calls. It is safe to call
+// while requests are in flight.
+func (c *Client) SetAccessToken(token string) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.accessToken = token
+}
+
 // TokenInfo contains information about the access token validity
 type TokenInfo struct {
 	IsValid           bool     `json:"is_valid"`
@@ -336,33 +1225,55 @@ type TokenInfo struct {
 	Application       string   `json:"application"`
 }
 
+// ExpiresAtTime returns the expiry as a time; it is the zero time for tokens that never expire.
+func (t *TokenInfo) ExpiresAtTime() time.Time {
+	if t.ExpiresAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(t.ExpiresAt, 0)
+}
+
+// DaysRemaining returns the number of whole days until the token expires.
+func (t *TokenInfo) DaysRemaining() int {
+	if t.ExpiresAt == 0 {
+		return 0
+	}
+	return int(time.Until(t.ExpiresAtTime()).Hours() / 24)
+}
+
 type debugTokenResponse struct {
 	Data TokenInfo `json:"data"`
 }
 
 // ValidateToken checks if the access token is valid by calling the debug_token endpoint
 func (c *Client) ValidateToken() (*TokenInfo, error) {
-	endpoint := fmt.Sprintf("%s/debug_token", baseURL)
+	return c.ValidateTokenContext(context.Background())
+}
 
+// ValidateTokenContext is like ValidateToken, with the request bound to ctx.
+func (c *Client) ValidateTokenContext(ctx context.Context) (*TokenInfo, error) {
+	endpoint := fmt.Sprintf("%s/debug_token", c.BaseURL)
+
+	// The token under inspection is itself a parameter of debug_token
 	params := url.Values{}
-	params.Set("access_token", c.AccessToken)
-	params.Set("input_token", c.AccessToken)
+	params.Set("input_token", c.AccessToken())
 
 	fullURL := fmt.Sprintf("%s?%s", endpoint, params.Encode())
 
-	resp, err := c.HTTPClient.Get(fullURL)
+	resp, err := c.getContext(ctx, fullURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to validate token: %w", err)
 	}
 	defer resp.Body.Close()
+	c.recordUsage(resp.Header)
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	bodyBytes, err := c.readBody(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, c.parseError(bodyBytes, resp.Status)
+		return nil, c.parseError(bodyBytes, resp)
 	}
 
 	var result debugTokenResponse
@@ -373,33 +1284,317 @@ func (c *Client) ValidateToken() (*TokenInfo, error) {
 	return &result.Data, nil
 }
 
-// splitText splits a string into chunks of max length, respecting word boundaries.
+// SplitText returns the posts text would be split into, including any numbering
+// suffixes, exactly as CreatePost would publish them. It makes no API calls.
+func (c *Client) SplitText(text string) []string {
+	return c.chunkText(text)
+}
+
+// chunkText splits text into post-sized chunks. The Footer, if any, is added to the first
+// or last chunk as set by FooterPlacement. When NumberChunks is enabled and the text spans
+// more than one post, each chunk gets a " (i/n)" suffix. Room for both is reserved in every
+// chunk, so they always fit within the limit.
+func (c *Client) chunkText(text string) []string {
+	footer := c.footerFor(text)
+	limit := maxCharLimit - utf8.RuneCountInString(footer)
+
+	chunks := c.split(text, limit)
+	numbered := c.NumberChunks && len(chunks) >= 2
+	if numbered {
+		// Reserving room for the suffix can produce more chunks, which in turn may need a
+		// longer suffix, so re-split until the reserved width covers the final count.
+		total := len(chunks)
+		for {
+			chunks = c.split(text, limit-utf8.RuneCountInString(chunkSuffix(total, total)))
+			if len(chunks) <= total {
+				break
+			}
+			total = len(chunks)
+		}
+	}
+
+	if footer != "" && len(chunks) > 0 {
+		i := len(chunks) - 1
+		if c.FooterPlacement == FooterFirst {
+			i = 0
+		}
+		chunks[i] += footer
+	}
+	if numbered {
+		for i := range chunks {
+			chunks[i] += chunkSuffix(i+1, len(chunks))
+		}
+	}
+	return chunks
+}
+
+func chunkSuffix(index, total int) string {
+	return fmt.Sprintf(" (%d/%d)", index, total)
+}
+
+// split picks the splitting strategy configured by SmartSplit.
+func (c *Client) split(text string, limit int) []string {
+	if c.SmartSplit {
+		return splitSentences(text, limit)
+	}
+	return splitText(text, limit)
+}
+
+// splitText splits a string into chunks of at most limit characters, respecting word boundaries.
+// Lengths are measured in runes because the Threads limit counts characters, not bytes.
+// Line breaks and blank lines between paragraphs are kept, and a full chunk is broken at the
+// last paragraph boundary if that keeps it at least half full. Otherwise chunks break on
+// whitespace; words longer than the limit are hard-split by splitWord, which also keeps
+// grapheme clusters such as emoji with combining marks intact. Links are never split while
+// they fit in a post, and one that doesn't fit takes the sentence before it to the next
+// chunk (see urlBreakPoint).
 func splitText(text string, limit int) []string {
+	return splitChunks(text, limit, false)
+}
+
+// splitSentences works like splitText, but when a chunk has no suitable paragraph boundary
+// it moves the break back to the last sentence end in it, again as long as that keeps the
+// chunk at least half full, before falling back to the last word that fits.
+func splitSentences(text string, limit int) []string {
+	return splitChunks(text, limit, true)
+}
+
+// textToken is a word together with the whitespace that precedes it.
+type textToken struct {
+	sep  string
+	word string
+}
+
+func splitChunks(text string, limit int, sentences bool) []string {
 	if text == "" {
 		return []string{}
 	}
-	if len(text) <= limit {
+	if utf8.RuneCountInString(text) <= limit {
 		return []string{text}
 	}
 
 	var chunks []string
-	words := strings.Fields(text)
-	currentChunk := ""
-
-	for _, word := range words {
-		// +1 for space
-		if len(currentChunk)+len(word)+1 > limit {
-			chunks = append(chunks, currentChunk)
-			currentChunk = word
-		} else {
-			if currentChunk != "" {
-				currentChunk += " "
+	var current []textToken
+
+	for _, tok := range splitLongWords(tokenize(text), limit) {
+		for len(current) > 0 && tokensLen(current)+utf8.RuneCountInString(tok.sep+tok.word) > limit {
+			cut, ok := urlBreakPoint(current, tok, limit)
+			if !ok {
+				cut = breakPoint(current, limit/2, sentences)
 			}
-			currentChunk += word
+			chunks = append(chunks, joinTokens(current[:cut]))
+			current = current[cut:]
 		}
+		current = append(current, tok)
 	}
-	if currentChunk != "" {
-		chunks = append(chunks, currentChunk)
+	if len(current) > 0 {
+		chunks = append(chunks, joinTokens(current))
 	}
 	return chunks
 }
+
+// tokenize splits text into words. Runs of spaces collapse to a single space, while runs
+// containing line breaks keep up to two of them (one blank line) plus the indentation of
+// the following line.
+func tokenize(text string) []textToken {
+	var tokens []textToken
+	rest := text
+	for rest != "" {
+		wordStart := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsSpace(r) })
+		if wordStart < 0 {
+			break
+		}
+		space := rest[:wordStart]
+		rest = rest[wordStart:]
+		wordEnd := strings.IndexFunc(rest, unicode.IsSpace)
+		if wordEnd < 0 {
+			wordEnd = len(rest)
+		}
+		tokens = append(tokens, textToken{sep: normalizeSpace(space), word: rest[:wordEnd]})
+		rest = rest[wordEnd:]
+	}
+	return tokens
+}
+
+func normalizeSpace(space string) string {
+	newlines := strings.Count(space, "\n")
+	if newlines == 0 {
+		return " "
+	}
+	indent := space[strings.LastIndex(space, "\n")+1:]
+	return strings.Repeat("\n", min(newlines, 2)) + indent
+}
+
+// breakPoint returns how many of tokens to keep in a full chunk: everything before the
+// last paragraph boundary, or with sentences set through the last sentence end, as long
+// as the kept part is at least minLen runes; otherwise all of them.
+func breakPoint(tokens []textToken, minLen int, sentences bool) int {
+	for i := len(tokens) - 1; i > 0; i-- {
+		if strings.HasPrefix(tokens[i].sep, "\n\n") && tokensLen(tokens[:i]) >= minLen {
+			return i
+		}
+	}
+	if sentences {
+		for i := len(tokens) - 1; i > 0; i-- {
+			if endsSentence(tokens[i-1].word) && tokensLen(tokens[:i]) >= minLen {
+				return i
+			}
+		}
+	}
+	return len(tokens)
+}
+
+// urlBreakPoint handles a link that doesn't fit in the current chunk. Instead of leaving
+// the link to open the next chunk on its own, the sentence before it moves along with it,
+// as long as the kept part is at least half the limit and the sentence and link fit
+// together in one chunk. ok is false when tok isn't a link or that isn't possible.
+func urlBreakPoint(tokens []textToken, tok textToken, limit int) (cut int, ok bool) {
+	if !urlPattern.MatchString(tok.word) {
+		return 0, false
+	}
+	// The last token always belongs to the preceding sentence; walk back to its start
+	cut = len(tokens) - 1
+	for cut > 0 && !endsSentence(tokens[cut-1].word) && !strings.HasPrefix(tokens[cut].sep, "\n") {
+		cut--
+	}
+	if cut == 0 || tokensLen(tokens[:cut]) < limit/2 {
+		return 0, false
+	}
+	if tokensLen(tokens[cut:])+utf8.RuneCountInString(tok.sep+tok.word) > limit {
+		return 0, false
+	}
+	return cut, true
+}
+
+// endsSentence reports whether word ends with . ! ? or an ellipsis, optionally followed by
+// closing quotes or brackets.
+func endsSentence(word string) bool {
+	word = strings.TrimRight(word, "\"')]}»”’")
+	r, _ := utf8.DecodeLastRuneInString(word)
+	return r == '.' || r == '!' || r == '?' || r == '…'
+}
+
+// joinTokens renders tokens as text; the whitespace before the first one is dropped.
+func joinTokens(tokens []textToken) string {
+	var b strings.Builder
+	for i, tok := range tokens {
+		if i > 0 {
+			b.WriteString(tok.sep)
+		}
+		b.WriteString(tok.word)
+	}
+	return b.String()
+}
+
+// tokensLen is the rune length of joinTokens(tokens).
+func tokensLen(tokens []textToken) int {
+	n := 0
+	for i, tok := range tokens {
+		if i > 0 {
+			n += utf8.RuneCountInString(tok.sep)
+		}
+		n += utf8.RuneCountInString(tok.word)
+	}
+	return n
+}
+
+// splitLongWords replaces every word longer than limit with its hard-split pieces. The
+// pieces follow each other without whitespace, like the word they came from. Links are
+// only ever split here, when they couldn't fit in a post even on their own.
+func splitLongWords(tokens []textToken, limit int) []textToken {
+	result := make([]textToken, 0, len(tokens))
+	for _, tok := range tokens {
+		if utf8.RuneCountInString(tok.word) <= limit {
+			result = append(result, tok)
+			continue
+		}
+		for i, piece := range splitWord(tok.word, limit) {
+			sep := ""
+			if i == 0 {
+				sep = tok.sep
+			}
+			result = append(result, textToken{sep: sep, word: piece})
+		}
+	}
+	return result
+}
+
+// splitWord breaks a single word into pieces of at most limit runes. Each cut is moved
+// back so it never lands before a combining mark, variation selector or zero-width
+// joiner, which would sever the grapheme cluster it belongs to.
+func splitWord(word string, limit int) []string {
+	runes := []rune(word)
+	var pieces []string
+
+	for len(runes) > limit {
+		cut := limit
+		for cut > 1 && continuesGrapheme(runes[cut]) {
+			cut--
+		}
+		// A cluster longer than the whole limit can't be kept intact
+		if cut == 1 && continuesGrapheme(runes[cut]) {
+			cut = limit
+		}
+		pieces = append(pieces, string(runes[:cut]))
+		runes = runes[cut:]
+	}
+	if len(runes) > 0 {
+		pieces = append(pieces, string(runes))
+	}
+	return pieces
+}
+
+// continuesGrapheme reports whether r attaches to the rune before it.
+func continuesGrapheme(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me) ||
+		unicode.Is(unicode.Variation_Selector, r) ||
+		r == '\u200d' ||
+		(r >= 0x1F3FB && r <= 0x1F3FF) // emoji skin tone modifiers
+}
+
+type refreshTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// RefreshToken exchanges the current long-lived token for a new one with a fresh 60-day
+// lifetime and starts using it for all subsequent calls. expiresIn is in seconds.
+// The token must be at least 24 hours old and not yet expired.
+func (c *Client) RefreshToken() (newToken string, expiresIn int64, err error) {
+	params := url.Values{}
+	params.Set("grant_type", "th_refresh_token")
+	// The token being refreshed is a parameter of the call itself
+	params.Set("access_token", c.AccessToken())
+
+	// Token refresh lives outside the versioned API
+	host := strings.TrimSuffix(strings.TrimRight(c.BaseURL, "/"), "/"+apiVersion)
+	fullURL := fmt.Sprintf("%s/refresh_access_token?%s", host, params.Encode())
+
+	resp, err := c.get(fullURL)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to refresh token: %w", err)
+	}
+	defer resp.Body.Close()
+	c.recordUsage(resp.Header)
+
+	bodyBytes, err := c.readBody(resp.Body)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, c.parseError(bodyBytes, resp)
+	}
+
+	var result refreshTokenResponse
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return "", 0, fmt.Errorf("failed to parse refresh response: %w", err)
+	}
+	if result.AccessToken == "" {
+		return "", 0, fmt.Errorf("refresh response has no access token")
+	}
+
+	c.SetAccessToken(result.AccessToken)
+	return result.AccessToken, result.ExpiresIn, nil
+}
@@ -0,0 +1,1654 @@
+package threads_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/think-root/threads-connector/internal/metrics"
+	"github.com/think-root/threads-connector/internal/threads"
+	"github.com/think-root/threads-connector/internal/threads/threadstest"
+)
+
+func TestPublish(t *testing.T) {
+	var words []string
+	for i := range 250 {
+		words = append(words, fmt.Sprintf("word%d", i))
+	}
+	long := strings.Join(words, " ")
+
+	tests := []struct {
+		name   string
+		params threads.PostParams
+		// wantChunks is the number of text posts; the texts themselves come from SplitText
+		wantChunks int
+		wantMedia  string
+		wantImage  string
+		wantURL    bool
+	}{
+		{"single post", threads.PostParams{Text: "hello"}, 1, "TEXT", "", false},
+		{"thread", threads.PostParams{Text: long}, 4, "TEXT", "", false},
+		{"image", threads.PostParams{Text: "look", ImageURL: "https://example.com/a.jpg"}, 1, "IMAGE", "https://example.com/a.jpg", false},
+		{"image thread", threads.PostParams{Text: long, ImageURL: "https://example.com/a.jpg"}, 4, "IMAGE", "https://example.com/a.jpg", false},
+		{"URL reply", threads.PostParams{Text: "read this", URL: "https://example.com/article"}, 1, "TEXT", "", true},
+		{"thread with URL reply", threads.PostParams{Text: long, URL: "https://example.com/article"}, 4, "TEXT", "", true},
+		{"quoted thread", threads.PostParams{Text: long, QuotePostID: "post-quoted"}, 4, "TEXT", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+			c := api.Client()
+
+			result, err := c.Publish(context.Background(), tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			published := api.Published()
+			wantPosts := tt.wantChunks
+			if tt.wantURL {
+				wantPosts++
+			}
+			if len(published) != wantPosts || len(api.Containers()) != wantPosts {
+				t.Fatalf("published %d of %d containers, want %d", len(published), len(api.Containers()), wantPosts)
+			}
+			if result.Chunks != tt.wantChunks {
+				t.Errorf("Chunks = %d, want %d", result.Chunks, tt.wantChunks)
+			}
+
+			var ids []string
+			for _, p := range published {
+				ids = append(ids, p.PublishedID)
+			}
+			if result.ID != ids[0] || !slices.Equal(result.IDs, ids) {
+				t.Errorf("result IDs = %v (root %s), want %v", result.IDs, result.ID, ids)
+			}
+
+			// Each post replies to the one before it, in the order the text was split
+			chunks := c.SplitText(tt.params.Text)
+			for i, p := range published[:tt.wantChunks] {
+				if got := p.Params.Get("text"); got != chunks[i] {
+					t.Errorf("post %d text = %q, want %q", i, got, chunks[i])
+				}
+				wantReplyTo := ""
+				if i > 0 {
+					wantReplyTo = ids[i-1]
+				}
+				if got := p.Params.Get("reply_to_id"); got != wantReplyTo {
+					t.Errorf("post %d reply_to_id = %q, want %q", i, got, wantReplyTo)
+				}
+
+				// Media only goes on the first post
+				wantMedia, wantImage := "TEXT", ""
+				if i == 0 {
+					wantMedia, wantImage = tt.wantMedia, tt.wantImage
+				}
+				if got := p.Params.Get("media_type"); got != wantMedia {
+					t.Errorf("post %d media_type = %q, want %q", i, got, wantMedia)
+				}
+				if got := p.Params.Get("image_url"); got != wantImage {
+					t.Errorf("post %d image_url = %q, want %q", i, got, wantImage)
+				}
+				// So does the quote
+				wantQuote := ""
+				if i == 0 {
+					wantQuote = tt.params.QuotePostID
+				}
+				if got := p.Params.Get("quote_post_id"); got != wantQuote {
+					t.Errorf("post %d quote_post_id = %q, want %q", i, got, wantQuote)
+				}
+			}
+
+			if result.URLReplyAttempted != tt.wantURL || result.URLReplyPosted != tt.wantURL {
+				t.Errorf("URL reply attempted %v, posted %v, want %v", result.URLReplyAttempted, result.URLReplyPosted, tt.wantURL)
+			}
+			if tt.wantURL {
+				reply := published[len(published)-1]
+				if got := reply.Params.Get("text"); got != tt.params.URL {
+					t.Errorf("URL reply text = %q, want %q", got, tt.params.URL)
+				}
+				if got := reply.Params.Get("reply_to_id"); got != ids[tt.wantChunks-1] {
+					t.Errorf("URL reply reply_to_id = %q, want the last post %q", got, ids[tt.wantChunks-1])
+				}
+			}
+		})
+	}
+}
+
+func TestPublishCarousel(t *testing.T) {
+	images := func(n int) []string {
+		var urls []string
+		for i := range n {
+			urls = append(urls, fmt.Sprintf("https://example.com/%d.jpg", i))
+		}
+		return urls
+	}
+
+	tests := []struct {
+		name      string
+		imageURLs []string
+		wantItems int
+		wantMedia string
+	}{
+		{"single image is an image post", images(1), 0, "IMAGE"},
+		{"two images", images(2), 2, "CAROUSEL"},
+		{"many images keep their order", images(threads.MaxCarouselItems), threads.MaxCarouselItems, "CAROUSEL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+
+			result, err := api.Client().Publish(context.Background(), threads.PostParams{Text: "album", ImageURLs: tt.imageURLs})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			containers := api.Containers()
+			if len(containers) != tt.wantItems+1 {
+				t.Fatalf("created %d containers, want %d", len(containers), tt.wantItems+1)
+			}
+			items := make(map[string]string)
+			for _, c := range containers[:tt.wantItems] {
+				if c.Params.Get("is_carousel_item") != "true" || c.Params.Get("media_type") != "IMAGE" {
+					t.Errorf("item %s has params %v, want an IMAGE carousel item", c.ID, c.Params)
+				}
+				if c.PublishedID != "" {
+					t.Errorf("item %s was published on its own", c.ID)
+				}
+				items[c.ID] = c.Params.Get("image_url")
+			}
+
+			root := containers[tt.wantItems]
+			if got := root.Params.Get("media_type"); got != tt.wantMedia {
+				t.Errorf("media_type = %q, want %q", got, tt.wantMedia)
+			}
+			if root.Params.Get("text") != "album" || root.PublishedID != result.ID {
+				t.Errorf("root post has text %q and ID %q, want %q and %q", root.Params.Get("text"), root.PublishedID, "album", result.ID)
+			}
+			if tt.wantMedia != "CAROUSEL" {
+				if got := root.Params.Get("image_url"); got != tt.imageURLs[0] {
+					t.Errorf("image_url = %q, want %q", got, tt.imageURLs[0])
+				}
+				return
+			}
+
+			// Items are created concurrently, but children lists them in request order
+			var got []string
+			for _, id := range strings.Split(root.Params.Get("children"), ",") {
+				got = append(got, items[id])
+			}
+			if !slices.Equal(got, tt.imageURLs) {
+				t.Errorf("children images = %v, want %v", got, tt.imageURLs)
+			}
+		})
+	}
+}
+
+func TestPublishTooManyCarouselItems(t *testing.T) {
+	api := threadstest.NewServer()
+	defer api.Close()
+
+	imageURLs := slices.Repeat([]string{"https://example.com/a.jpg"}, threads.MaxCarouselItems+1)
+	_, err := api.Client().Publish(context.Background(), threads.PostParams{Text: "album", ImageURLs: imageURLs})
+	if !errors.Is(err, threads.ErrInvalidPost) {
+		t.Errorf("Publish = %v, want ErrInvalidPost", err)
+	}
+	if n := len(api.Containers()); n != 0 {
+		t.Errorf("created %d containers, want none", n)
+	}
+}
+
+func TestPublishCarouselConcurrency(t *testing.T) {
+	var imageURLs []string
+	for i := range threads.MaxCarouselItems {
+		imageURLs = append(imageURLs, fmt.Sprintf("https://example.com/%d.jpg", i))
+	}
+
+	api := threadstest.NewServer()
+	defer api.Close()
+
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	api.Fail = func(r *http.Request) *threads.APIError {
+		if r.Form.Get("is_carousel_item") != "true" {
+			return nil
+		}
+		mu.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return nil
+	}
+
+	if _, err := api.Client().Publish(context.Background(), threads.PostParams{Text: "album", ImageURLs: imageURLs}); err != nil {
+		t.Fatal(err)
+	}
+	// Items are created four at a time
+	if peak < 2 || peak > 4 {
+		t.Errorf("%d carousel items were created at once, want 2 to 4", peak)
+	}
+}
+
+func TestPublishCarouselItemFails(t *testing.T) {
+	var imageURLs []string
+	for i := range threads.MaxCarouselItems {
+		imageURLs = append(imageURLs, fmt.Sprintf("https://example.com/%d.jpg", i))
+	}
+
+	api := threadstest.NewServer()
+	defer api.Close()
+	api.Fail = func(r *http.Request) *threads.APIError {
+		if r.Form.Get("image_url") == imageURLs[2] {
+			return &threads.APIError{StatusCode: http.StatusBadRequest, Message: "Media download failed", Code: threads.ErrorCodeInvalidParameter}
+		}
+		return nil
+	}
+
+	_, err := api.Client().Publish(context.Background(), threads.PostParams{Text: "album", ImageURLs: imageURLs})
+	if err == nil || !strings.Contains(err.Error(), "carousel item 2") {
+		t.Errorf("Publish = %v, want the failure of carousel item 2", err)
+	}
+	for _, c := range api.Containers() {
+		if c.Params.Get("media_type") == "CAROUSEL" {
+			t.Error("created the carousel although an item failed")
+		}
+	}
+	if n := len(api.Published()); n != 0 {
+		t.Errorf("published %d posts, want none", n)
+	}
+}
+
+func TestPublishVideo(t *testing.T) {
+	const video = "https://example.com/a.mp4"
+
+	tests := []struct {
+		name string
+		// status reports the status of each poll, by how many came before it
+		status    func(polls int) string
+		wantErr   string
+		wantPolls int
+	}{
+		{"processing finishes", func(polls int) string {
+			if polls < 2 {
+				return "IN_PROGRESS"
+			}
+			return "FINISHED"
+		}, "", 3},
+		{"processing fails", func(polls int) string {
+			if polls < 1 {
+				return "IN_PROGRESS"
+			}
+			return "ERROR"
+		}, "container processing failed", 2},
+		{"unknown status", func(polls int) string { return "PAUSED" }, `unexpected status "PAUSED" 3 times in a row`, 3},
+		{"unknown status between progress reports", func(polls int) string {
+			return []string{"PAUSED", "PAUSED", "IN_PROGRESS", "PAUSED", "PAUSED", "FINISHED"}[polls]
+		}, "", 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+			api.Status = func(c threadstest.Container) string { return tt.status(c.Polls - 1) }
+
+			result, err := api.Client().Publish(context.Background(), threads.PostParams{Text: "watch", VideoURL: video})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				if n := len(api.Published()); n != 0 {
+					t.Errorf("published %d posts, want none", n)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			containers := api.Containers()
+			if len(containers) != 1 {
+				t.Fatalf("created %d containers, want 1", len(containers))
+			}
+			c := containers[0]
+			if c.Params.Get("media_type") != "VIDEO" || c.Params.Get("video_url") != video {
+				t.Errorf("container params = %v, want a VIDEO container for %s", c.Params, video)
+			}
+			if c.Polls != tt.wantPolls {
+				t.Errorf("polled %d times, want %d", c.Polls, tt.wantPolls)
+			}
+			if tt.wantErr == "" && (c.PublishedID == "" || result.ID != c.PublishedID) {
+				t.Errorf("result ID = %q, want the published %q", result.ID, c.PublishedID)
+			}
+		})
+	}
+}
+
+func TestPublishContainerTimeout(t *testing.T) {
+	const (
+		short = 30 * time.Millisecond
+		long  = 5 * time.Second
+	)
+
+	tests := []struct {
+		name         string
+		params       threads.PostParams
+		timeout      time.Duration
+		videoTimeout time.Duration
+		wantErr      bool
+	}{
+		{"image times out", threads.PostParams{Text: "look", ImageURL: "https://example.com/a.jpg"}, short, long, true},
+		{"video gets its own timeout", threads.PostParams{Text: "watch", VideoURL: "https://example.com/a.mp4"}, short, long, false},
+		{"video times out", threads.PostParams{Text: "watch", VideoURL: "https://example.com/a.mp4"}, long, short, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+			// Processing takes longer than the short timeout
+			api.Status = func(c threadstest.Container) string {
+				if c.Polls < 6 {
+					return "IN_PROGRESS"
+				}
+				return "FINISHED"
+			}
+			c := api.Client()
+			c.ContainerTimeout = tt.timeout
+			c.VideoContainerTimeout = tt.videoTimeout
+			c.ContainerPollInterval = 10 * time.Millisecond
+
+			_, err := c.Publish(context.Background(), tt.params)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "timeout waiting for container") {
+					t.Fatalf("error = %v, want a container timeout", err)
+				}
+				if n := len(api.Published()); n != 0 {
+					t.Errorf("published %d posts, want none", n)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestPublishRecreateExpired(t *testing.T) {
+	tests := []struct {
+		name     string
+		recreate bool
+		// expired are the containers reported as EXPIRED
+		expired        []string
+		wantErr        bool
+		wantContainers int
+	}{
+		{"not expired", true, nil, false, 1},
+		{"expired", false, []string{"container-1"}, true, 1},
+		{"recreated", true, []string{"container-1"}, false, 2},
+		{"recreated once only", true, []string{"container-1", "container-2"}, true, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+			api.Status = func(c threadstest.Container) string {
+				if slices.Contains(tt.expired, c.ID) {
+					return "EXPIRED"
+				}
+				return "FINISHED"
+			}
+			c := api.Client()
+			c.RecreateExpired = tt.recreate
+
+			_, err := c.Publish(context.Background(), threads.PostParams{Text: "hello"})
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Publish = %v, want an error %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "expired") {
+				t.Errorf("Publish = %v, want it to report the expired container", err)
+			}
+			if n := len(api.Containers()); n != tt.wantContainers {
+				t.Errorf("created %d containers, want %d", n, tt.wantContainers)
+			}
+			wantPublished := 1
+			if tt.wantErr {
+				wantPublished = 0
+			}
+			if n := len(api.Published()); n != wantPublished {
+				t.Errorf("published %d posts, want %d", n, wantPublished)
+			}
+		})
+	}
+}
+
+func TestPublishFast(t *testing.T) {
+	tests := []struct {
+		name     string
+		fast     bool
+		params   threads.PostParams
+		notReady bool
+		// wantPolls says whether the container status was checked before publishing
+		wantPolls bool
+	}{
+		{"text", true, threads.PostParams{Text: "hello"}, false, false},
+		{"text not ready yet", true, threads.PostParams{Text: "hello"}, true, true},
+		{"image", true, threads.PostParams{Text: "hello", ImageURL: "https://example.com/a.jpg"}, false, true},
+		{"disabled", false, threads.PostParams{Text: "hello"}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+			var mu sync.Mutex
+			refused := false
+			api.Fail = func(r *http.Request) *threads.APIError {
+				mu.Lock()
+				defer mu.Unlock()
+				if tt.notReady && !refused && strings.HasSuffix(r.URL.Path, "/threads_publish") {
+					refused = true
+					return &threads.APIError{StatusCode: http.StatusBadRequest, Message: "Media not ready", Code: threads.ErrorCodeMediaNotReady}
+				}
+				return nil
+			}
+			c := api.Client()
+			c.FastPublish = tt.fast
+
+			if _, err := c.Publish(context.Background(), tt.params); err != nil {
+				t.Fatal(err)
+			}
+			containers := api.Containers()
+			if len(containers) != 1 || containers[0].PublishedID == "" {
+				t.Fatalf("containers %+v, want one published", containers)
+			}
+			if polled := containers[0].Polls > 0; polled != tt.wantPolls {
+				t.Errorf("container polled %d times, want polls %v", containers[0].Polls, tt.wantPolls)
+			}
+		})
+	}
+}
+
+func TestPublishMetrics(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  threads.PostParams
+		timeout bool
+		// want are the increases of posts_created_total, posts_failed_total for
+		// category and container_timeouts_total
+		wantCreated  float64
+		category     string
+		wantFailed   float64
+		wantTimeouts float64
+	}{
+		{"published", threads.PostParams{Text: "hello"}, false, 1, "api", 0, 0},
+		{"invalid post", threads.PostParams{}, false, 0, "invalid_post", 1, 0},
+		{"container timeout", threads.PostParams{Text: "hello"}, true, 0, "container_timeout", 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+			c := api.Client()
+			if tt.timeout {
+				api.Status = func(threadstest.Container) string { return "IN_PROGRESS" }
+				c.ContainerTimeout = 20 * time.Millisecond
+			}
+
+			created := testutil.ToFloat64(metrics.PostsCreated)
+			failed := testutil.ToFloat64(metrics.PostsFailed.WithLabelValues(tt.category))
+			timeouts := testutil.ToFloat64(metrics.ContainerTimeouts)
+			durations := durationCount(t)
+
+			c.Publish(context.Background(), tt.params)
+
+			if got := testutil.ToFloat64(metrics.PostsCreated) - created; got != tt.wantCreated {
+				t.Errorf("posts_created_total rose by %v, want %v", got, tt.wantCreated)
+			}
+			if got := testutil.ToFloat64(metrics.PostsFailed.WithLabelValues(tt.category)) - failed; got != tt.wantFailed {
+				t.Errorf("posts_failed_total{category=%q} rose by %v, want %v", tt.category, got, tt.wantFailed)
+			}
+			if got := testutil.ToFloat64(metrics.ContainerTimeouts) - timeouts; got != tt.wantTimeouts {
+				t.Errorf("container_timeouts_total rose by %v, want %v", got, tt.wantTimeouts)
+			}
+			// Only published posts have their duration observed
+			if got := float64(durationCount(t) - durations); got != tt.wantCreated {
+				t.Errorf("post_duration_seconds observed %v more posts, want %v", got, tt.wantCreated)
+			}
+		})
+	}
+}
+
+// durationCount returns how many posts post_duration_seconds has observed.
+func durationCount(t *testing.T) uint64 {
+	t.Helper()
+	var m dto.Metric
+	if err := metrics.PostDuration.Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestPublishCanceledWhilePolling(t *testing.T) {
+	tests := []struct {
+		name   string
+		params threads.PostParams
+	}{
+		{"text", threads.PostParams{Text: "hello"}},
+		{"video", threads.PostParams{Text: "watch", VideoURL: "https://example.com/a.mp4"}},
+		{"thread", threads.PostParams{Text: strings.Repeat("word ", 200)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			api.Status = func(c threadstest.Container) string {
+				if c.Polls == 2 {
+					cancel()
+				}
+				return "IN_PROGRESS"
+			}
+
+			_, err := api.Client().Publish(ctx, tt.params)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("error = %v, want context.Canceled", err)
+			}
+			if n := len(api.Published()); n != 0 {
+				t.Errorf("published %d posts, want none", n)
+			}
+			if containers := api.Containers(); len(containers) != 1 || containers[0].Polls != 2 {
+				t.Errorf("containers = %+v, want one polled twice", containers)
+			}
+		})
+	}
+}
+
+func TestPublishReplyControl(t *testing.T) {
+	tests := []struct {
+		control threads.ReplyControl
+		wantErr bool
+	}{
+		{"", false},
+		{threads.ReplyControlEveryone, false},
+		{threads.ReplyControlAccountsYouFollow, false},
+		{threads.ReplyControlMentionedOnly, false},
+		{"followers_only", true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.control), func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+
+			_, err := api.Client().Publish(context.Background(), threads.PostParams{Text: strings.Repeat("word ", 200), ReplyControl: tt.control})
+			if tt.wantErr {
+				if !errors.Is(err, threads.ErrInvalidPost) {
+					t.Fatalf("error = %v, want ErrInvalidPost", err)
+				}
+				if n := len(api.Containers()); n != 0 {
+					t.Errorf("created %d containers, want none", n)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Replies in the thread inherit the root post's setting
+			published := api.Published()
+			if len(published) < 2 {
+				t.Fatalf("published %d posts, want a thread", len(published))
+			}
+			for i, p := range published {
+				want := ""
+				if i == 0 {
+					want = string(tt.control)
+				}
+				if got := p.Params.Get("reply_control"); got != want {
+					t.Errorf("post %d reply_control = %q, want %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPublishReplyTo(t *testing.T) {
+	const parent = "post-external"
+
+	tests := []struct {
+		name      string
+		params    threads.PostParams
+		wantPosts int
+	}{
+		{"reply", threads.PostParams{Text: "hello", ReplyToID: parent}, 1},
+		{"thread", threads.PostParams{Text: strings.Repeat("word ", 200), ReplyToID: parent}, 2},
+		{"URL only", threads.PostParams{URL: "https://example.com/article", ReplyToID: parent}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+
+			if _, err := api.Client().Publish(context.Background(), tt.params); err != nil {
+				t.Fatal(err)
+			}
+			published := api.Published()
+			if len(published) != tt.wantPosts {
+				t.Fatalf("published %d posts, want %d", len(published), tt.wantPosts)
+			}
+			// The root replies to the parent, and each later post to the one before it
+			want := parent
+			for i, p := range published {
+				if got := p.Params.Get("reply_to_id"); got != want {
+					t.Errorf("post %d reply_to_id = %q, want %q", i, got, want)
+				}
+				want = p.PublishedID
+			}
+		})
+	}
+}
+
+func TestPublishWaitsForURLReplyParent(t *testing.T) {
+	tests := []struct {
+		name string
+		// misses is how often fetching the root post fails before it is available
+		misses      int
+		timeout     time.Duration
+		ctxTimeout  time.Duration
+		wantChecks  int
+		wantWait    time.Duration
+		wantErr     error
+		wantReplied bool
+	}{
+		{"available at once", 0, time.Second, 0, 1, 0, nil, true},
+		{"available after a miss", 1, time.Second, 0, 2, 500 * time.Millisecond, nil, true},
+		{"gives up after the timeout", 100, 300 * time.Millisecond, 0, 2, 300 * time.Millisecond, nil, true},
+		{"canceled while waiting", 100, time.Minute, 100 * time.Millisecond, 1, 100 * time.Millisecond, context.DeadlineExceeded, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+
+			var mu sync.Mutex
+			checks := 0
+			api.Fail = func(r *http.Request) *threads.APIError {
+				if r.Method != http.MethodGet || !strings.HasPrefix(r.URL.Path, "/post-") {
+					return nil
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				checks++
+				if checks <= tt.misses {
+					return &threads.APIError{StatusCode: http.StatusBadRequest, Message: "Object does not exist", Code: threads.ErrorCodeInvalidParameter, Subcode: 33}
+				}
+				return nil
+			}
+
+			ctx := context.Background()
+			if tt.ctxTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.ctxTimeout)
+				defer cancel()
+			}
+			c := api.Client()
+			c.URLReplyTimeout = tt.timeout
+
+			start := time.Now()
+			result, err := c.Publish(ctx, threads.PostParams{Text: "read", URL: "https://example.com", URLMode: threads.URLModeReply})
+			elapsed := time.Since(start)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Publish = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && result.URLReplyPosted != tt.wantReplied {
+				t.Errorf("URLReplyPosted = %v, want %v", result.URLReplyPosted, tt.wantReplied)
+			}
+			if n := len(api.Published()); (n == 2) != tt.wantReplied {
+				t.Errorf("published %d posts, want the URL reply %v", n, tt.wantReplied)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if checks != tt.wantChecks {
+				t.Errorf("fetched the root post %d times, want %d", checks, tt.wantChecks)
+			}
+			if elapsed < tt.wantWait {
+				t.Errorf("waited %v, want at least %v", elapsed, tt.wantWait)
+			}
+		})
+	}
+}
+
+func TestPublishURLReplyFails(t *testing.T) {
+	const link = "https://example.com/article"
+
+	tests := []struct {
+		name          string
+		params        threads.PostParams
+		failReply     bool
+		wantAttempted bool
+		wantPosted    bool
+	}{
+		{"reply posted", threads.PostParams{Text: "read", URL: link}, false, true, true},
+		{"reply failed", threads.PostParams{Text: "read", URL: link}, true, true, false},
+		{"no URL", threads.PostParams{Text: "read"}, false, false, false},
+		{"attachment", threads.PostParams{Text: "read", URL: link, URLMode: threads.URLModeAttachment}, false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+			api.Fail = func(r *http.Request) *threads.APIError {
+				if tt.failReply && r.Method == http.MethodPost && r.Form.Get("text") == link {
+					return &threads.APIError{StatusCode: http.StatusBadRequest, Message: "Invalid parameter", Code: threads.ErrorCodeInvalidParameter}
+				}
+				return nil
+			}
+
+			result, err := api.Client().Publish(context.Background(), tt.params)
+			if err != nil {
+				t.Fatalf("Publish = %v, want the post to succeed", err)
+			}
+			if result.URLReplyAttempted != tt.wantAttempted || result.URLReplyPosted != tt.wantPosted {
+				t.Errorf("URLReplyAttempted, URLReplyPosted = %v, %v, want %v, %v", result.URLReplyAttempted, result.URLReplyPosted, tt.wantAttempted, tt.wantPosted)
+			}
+			published := api.Published()
+			if published[0].PublishedID != result.ID {
+				t.Errorf("ID = %q, want the root post %q", result.ID, published[0].PublishedID)
+			}
+			if (len(published) == 2) != tt.wantPosted {
+				t.Errorf("published %d posts, want the URL reply %v", len(published), tt.wantPosted)
+			}
+		})
+	}
+}
+
+func TestPublishIDs(t *testing.T) {
+	const link = "https://example.com/article"
+	long := strings.Repeat("word ", 250)
+
+	tests := []struct {
+		name    string
+		params  threads.PostParams
+		wantIDs int
+	}{
+		{"single post", threads.PostParams{Text: "hello"}, 1},
+		{"thread", threads.PostParams{Text: long}, 3},
+		{"thread with URL reply", threads.PostParams{Text: long, URL: link}, 4},
+		{"URL attachment", threads.PostParams{Text: long, URL: link, URLMode: threads.URLModeAttachment}, 3},
+		{"URL only", threads.PostParams{URL: link}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+
+			result, err := api.Client().Publish(context.Background(), tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want []string
+			for _, p := range api.Published() {
+				want = append(want, p.PublishedID)
+			}
+			if len(result.IDs) != tt.wantIDs || !slices.Equal(result.IDs, want) {
+				t.Errorf("IDs = %q, want the %d published posts in thread order %q", result.IDs, tt.wantIDs, want)
+			}
+			if result.ID != result.IDs[0] {
+				t.Errorf("ID = %q, want the first of IDs %q", result.ID, result.IDs)
+			}
+		})
+	}
+}
+
+func TestPublishFirstPostParams(t *testing.T) {
+	long := strings.Repeat("word ", 200)
+
+	tests := []struct {
+		name   string
+		params threads.PostParams
+		// want are the form values of the first post; an empty value must be absent.
+		// Later posts of the thread must carry none of them.
+		want map[string]string
+	}{
+		{"image alt text", threads.PostParams{Text: long, ImageURL: "https://example.com/a.jpg", AltText: "a cat"}, map[string]string{"alt_text": "a cat"}},
+		{"video alt text", threads.PostParams{Text: long, VideoURL: "https://example.com/a.mp4", AltText: "a dog"}, map[string]string{"alt_text": "a dog"}},
+		{"alt text without media", threads.PostParams{Text: long, AltText: "nothing"}, map[string]string{"alt_text": ""}},
+		{"location", threads.PostParams{Text: long, LocationID: "101"}, map[string]string{"location_id": "101"}},
+		{"topic tag", threads.PostParams{Text: long, TopicTag: "golang"}, map[string]string{"topic_tag": "golang"}},
+		{"poll", threads.PostParams{Text: long, PollOptions: []string{"yes", "no", "maybe"}}, map[string]string{"poll_attachment": `{"option_a":"yes","option_b":"no","option_c":"maybe"}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+
+			if _, err := api.Client().Publish(context.Background(), tt.params); err != nil {
+				t.Fatal(err)
+			}
+			published := api.Published()
+			if len(published) < 2 {
+				t.Fatalf("published %d posts, want a thread", len(published))
+			}
+			for i, p := range published {
+				for key, want := range tt.want {
+					if i > 0 {
+						want = ""
+					}
+					if got := p.Params.Get(key); got != want {
+						t.Errorf("post %d %s = %q, want %q", i, key, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPublishInvalidPoll(t *testing.T) {
+	tests := []struct {
+		name   string
+		params threads.PostParams
+	}{
+		{"one option", threads.PostParams{Text: "Tabs or spaces?", PollOptions: []string{"tabs"}}},
+		{"five options", threads.PostParams{Text: "Pick one", PollOptions: []string{"a", "b", "c", "d", "e"}}},
+		{"no question", threads.PostParams{URL: "https://example.com", URLMode: threads.URLModeReply, PollOptions: []string{"yes", "no"}}},
+		{"with an image", threads.PostParams{Text: "Cute?", ImageURL: "https://example.com/a.jpg", PollOptions: []string{"yes", "no"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+
+			if _, err := api.Client().Publish(context.Background(), tt.params); !errors.Is(err, threads.ErrInvalidPost) {
+				t.Errorf("Publish = %v, want ErrInvalidPost", err)
+			}
+			if n := len(api.Containers()); n != 0 {
+				t.Errorf("created %d containers, want none", n)
+			}
+		})
+	}
+}
+
+func TestPublishInterPostDelay(t *testing.T) {
+	const delay = 40 * time.Millisecond
+
+	tests := []struct {
+		name   string
+		delay  time.Duration
+		delays []time.Duration
+		// wantGaps are the least pauses between the posts of a three-post thread
+		wantGaps []time.Duration
+	}{
+		{"no delay", 0, nil, []time.Duration{0, 0}},
+		{"delay", delay, nil, []time.Duration{delay, delay}},
+		{"longer first pause", delay, []time.Duration{3 * delay}, []time.Duration{3 * delay, delay}},
+		{"every position", 0, []time.Duration{delay, 2 * delay}, []time.Duration{delay, 2 * delay}},
+		{"more positions than posts", 0, []time.Duration{delay, delay, time.Hour}, []time.Duration{delay, delay}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+			c := api.Client()
+			c.InterPostDelay = tt.delay
+			c.InterPostDelays = tt.delays
+
+			if _, err := c.Publish(context.Background(), threads.PostParams{Text: strings.Repeat("word ", 250)}); err != nil {
+				t.Fatal(err)
+			}
+			published := api.Published()
+			if len(published) != len(tt.wantGaps)+1 {
+				t.Fatalf("published %d posts, want %d", len(published), len(tt.wantGaps)+1)
+			}
+			for i, want := range tt.wantGaps {
+				if gap := published[i+1].PublishedAt.Sub(published[i].PublishedAt); gap < want {
+					t.Errorf("post %d was published %v after the one before, want at least %v", i+1, gap, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPublishMaxThreadChunks(t *testing.T) {
+	var words []string
+	for i := range 250 {
+		words = append(words, fmt.Sprintf("word%d", i))
+	}
+	long := strings.Join(words, " ")
+
+	tests := []struct {
+		name      string
+		max       int
+		wantPosts int
+	}{
+		{"no limit", 0, 4},
+		{"at the limit", 4, 4},
+		{"over the limit", 3, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+			c := api.Client()
+			c.MaxThreadChunks = tt.max
+
+			_, err := c.Publish(context.Background(), threads.PostParams{Text: long})
+			if (err != nil) != (tt.wantPosts == 0) {
+				t.Fatalf("error = %v, want error %v", err, tt.wantPosts == 0)
+			}
+			if err != nil && !errors.Is(err, threads.ErrInvalidPost) {
+				t.Errorf("error = %v, want ErrInvalidPost", err)
+			}
+			// The limit is checked before anything is created
+			if n := len(api.Containers()); n != tt.wantPosts {
+				t.Errorf("created %d containers, want %d", n, tt.wantPosts)
+			}
+		})
+	}
+}
+
+func TestPublishPartialFailure(t *testing.T) {
+	var words []string
+	for i := range 250 {
+		words = append(words, fmt.Sprintf("word%d", i))
+	}
+
+	api := threadstest.NewServer()
+	defer api.Close()
+
+	var mu sync.Mutex
+	creates := 0
+	api.Fail = func(r *http.Request) *threads.APIError {
+		if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/threads") {
+			return nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if creates++; creates == 3 {
+			return &threads.APIError{StatusCode: http.StatusBadRequest, Message: "bad", Code: threads.ErrorCodeInvalidParameter}
+		}
+		return nil
+	}
+
+	_, err := api.Client().Publish(context.Background(), threads.PostParams{Text: strings.Join(words, " ")})
+	var partial *threads.PartialPostError
+	if !errors.As(err, &partial) {
+		t.Fatalf("error = %v, want a PartialPostError", err)
+	}
+
+	published := api.Published()
+	if len(published) != 2 {
+		t.Fatalf("published %d posts, want the 2 before the failure", len(published))
+	}
+	want := []string{published[0].PublishedID, published[1].PublishedID}
+	if !slices.Equal(partial.PublishedIDs, want) {
+		t.Errorf("PublishedIDs = %v, want %v", partial.PublishedIDs, want)
+	}
+	var apiErr *threads.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != threads.ErrorCodeInvalidParameter {
+		t.Errorf("error = %v, want it to wrap the API error", err)
+	}
+}
+
+func TestPublishRetries(t *testing.T) {
+	tests := []struct {
+		name        string
+		failures    int
+		err         *threads.APIError
+		maxAttempts int
+		wantCalls   int
+		wantErr     bool
+	}{
+		{"transient error recovers", 2, &threads.APIError{StatusCode: http.StatusServiceUnavailable, Message: "busy", Code: 2}, 3, 3, false},
+		{"transient error persists", 5, &threads.APIError{StatusCode: http.StatusServiceUnavailable, Message: "busy", Code: 2}, 3, 3, true},
+		{"permanent error is not retried", 5, &threads.APIError{StatusCode: http.StatusBadRequest, Message: "bad", Code: threads.ErrorCodeInvalidParameter}, 3, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+
+			var mu sync.Mutex
+			calls := 0
+			api.Fail = func(r *http.Request) *threads.APIError {
+				if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/threads") {
+					return nil
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				calls++
+				if calls <= tt.failures {
+					return tt.err
+				}
+				return nil
+			}
+
+			c := api.Client()
+			c.MaxAttempts = tt.maxAttempts
+			_, err := c.Publish(context.Background(), threads.PostParams{Text: "hello"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("container created %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestPublishDuplicateContent(t *testing.T) {
+	api := threadstest.NewServer()
+	defer api.Close()
+	var mu sync.Mutex
+	attempts := 0
+	api.Fail = func(r *http.Request) *threads.APIError {
+		if !strings.HasSuffix(r.URL.Path, "/threads_publish") {
+			return nil
+		}
+		mu.Lock()
+		attempts++
+		mu.Unlock()
+		return &threads.APIError{StatusCode: http.StatusBadRequest, Message: "Duplicate status message", Code: threads.ErrorCodeDuplicateContent}
+	}
+
+	_, err := api.Client().Publish(context.Background(), threads.PostParams{Text: "hello"})
+	if !errors.Is(err, threads.ErrDuplicateContent) {
+		t.Errorf("Publish = %v, want ErrDuplicateContent", err)
+	}
+	// A duplicate stays a duplicate, so it isn't retried
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 1 {
+		t.Errorf("tried to publish %d times, want 1", attempts)
+	}
+}
+
+func TestPublishRetriesPublishStep(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       *threads.APIError
+		wantCalls int
+		wantErr   bool
+	}{
+		{"rate limit is retried", &threads.APIError{StatusCode: http.StatusBadRequest, Message: "slow down", Code: 4}, 2, false},
+		{"too many requests is retried", &threads.APIError{StatusCode: http.StatusTooManyRequests, Message: "slow down"}, 2, false},
+		{"server error is not retried", &threads.APIError{StatusCode: http.StatusInternalServerError, Message: "oops", Code: 2}, 1, true},
+		{"transient code is not retried", &threads.APIError{StatusCode: http.StatusBadRequest, Message: "busy", Code: 2, Transient: true}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+
+			var mu sync.Mutex
+			calls := 0
+			api.Fail = func(r *http.Request) *threads.APIError {
+				if !strings.HasSuffix(r.URL.Path, "/threads_publish") {
+					return nil
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				calls++
+				if calls == 1 {
+					return tt.err
+				}
+				return nil
+			}
+
+			c := api.Client()
+			c.MaxAttempts = 3
+			_, err := c.Publish(context.Background(), threads.PostParams{Text: "hello"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("publish called %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// flakyTransport fails the first failures requests whose path ends in suffix
+// with a transport error.
+type flakyTransport struct {
+	suffix   string
+	failures int
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (t *flakyTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if strings.HasSuffix(r.URL.Path, t.suffix) {
+		t.mu.Lock()
+		t.calls++
+		fail := t.calls <= t.failures
+		t.mu.Unlock()
+		if fail {
+			return nil, errors.New("connection reset by peer")
+		}
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestPublishRetriesTransportErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		suffix    string
+		wantCalls int
+		wantErr   bool
+	}{
+		{"container creation is retried", "/threads", 2, false},
+		{"publish is not retried", "/threads_publish", 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+
+			transport := &flakyTransport{suffix: tt.suffix, failures: 1}
+			c := api.Client()
+			c.MaxAttempts = 3
+			c.HTTPClient = &http.Client{Transport: transport}
+			_, err := c.Publish(context.Background(), threads.PostParams{Text: "hello"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if transport.calls != tt.wantCalls {
+				t.Errorf("%s called %d times, want %d", tt.suffix, transport.calls, tt.wantCalls)
+			}
+			if wantPublished := !tt.wantErr; (len(api.Published()) == 1) != wantPublished {
+				t.Errorf("published %d posts, want published %v", len(api.Published()), wantPublished)
+			}
+		})
+	}
+}
+
+// recordingTransport records every request before sending it on.
+type recordingTransport struct {
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func (t *recordingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.requests = append(t.requests, r.Clone(r.Context()))
+	t.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestHTTPTimeout(t *testing.T) {
+	if got := threads.NewClient("user", "token").HTTPClient.Timeout; got != time.Minute {
+		t.Errorf("NewClient timeout = %v, want 1m", got)
+	}
+
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		wantErr bool
+	}{
+		{"fast enough", time.Second, false},
+		{"too slow", 20 * time.Millisecond, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+			postID, err := api.Client().CreatePost(threads.PostParams{Text: "hello"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			api.Fail = func(r *http.Request) *threads.APIError {
+				time.Sleep(100 * time.Millisecond)
+				return nil
+			}
+
+			c := threads.NewClientWithHTTP(threadstest.UserID, threadstest.AccessToken, &http.Client{Timeout: tt.timeout})
+			c.BaseURL = api.URL
+			_, err = c.GetPost(postID)
+			var netErr net.Error
+			if tt.wantErr != (errors.As(err, &netErr) && netErr.Timeout()) {
+				t.Errorf("GetPost = %v, want a timeout %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	body := `{"id":"post-1","text":"` + strings.Repeat("a", 1000) + `"}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		max     int64
+		wantErr bool
+	}{
+		{"within the limit", int64(len(body)), false},
+		{"over the limit", int64(len(body)) - 1, true},
+		{"no limit", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := threads.NewClientWithHTTP(threadstest.UserID, threadstest.AccessToken, srv.Client())
+			c.BaseURL = srv.URL
+			c.MaxResponseBytes = tt.max
+
+			post, err := c.GetPost("post-1")
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "too large") {
+					t.Errorf("GetPost = %v, want a too large error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(post.Text) != 1000 {
+				t.Errorf("post text has %d bytes, want 1000", len(post.Text))
+			}
+		})
+	}
+
+	if got := threads.NewClient("user", "token").MaxResponseBytes; got != 1<<20 {
+		t.Errorf("NewClient MaxResponseBytes = %d, want 1 MiB", got)
+	}
+}
+
+func TestAccessTokenInHeader(t *testing.T) {
+	api := threadstest.NewServer()
+	defer api.Close()
+
+	transport := &recordingTransport{}
+	c := api.Client()
+	c.HTTPClient = &http.Client{Transport: transport}
+
+	ctx := context.Background()
+	if _, err := c.Publish(ctx, threads.PostParams{Text: "hello", URL: "https://example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.ListPostsContext(ctx, 10, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ValidateTokenContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range transport.requests {
+		if got := r.Header.Get("Authorization"); got != "Bearer "+threadstest.AccessToken {
+			t.Errorf("%s %s: Authorization = %q, want the bearer token", r.Method, r.URL.Path, got)
+		}
+		// debug_token takes the token it inspects as input_token, which is unavoidable
+		if r.URL.Query().Has("access_token") {
+			t.Errorf("%s %s: access_token in the URL", r.Method, r.URL.Path)
+		}
+	}
+
+	// The fake API refuses a token in the URL, so every test checks this
+	r, err := http.NewRequest(http.MethodGet, api.URL+"/debug_token?access_token="+threadstest.AccessToken, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Authorization", "Bearer "+threadstest.AccessToken)
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("token in the URL: status %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent *string
+		want      string
+	}{
+		{"default", nil, threads.DefaultUserAgent},
+		{"custom", ptr("my-bot/2.0"), "my-bot/2.0"},
+		{"empty sets none", ptr(""), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+
+			transport := &recordingTransport{}
+			c := api.Client()
+			c.HTTPClient = &http.Client{Transport: transport}
+			if tt.userAgent != nil {
+				c.UserAgent = *tt.userAgent
+			}
+
+			ctx := context.Background()
+			if _, err := c.Publish(ctx, threads.PostParams{Text: "hello", URL: "https://example.com"}); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := c.ListPostsContext(ctx, 10, ""); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.ValidateTokenContext(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, r := range transport.requests {
+				if got := r.Header.Get("User-Agent"); got != tt.want {
+					t.Errorf("%s %s: User-Agent = %q, want %q", r.Method, r.URL.Path, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestPublishURLModes(t *testing.T) {
+	const (
+		link  = "https://example.com/article"
+		image = "https://example.com/a.jpg"
+		video = "https://example.com/a.mp4"
+	)
+
+	tests := []struct {
+		name   string
+		params threads.PostParams
+		// wantText and wantAttachment are the root post's text and link_attachment
+		wantText       string
+		wantAttachment string
+		wantReply      bool
+	}{
+		{"reply", threads.PostParams{Text: "read", URL: link, URLMode: threads.URLModeReply}, "read", "", true},
+		{"reply with image", threads.PostParams{Text: "read", ImageURL: image, URL: link, URLMode: threads.URLModeReply}, "read", "", true},
+		{"attachment", threads.PostParams{Text: "read", URL: link, URLMode: threads.URLModeAttachment}, "read", link, false},
+		{"attachment with image falls back to a reply", threads.PostParams{Text: "read", ImageURL: image, URL: link, URLMode: threads.URLModeAttachment}, "read", "", true},
+		{"attachment with video falls back to a reply", threads.PostParams{Text: "read", VideoURL: video, URL: link, URLMode: threads.URLModeAttachment}, "read", "", true},
+		{"prepend", threads.PostParams{Text: "read", URL: link, URLMode: threads.URLModePrepend}, link + "\n\nread", "", false},
+		{"append with image", threads.PostParams{Text: "read", ImageURL: image, URL: link, URLMode: threads.URLModeAppend}, "read\n\n" + link, "", false},
+		{"none", threads.PostParams{Text: "read", URL: link, URLMode: threads.URLModeNone}, "read", "", false},
+		{"none with image", threads.PostParams{Text: "read", ImageURL: image, URL: link, URLMode: threads.URLModeNone}, "read", "", false},
+		{"URL only", threads.PostParams{URL: link, URLMode: threads.URLModeAppend}, link, "", false},
+		{"URL only in reply mode", threads.PostParams{URL: link, URLMode: threads.URLModeReply}, link, "", false},
+		{"URL only as attachment", threads.PostParams{URL: link, URLMode: threads.URLModeAttachment}, link, link, false},
+		{"quote with URL reply", threads.PostParams{Text: "read", URL: link, URLMode: threads.URLModeReply, QuotePostID: "post-quoted"}, "read", "", true},
+		{"quoted URL only", threads.PostParams{URL: link, URLMode: threads.URLModeReply, QuotePostID: "post-quoted"}, link, "", false},
+		{"quoted URL only as attachment", threads.PostParams{URL: link, URLMode: threads.URLModeAttachment, QuotePostID: "post-quoted"}, link, link, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+
+			result, err := api.Client().Publish(context.Background(), tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			published := api.Published()
+			wantPosts := 1
+			if tt.wantReply {
+				wantPosts++
+			}
+			if len(published) != wantPosts {
+				t.Fatalf("published %d posts, want %d", len(published), wantPosts)
+			}
+
+			root := published[0]
+			if got := root.Params.Get("text"); got != tt.wantText {
+				t.Errorf("text = %q, want %q", got, tt.wantText)
+			}
+			if got := root.Params.Get("link_attachment"); got != tt.wantAttachment {
+				t.Errorf("link_attachment = %q, want %q", got, tt.wantAttachment)
+			}
+			if got, want := root.Params.Get("image_url"), tt.params.ImageURL; got != want {
+				t.Errorf("image_url = %q, want %q", got, want)
+			}
+			if got, want := root.Params.Get("video_url"), tt.params.VideoURL; got != want {
+				t.Errorf("video_url = %q, want %q", got, want)
+			}
+			if got, want := root.Params.Get("quote_post_id"), tt.params.QuotePostID; got != want {
+				t.Errorf("quote_post_id = %q, want %q", got, want)
+			}
+
+			if result.URLReplyPosted != tt.wantReply {
+				t.Errorf("URLReplyPosted = %v, want %v", result.URLReplyPosted, tt.wantReply)
+			}
+			if tt.wantReply {
+				reply := published[1]
+				if reply.Params.Get("text") != link || reply.Params.Get("reply_to_id") != root.PublishedID || reply.Params.Has("quote_post_id") {
+					t.Errorf("URL reply %v, want %q replying to %q without a quote", reply.Params, link, root.PublishedID)
+				}
+			}
+		})
+	}
+}
+
+func TestPublishInlineURLThread(t *testing.T) {
+	const link = "https://example.com/article"
+	long := strings.TrimSpace(strings.Repeat("word ", 150))
+
+	tests := []struct {
+		name     string
+		mode     threads.URLMode
+		truncate bool
+		// wantPost is the post that carries the URL, -1 for the last one
+		wantPost int
+	}{
+		{"prepend", threads.URLModePrepend, false, 0},
+		{"append", threads.URLModeAppend, false, -1},
+		{"prepend truncated", threads.URLModePrepend, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+
+			result, err := api.Client().Publish(context.Background(), threads.PostParams{Text: long, URL: link, URLMode: tt.mode, Truncate: tt.truncate})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.URLReplyAttempted {
+				t.Error("URLReplyAttempted = true, want the URL inline")
+			}
+
+			published := api.Published()
+			if tt.truncate != (len(published) == 1) {
+				t.Fatalf("published %d posts, truncate %v", len(published), tt.truncate)
+			}
+			want := tt.wantPost
+			if want < 0 {
+				want = len(published) - 1
+			}
+			for i, p := range published {
+				text := p.Params.Get("text")
+				if got := strings.Contains(text, link); got != (i == want) {
+					t.Errorf("post %d has the URL %v, want it only in post %d: %q", i, got, want, text)
+				}
+			}
+			text := published[want].Params.Get("text")
+			if tt.mode == threads.URLModePrepend && !strings.HasPrefix(text, link+"\n\n") {
+				t.Errorf("root post = %q, want it to start with the URL", text)
+			}
+			if tt.mode == threads.URLModeAppend && !strings.HasSuffix(text, "\n\n"+link) {
+				t.Errorf("last post = %q, want it to end with the URL", text)
+			}
+		})
+	}
+}
+
+func TestPublishAutoLinkPreview(t *testing.T) {
+	const text = "New release: https://example.com/v2. Docs at https://example.com/docs"
+
+	tests := []struct {
+		name    string
+		enabled bool
+		params  threads.PostParams
+		// wantAttachment is the root post's link_attachment
+		wantAttachment string
+	}{
+		{"first link", true, threads.PostParams{Text: text}, "https://example.com/v2"},
+		{"disabled", false, threads.PostParams{Text: text}, ""},
+		{"no link", true, threads.PostParams{Text: "nothing to see"}, ""},
+		{"explicit URL wins", true, threads.PostParams{Text: text, URL: "https://example.com/blog", URLMode: threads.URLModeAttachment}, "https://example.com/blog"},
+		{"explicit URL as a reply", true, threads.PostParams{Text: text, URL: "https://example.com/blog", URLMode: threads.URLModeReply}, ""},
+		{"media post", true, threads.PostParams{Text: text, ImageURL: "https://example.com/a.jpg"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+			c := api.Client()
+			c.AutoLinkPreview = tt.enabled
+
+			if _, err := c.Publish(context.Background(), tt.params); err != nil {
+				t.Fatal(err)
+			}
+			root := api.Published()[0]
+			if got := root.Params.Get("link_attachment"); got != tt.wantAttachment {
+				t.Errorf("link_attachment = %q, want %q", got, tt.wantAttachment)
+			}
+			if got := root.Params.Get("text"); got != tt.params.Text {
+				t.Errorf("text = %q, want it unchanged", got)
+			}
+		})
+	}
+}
+
+func TestPublishURLModeNoneWithoutContent(t *testing.T) {
+	api := threadstest.NewServer()
+	defer api.Close()
+
+	_, err := api.Client().Publish(context.Background(), threads.PostParams{URL: "https://example.com", URLMode: threads.URLModeNone})
+	if !errors.Is(err, threads.ErrInvalidPost) {
+		t.Errorf("Publish = %v, want ErrInvalidPost", err)
+	}
+	if n := len(api.Containers()); n != 0 {
+		t.Errorf("created %d containers, want none", n)
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	api := threadstest.NewServer()
+	defer api.Close()
+	c := api.Client()
+
+	token, expiresIn, err := c.RefreshToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == threadstest.AccessToken || token != api.Token() || c.AccessToken() != token {
+		t.Errorf("refreshed token %q, client uses %q, want the API's new token %q", token, c.AccessToken(), api.Token())
+	}
+	if want := int64(60 * 24 * 60 * 60); expiresIn != want {
+		t.Errorf("expiresIn = %d, want %d", expiresIn, want)
+	}
+
+	// The client goes on with the new token, while the old one stops working
+	if _, err := c.Publish(context.Background(), threads.PostParams{Text: "hello"}); err != nil {
+		t.Errorf("Publish with the refreshed token: %v", err)
+	}
+	var apiErr *threads.APIError
+	if _, err := api.Client().ValidateToken(); !errors.As(err, &apiErr) || apiErr.Code != threads.ErrorCodeInvalidToken {
+		t.Errorf("ValidateToken with the old token = %v, want an invalid token error", err)
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	if threads.DefaultBaseURL != "https://graph.threads.net/v1.0" {
+		t.Errorf("DefaultBaseURL = %q, want the v1.0 Graph API", threads.DefaultBaseURL)
+	}
+	if got := threads.NewClient("user", "token").BaseURL; got != threads.DefaultBaseURL {
+		t.Errorf("NewClient BaseURL = %q, want DefaultBaseURL", got)
+	}
+
+	api := threadstest.NewServer()
+	defer api.Close()
+
+	// Serve the fake API under a version prefix like the real one, with token
+	// refresh outside it
+	var mu sync.Mutex
+	var paths []string
+	mux := http.NewServeMux()
+	mux.Handle("/v2.0/", http.StripPrefix("/v2.0", api.Config.Handler))
+	mux.Handle("/refresh_access_token", api.Config.Handler)
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		mux.ServeHTTP(w, r)
+	}))
+	defer mock.Close()
+
+	c := api.Client()
+	c.BaseURL = mock.URL + "/v2.0"
+	if _, err := c.Publish(context.Background(), threads.PostParams{Text: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.RefreshToken(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(api.Published()) != 1 {
+		t.Errorf("published %d posts through the custom base URL, want 1", len(api.Published()))
+	}
+	for _, p := range paths {
+		if !strings.HasPrefix(p, "/v2.0/") && p != "/refresh_access_token" {
+			t.Errorf("request to %s, want it under the base URL", p)
+		}
+	}
+}
+
+func TestLogResponseBody(t *testing.T) {
+	for _, debug := range []bool{false, true} {
+		t.Run(fmt.Sprintf("debug %v", debug), func(t *testing.T) {
+			defer slog.SetDefault(slog.Default())
+			level := slog.LevelInfo
+			if debug {
+				level = slog.LevelDebug
+			}
+			var buf bytes.Buffer
+			slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: level})))
+
+			api := threadstest.NewServer()
+			defer api.Close()
+
+			result, err := api.Client().Publish(context.Background(), threads.PostParams{Text: "hello"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out := buf.String()
+			// The returned IDs are logged either way, the bodies only at debug level
+			if !strings.Contains(out, "id="+result.ID) {
+				t.Errorf("log output lacks the published ID %s: %s", result.ID, out)
+			}
+			if got := strings.Contains(out, "body="); got != debug {
+				t.Errorf("log output has response bodies %v, want %v: %s", got, debug, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package threads
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrDuplicateContent matches an *APIError with errors.Is when Threads rejected a post
+// because the same content was just published.
+var ErrDuplicateContent = errors.New("duplicate content")
+
+// Graph API error codes with a meaning callers commonly act on.
+const (
+	ErrorCodeInvalidParameter = 100
+	ErrorCodeInvalidToken     = 190
+	// ErrorCodeMediaNotReady is returned when a container is published before it finished processing
+	ErrorCodeMediaNotReady = 9007
+	// ErrorCodeDuplicateContent is returned for a post identical to one just published
+	ErrorCodeDuplicateContent = 506
+)
+
+// APIError is an error response from the Threads API. Code and Subcode are the Graph
+// API error codes (see https://developers.facebook.com/docs/graph-api/guides/error-handling);
+// both are 0 when the response body couldn't be parsed.
+type APIError struct {
+	StatusCode int
+	Status     string
+	Message    string
+	Type       string
+	Code       int
+	Subcode    int
+	UserTitle  string
+	UserMsg    string
+	FBTraceID  string
+	// Transient is set when the API marked the error as temporary
+	Transient bool
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("API error: %s - %s", e.Status, e.Message)
+	if e.UserTitle != "" {
+		msg += fmt.Sprintf(" (%s: %s)", e.UserTitle, e.UserMsg)
+	}
+	return msg
+}
+
+// RateLimited reports whether the request was rejected for exceeding a rate limit.
+func (e *APIError) RateLimited() bool {
+	switch e.Code {
+	case 4, 17, 32, 613:
+		return true
+	}
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// duplicateContentMessage is the Graph API message that goes with ErrorCodeDuplicateContent.
+const duplicateContentMessage = "Duplicate status message"
+
+// Duplicate reports whether the post was rejected as a repeat of recent content. The
+// message is only consulted when the response carried no error code at all.
+func (e *APIError) Duplicate() bool {
+	if e.Code != 0 {
+		return e.Code == ErrorCodeDuplicateContent
+	}
+	return e.Message == duplicateContentMessage
+}
+
+// Is lets errors.Is(err, ErrDuplicateContent) recognize duplicate-content rejections.
+func (e *APIError) Is(target error) bool {
+	return target == ErrDuplicateContent && e.Duplicate()
+}
+
+// Temporary reports whether retrying later may succeed.
+func (e *APIError) Temporary() bool {
+	return e.Transient || transientErrorCodes[e.Code] || e.StatusCode >= http.StatusInternalServerError
+}
+
+// parseError turns a failed API response into an *APIError.
+func (c *Client) parseError(body []byte, resp *http.Response) error {
+	apiErr := &APIError{StatusCode: resp.StatusCode, Status: resp.Status, Message: string(body)}
+
+	var errResp APIErrorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil || errResp.Error.Message == "" {
+		// Fall back to the raw body if it isn't a Graph API error
+		return apiErr
+	}
+
+	apiErr.Message = errResp.Error.Message
+	apiErr.Type = errResp.Error.Type
+	apiErr.Code = errResp.Error.Code
+	apiErr.Subcode = errResp.Error.ErrorSubcode
+	apiErr.UserTitle = errResp.Error.ErrorUserTitle
+	apiErr.UserMsg = errResp.Error.ErrorUserMsg
+	apiErr.FBTraceID = errResp.Error.FBTraceID
+	apiErr.Transient = errResp.Error.IsTransient
+	return apiErr
+}
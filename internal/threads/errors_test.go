@@ -0,0 +1,32 @@
+package threads
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestAPIErrorDuplicate(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *APIError
+		want bool
+	}{
+		{"duplicate code", &APIError{Code: ErrorCodeDuplicateContent, Message: "Duplicate status message"}, true},
+		{"duplicate code, other message", &APIError{Code: ErrorCodeDuplicateContent, Message: "Try again"}, true},
+		{"other code mentioning duplicates", &APIError{Code: ErrorCodeInvalidParameter, Message: "Duplicate children in carousel"}, false},
+		{"no code, duplicate message", &APIError{Message: duplicateContentMessage}, true},
+		{"no code, other message", &APIError{Message: "duplicate key"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Duplicate(); got != tt.want {
+				t.Errorf("Duplicate() = %v, want %v", got, tt.want)
+			}
+			wrapped := fmt.Errorf("failed to publish: %w", tt.err)
+			if got := errors.Is(wrapped, ErrDuplicateContent); got != tt.want {
+				t.Errorf("errors.Is(err, ErrDuplicateContent) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package threads
+
+import "strings"
+
+// MaxFooterLength is the longest footer accepted, leaving most of each post for the text.
+const MaxFooterLength = 100
+
+// footerSeparator sets the footer apart from the text it follows
+const footerSeparator = "\n\n"
+
+// FooterPlacement selects which post of a thread carries the footer.
+type FooterPlacement string
+
+const (
+	// FooterLast adds the footer to the last post, so it closes the thread
+	FooterLast FooterPlacement = "last"
+	// FooterFirst adds the footer to the first post, the one shown in feeds
+	FooterFirst FooterPlacement = "first"
+)
+
+// Valid reports whether p is a known footer placement.
+func (p FooterPlacement) Valid() bool {
+	switch p {
+	case FooterLast, FooterFirst:
+		return true
+	}
+	return false
+}
+
+// footerFor returns the separator and Footer to add to text, or "" when there is no
+// footer or no text to add it to.
+func (c *Client) footerFor(text string) string {
+	if c.Footer == "" || strings.TrimSpace(text) == "" {
+		return ""
+	}
+	return footerSeparator + c.Footer
+}
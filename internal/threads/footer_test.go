@@ -0,0 +1,84 @@
+package threads
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFooter(t *testing.T) {
+	const footer = "— via Bot"
+	long := strings.TrimSpace(strings.Repeat("word ", 150))
+
+	tests := []struct {
+		name      string
+		footer    string
+		placement FooterPlacement
+		numbered  bool
+		text      string
+		// wantChunks is the number of posts and wantFooter the one carrying the footer,
+		// or -1 when none should
+		wantChunks int
+		wantFooter int
+	}{
+		{"no footer", "", FooterLast, false, "hello", 1, -1},
+		{"single post", footer, FooterLast, false, "hello", 1, 0},
+		{"single post first", footer, FooterFirst, false, "hello", 1, 0},
+		{"blank text", footer, FooterLast, false, "  ", 1, -1},
+		{"thread, last post", footer, "", false, long, 2, 1},
+		{"thread, first post", footer, FooterFirst, false, long, 2, 0},
+		{"numbered thread", footer, FooterLast, true, long, 2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient("user", "token")
+			c.Footer = tt.footer
+			c.FooterPlacement = tt.placement
+			c.NumberChunks = tt.numbered
+
+			chunks := c.SplitText(tt.text)
+			if len(chunks) != tt.wantChunks {
+				t.Fatalf("SplitText returned %d posts, want %d: %q", len(chunks), tt.wantChunks, chunks)
+			}
+			for i, chunk := range chunks {
+				if n := utf8.RuneCountInString(chunk); n > maxCharLimit {
+					t.Errorf("post %d has %d characters, more than %d", i, n, maxCharLimit)
+				}
+				hasFooter := tt.footer != "" && strings.Contains(chunk, footerSeparator+tt.footer)
+				if hasFooter != (i == tt.wantFooter) {
+					t.Errorf("post %d has the footer %v, want it on post %d: %q", i, hasFooter, tt.wantFooter, chunk)
+				}
+				if tt.numbered && !strings.HasSuffix(chunk, chunkSuffix(i+1, len(chunks))) {
+					t.Errorf("post %d = %q, want it to end with its number", i, chunk)
+				}
+			}
+		})
+	}
+}
+
+func TestFooterIsNotSplit(t *testing.T) {
+	c := NewClient("user", "token")
+	c.Footer = "— via Bot"
+	// Text that fills a post on its own must still leave room for the footer
+	text := strings.Repeat("a", maxCharLimit)
+
+	got := c.SplitText(text)
+	want := []string{strings.Repeat("a", maxCharLimit-utf8.RuneCountInString(footerSeparator+c.Footer)), strings.Repeat("a", 11) + footerSeparator + c.Footer}
+	if !slices.Equal(got, want) {
+		t.Errorf("SplitText = %q, want %q", got, want)
+	}
+}
+
+func TestFooterPlacementValid(t *testing.T) {
+	for _, p := range []FooterPlacement{FooterLast, FooterFirst} {
+		if !p.Valid() {
+			t.Errorf("%q.Valid() = false, want true", p)
+		}
+	}
+	for _, p := range []FooterPlacement{"", "middle", "Last"} {
+		if p.Valid() {
+			t.Errorf("%q.Valid() = true, want false", p)
+		}
+	}
+}
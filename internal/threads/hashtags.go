@@ -0,0 +1,134 @@
+package threads
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// hashtagPattern matches a hashtag at the start of the text or after whitespace, so
+// URL fragments such as example.com/#section don't count.
+var hashtagPattern = regexp.MustCompile(`(?:^|\s)#[\p{L}\p{N}_]+`)
+
+// CountHashtags returns how many hashtags text contains. Threads only turns the first
+// one into a clickable tag.
+func CountHashtags(text string) int {
+	return len(hashtagPattern.FindAllStringIndex(text, -1))
+}
+
+// maxTopicTagLength is the longest topic tag the Threads API accepts
+const maxTopicTagLength = 50
+
+// ValidateTopicTag checks a topic_tag value: 1 to 50 characters without a leading '#',
+// whitespace, periods or ampersands.
+func ValidateTopicTag(tag string) error {
+	switch {
+	case tag == "":
+		return fmt.Errorf("topic tag is empty")
+	case utf8.RuneCountInString(tag) > maxTopicTagLength:
+		return fmt.Errorf("topic tag is longer than %d characters", maxTopicTagLength)
+	case strings.HasPrefix(tag, "#"):
+		return fmt.Errorf("topic tag must not start with '#'")
+	case strings.IndexFunc(tag, unicode.IsSpace) >= 0:
+		return fmt.Errorf("topic tag must not contain spaces")
+	case strings.ContainsAny(tag, ".&"):
+		return fmt.Errorf("topic tag must not contain '.' or '&'")
+	}
+	return nil
+}
+
+// HashtagPolicy decides what happens to posts with more than one hashtag.
+type HashtagPolicy string
+
+const (
+	HashtagPolicyIgnore HashtagPolicy = "ignore"
+	HashtagPolicyWarn   HashtagPolicy = "warn"
+	HashtagPolicyReject HashtagPolicy = "reject"
+)
+
+// Valid reports whether p is a known policy.
+func (p HashtagPolicy) Valid() bool {
+	switch p {
+	case HashtagPolicyIgnore, HashtagPolicyWarn, HashtagPolicyReject:
+		return true
+	}
+	return false
+}
+
+// HashtagTopic decides whether hashtags in the text are turned into the topic tag.
+type HashtagTopic string
+
+const (
+	// HashtagTopicOff leaves hashtags in the text alone
+	HashtagTopicOff HashtagTopic = "off"
+	// HashtagTopicFirst makes the first hashtag the topic tag and removes it from the text
+	HashtagTopicFirst HashtagTopic = "first"
+	// HashtagTopicAll also removes every other hashtag from the text
+	HashtagTopicAll HashtagTopic = "all"
+)
+
+// Valid reports whether m is a known mode.
+func (m HashtagTopic) Valid() bool {
+	switch m {
+	case HashtagTopicOff, HashtagTopicFirst, HashtagTopicAll:
+		return true
+	}
+	return false
+}
+
+var (
+	hashtagLineEdge  = regexp.MustCompile(`^(?:\s*#[\p{L}\p{N}_]+)*\s*$`)
+	trailingSpaces   = regexp.MustCompile(`[ \t]+\n`)
+	repeatedSpaces   = regexp.MustCompile(`(\S)[ \t]{2,}`)
+	repeatedNewlines = regexp.MustCompile(`\n{3,}`)
+)
+
+// HashtagToTopic takes the first hashtag in text as a topic tag and removes it from
+// the text, along with all other hashtags when all is set. Hashtags at the start or
+// end of a line, like a trailing "#golang #dev", are dropped entirely; one within a
+// sentence only loses its '#', so the sentence still reads. It returns text unchanged
+// and an empty topic when there is no hashtag usable as a topic tag.
+func HashtagToTopic(text string, all bool) (cleaned, topic string) {
+	matches := hashtagPattern.FindAllStringIndex(text, -1)
+	if len(matches) == 0 {
+		return text, ""
+	}
+
+	first := strings.TrimLeftFunc(text[matches[0][0]:matches[0][1]], unicode.IsSpace)
+	topic = strings.TrimPrefix(first, "#")
+	if ValidateTopicTag(topic) != nil {
+		return text, ""
+	}
+	if !all {
+		matches = matches[:1]
+	}
+
+	// Work backwards so earlier offsets stay valid
+	for i := len(matches) - 1; i >= 0; i-- {
+		start, end := matches[i][0], matches[i][1]
+		// The match may include the whitespace before the '#'
+		start += strings.IndexByte(text[start:end], '#')
+
+		lineStart := strings.LastIndexByte(text[:start], '\n') + 1
+		lineEnd := len(text)
+		if n := strings.IndexByte(text[end:], '\n'); n >= 0 {
+			lineEnd = end + n
+		}
+		switch {
+		case hashtagLineEdge.MatchString(text[lineStart:start]):
+			// Don't leave the line indented by the space that followed the hashtag
+			text = text[:start] + strings.TrimLeft(text[end:], " \t")
+		case hashtagLineEdge.MatchString(text[end:lineEnd]):
+			text = text[:start] + text[end:]
+		default:
+			text = text[:start] + text[start+1:]
+		}
+	}
+
+	text = trailingSpaces.ReplaceAllString(text, "\n")
+	text = repeatedSpaces.ReplaceAllString(text, "$1 ")
+	text = repeatedNewlines.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text), topic
+}
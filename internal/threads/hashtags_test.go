@@ -0,0 +1,112 @@
+package threads
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCountHashtags(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"none", "hello world", 0},
+		{"one", "hello #world", 1},
+		{"at the start", "#go is fun", 1},
+		{"several", "#go #golang\n#dev", 3},
+		{"unicode", "привіт #світ", 1},
+		{"URL fragment", "see example.com/#section", 0},
+		{"inside a word", "C# and F#", 0},
+		{"bare hash", "# heading", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CountHashtags(tt.text); got != tt.want {
+				t.Errorf("CountHashtags(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashtagPolicyValid(t *testing.T) {
+	for _, p := range []HashtagPolicy{HashtagPolicyIgnore, HashtagPolicyWarn, HashtagPolicyReject} {
+		if !p.Valid() {
+			t.Errorf("%q.Valid() = false, want true", p)
+		}
+	}
+	for _, p := range []HashtagPolicy{"", "Reject", "drop"} {
+		if p.Valid() {
+			t.Errorf("%q.Valid() = true, want false", p)
+		}
+	}
+}
+
+func TestValidateTopicTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		tag     string
+		wantErr bool
+	}{
+		{"simple", "golang", false},
+		{"unicode", "програмування", false},
+		{"at the limit", strings.Repeat("ї", maxTopicTagLength), false},
+		{"empty", "", true},
+		{"too long", strings.Repeat("ї", maxTopicTagLength+1), true},
+		{"leading hash", "#golang", true},
+		{"space", "go lang", true},
+		{"tab", "go\tlang", true},
+		{"period", "go.dev", true},
+		{"ampersand", "R&D", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateTopicTag(tt.tag); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTopicTag(%q) = %v, want an error %v", tt.tag, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHashtagToTopic(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		all       bool
+		wantText  string
+		wantTopic string
+	}{
+		{"no hashtag", "Release notes", false, "Release notes", ""},
+		{"trailing hashtag", "Release notes #golang", false, "Release notes", "golang"},
+		{"leading hashtag", "#golang Release notes", false, "Release notes", "golang"},
+		{"within a sentence", "Why #golang is fun", false, "Why golang is fun", "golang"},
+		{"only the first", "Notes #golang #dev", false, "Notes #dev", "golang"},
+		{"all", "Notes #golang #dev", true, "Notes", "golang"},
+		{"all within sentences", "I like #golang and #rust a lot", true, "I like golang and rust a lot", "golang"},
+		{"hashtag line", "Release notes\n\n#golang #dev", true, "Release notes", "golang"},
+		{"unicode", "Привіт #програмування", false, "Привіт", "програмування"},
+		{"URL fragment is not a hashtag", "see example.com/#intro", false, "see example.com/#intro", ""},
+		{"too long for a topic", "Notes #" + strings.Repeat("a", maxTopicTagLength+1), false, "Notes #" + strings.Repeat("a", maxTopicTagLength+1), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, topic := HashtagToTopic(tt.text, tt.all)
+			if text != tt.wantText || topic != tt.wantTopic {
+				t.Errorf("HashtagToTopic(%q, %v) = %q, %q, want %q, %q", tt.text, tt.all, text, topic, tt.wantText, tt.wantTopic)
+			}
+		})
+	}
+}
+
+func TestHashtagTopicValid(t *testing.T) {
+	for _, m := range []HashtagTopic{HashtagTopicOff, HashtagTopicFirst, HashtagTopicAll} {
+		if !m.Valid() {
+			t.Errorf("%q.Valid() = false, want true", m)
+		}
+	}
+	for _, m := range []HashtagTopic{"", "last", "First"} {
+		if m.Valid() {
+			t.Errorf("%q.Valid() = true, want false", m)
+		}
+	}
+}
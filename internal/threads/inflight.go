@@ -0,0 +1,64 @@
+package threads
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/think-root/threads-connector/internal/metrics"
+)
+
+// ErrTooManyPosts is returned by Publish when MaxConcurrentPosts posts are already in
+// flight and RejectWhenBusy is set.
+var ErrTooManyPosts = errors.New("too many posts in flight")
+
+// postLimiter caps how many posts Publish works on at once across all callers.
+type postLimiter struct {
+	once     sync.Once
+	slots    chan struct{}
+	inFlight atomic.Int64
+}
+
+// PostsInFlight returns how many posts are being published right now.
+func (c *Client) PostsInFlight() int {
+	return int(c.posts.inFlight.Load())
+}
+
+// acquirePost takes one of the MaxConcurrentPosts slots, waiting for ctx unless
+// RejectWhenBusy is set. The returned release must be called once the post is done.
+func (c *Client) acquirePost(ctx context.Context) (release func(), err error) {
+	l := &c.posts
+	// The limit is read once, since the fields are set after NewClient
+	l.once.Do(func() {
+		if c.MaxConcurrentPosts > 0 {
+			l.slots = make(chan struct{}, c.MaxConcurrentPosts)
+		}
+	})
+
+	if l.slots != nil {
+		if c.RejectWhenBusy {
+			select {
+			case l.slots <- struct{}{}:
+			default:
+				return nil, ErrTooManyPosts
+			}
+		} else {
+			select {
+			case l.slots <- struct{}{}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+
+	l.inFlight.Add(1)
+	metrics.PostsInFlight.Inc()
+	return func() {
+		l.inFlight.Add(-1)
+		metrics.PostsInFlight.Dec()
+		if l.slots != nil {
+			<-l.slots
+		}
+	}, nil
+}
@@ -0,0 +1,84 @@
+package threads_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/think-root/threads-connector/internal/metrics"
+	"github.com/think-root/threads-connector/internal/threads"
+	"github.com/think-root/threads-connector/internal/threads/threadstest"
+)
+
+func TestMaxConcurrentPosts(t *testing.T) {
+	tests := []struct {
+		name   string
+		max    int
+		reject bool
+		// wantErr is the error of a second post while the first is still publishing
+		wantErr error
+	}{
+		{"no limit", 0, false, nil},
+		{"room for both", 2, true, nil},
+		{"rejected when busy", 1, true, threads.ErrTooManyPosts},
+		{"waits for a slot", 1, false, context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+
+			// Hold container creation of the first post until released
+			entered := make(chan struct{}, 2)
+			gate := make(chan struct{})
+			api.Fail = func(r *http.Request) *threads.APIError {
+				if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/threads") && r.Form.Get("text") == "first" {
+					entered <- struct{}{}
+					<-gate
+				}
+				return nil
+			}
+			c := api.Client()
+			c.MaxConcurrentPosts = tt.max
+			c.RejectWhenBusy = tt.reject
+
+			before := testutil.ToFloat64(metrics.PostsInFlight)
+			done := make(chan error, 1)
+			go func() {
+				_, err := c.Publish(context.Background(), threads.PostParams{Text: "first"})
+				done <- err
+			}()
+			<-entered
+			if n := c.PostsInFlight(); n != 1 {
+				t.Errorf("PostsInFlight = %d while publishing, want 1", n)
+			}
+			if got := testutil.ToFloat64(metrics.PostsInFlight) - before; got != 1 {
+				t.Errorf("posts_in_flight rose by %v, want 1", got)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			_, err := c.Publish(ctx, threads.PostParams{Text: "second"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("second Publish = %v, want %v", err, tt.wantErr)
+			}
+
+			close(gate)
+			if err := <-done; err != nil {
+				t.Fatalf("first Publish = %v", err)
+			}
+			if n := c.PostsInFlight(); n != 0 {
+				t.Errorf("PostsInFlight = %d when done, want 0", n)
+			}
+			// The slot is free again
+			if _, err := c.Publish(context.Background(), threads.PostParams{Text: "third"}); err != nil {
+				t.Errorf("third Publish = %v, want nil", err)
+			}
+		})
+	}
+}
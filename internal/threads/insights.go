@@ -0,0 +1,168 @@
+package threads
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// postInsightMetrics are the metrics requested for a single post
+const postInsightMetrics = "views,likes,replies,reposts,quotes,shares"
+
+// userInsightMetrics are the metrics requested for the account
+const userInsightMetrics = "views,likes,replies,reposts,quotes,followers_count"
+
+// Insights is the engagement of a single post.
+type Insights struct {
+	Views   int64 `json:"views"`
+	Likes   int64 `json:"likes"`
+	Replies int64 `json:"replies"`
+	Reposts int64 `json:"reposts"`
+	Quotes  int64 `json:"quotes"`
+	Shares  int64 `json:"shares"`
+}
+
+// insightsResponse is the metric list returned by the insights endpoints. Each metric
+// reports either a list of values or, for totals, a single total_value.
+type insightsResponse struct {
+	Data []struct {
+		Name   string `json:"name"`
+		Values []struct {
+			Value   int64  `json:"value"`
+			EndTime string `json:"end_time,omitempty"`
+		} `json:"values"`
+		TotalValue *struct {
+			Value int64 `json:"value"`
+		} `json:"total_value"`
+	} `json:"data"`
+}
+
+// metricTotals sums the values of each metric in r.
+func (r insightsResponse) metricTotals() map[string]int64 {
+	totals := make(map[string]int64, len(r.Data))
+	for _, metric := range r.Data {
+		if metric.TotalValue != nil {
+			totals[metric.Name] = metric.TotalValue.Value
+			continue
+		}
+		for _, v := range metric.Values {
+			totals[metric.Name] += v.Value
+		}
+	}
+	return totals
+}
+
+// GetPostInsights fetches the engagement metrics of a published post. It requires the
+// threads_manage_insights permission and returns ErrPostNotFound when there is no such post.
+func (c *Client) GetPostInsights(postID string) (*Insights, error) {
+	return c.GetPostInsightsContext(context.Background(), postID)
+}
+
+// GetPostInsightsContext is like GetPostInsights, with the request bound to ctx.
+func (c *Client) GetPostInsightsContext(ctx context.Context, postID string) (*Insights, error) {
+	params := url.Values{}
+	params.Set("metric", postInsightMetrics)
+
+	endpoint := fmt.Sprintf("%s/%s/insights?%s", c.BaseURL, url.PathEscape(postID), params.Encode())
+
+	resp, err := c.getContext(ctx, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post insights: %w", err)
+	}
+	defer resp.Body.Close()
+	c.recordUsage(resp.Header)
+
+	bodyBytes, err := c.readBody(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if isNotFound(resp.StatusCode, bodyBytes) {
+			return nil, fmt.Errorf("%w: %s", ErrPostNotFound, postID)
+		}
+		return nil, c.parseError(bodyBytes, resp)
+	}
+
+	var result insightsResponse
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse insights: %w", err)
+	}
+
+	totals := result.metricTotals()
+	return &Insights{
+		Views:   totals["views"],
+		Likes:   totals["likes"],
+		Replies: totals["replies"],
+		Reposts: totals["reposts"],
+		Quotes:  totals["quotes"],
+		Shares:  totals["shares"],
+	}, nil
+}
+
+// UserInsights is the account-level engagement over a period. FollowersCount is the
+// current total regardless of the period.
+type UserInsights struct {
+	Views          int64 `json:"views"`
+	Likes          int64 `json:"likes"`
+	Replies        int64 `json:"replies"`
+	Reposts        int64 `json:"reposts"`
+	Quotes         int64 `json:"quotes"`
+	FollowersCount int64 `json:"followers_count"`
+}
+
+// GetUserInsights fetches the account's engagement between since and until; a zero time
+// leaves that end of the range to the API default. It requires the threads_manage_insights
+// permission.
+func (c *Client) GetUserInsights(since, until time.Time) (*UserInsights, error) {
+	return c.GetUserInsightsContext(context.Background(), since, until)
+}
+
+// GetUserInsightsContext is like GetUserInsights, with the request bound to ctx.
+func (c *Client) GetUserInsightsContext(ctx context.Context, since, until time.Time) (*UserInsights, error) {
+	params := url.Values{}
+	params.Set("metric", userInsightMetrics)
+	if !since.IsZero() {
+		params.Set("since", strconv.FormatInt(since.Unix(), 10))
+	}
+	if !until.IsZero() {
+		params.Set("until", strconv.FormatInt(until.Unix(), 10))
+	}
+
+	endpoint := fmt.Sprintf("%s/%s/threads_insights?%s", c.BaseURL, c.UserID, params.Encode())
+
+	resp, err := c.getContext(ctx, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user insights: %w", err)
+	}
+	defer resp.Body.Close()
+	c.recordUsage(resp.Header)
+
+	bodyBytes, err := c.readBody(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.parseError(bodyBytes, resp)
+	}
+
+	var result insightsResponse
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse insights: %w", err)
+	}
+
+	totals := result.metricTotals()
+	return &UserInsights{
+		Views:          totals["views"],
+		Likes:          totals["likes"],
+		Replies:        totals["replies"],
+		Reposts:        totals["reposts"],
+		Quotes:         totals["quotes"],
+		FollowersCount: totals["followers_count"],
+	}, nil
+}
@@ -0,0 +1,49 @@
+package threads_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/think-root/threads-connector/internal/threads"
+	"github.com/think-root/threads-connector/internal/threads/threadstest"
+)
+
+func TestGetPostInsights(t *testing.T) {
+	api := threadstest.NewServer()
+	defer api.Close()
+	api.Insights = map[string]int64{"views": 1520, "likes": 87, "replies": 12, "reposts": 5, "quotes": 2, "shares": 9}
+	c := api.Client()
+
+	postID, err := c.CreatePost(threads.PostParams{Text: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.GetPostInsights(postID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := threads.Insights{Views: 1520, Likes: 87, Replies: 12, Reposts: 5, Quotes: 2, Shares: 9}
+	if *got != want {
+		t.Errorf("GetPostInsights = %+v, want %+v", *got, want)
+	}
+
+	if _, err := c.GetPostInsights("post-404"); !errors.Is(err, threads.ErrPostNotFound) {
+		t.Errorf("GetPostInsights of an unknown post = %v, want ErrPostNotFound", err)
+	}
+}
+
+func TestGetUserInsights(t *testing.T) {
+	api := threadstest.NewServer()
+	defer api.Close()
+	api.Insights = map[string]int64{"views": 48210, "likes": 1930, "replies": 214, "reposts": 96, "quotes": 31, "followers_count": 5120}
+
+	got, err := api.Client().GetUserInsights(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := threads.UserInsights{Views: 48210, Likes: 1930, Replies: 214, Reposts: 96, Quotes: 31, FollowersCount: 5120}
+	if *got != want {
+		t.Errorf("GetUserInsights = %+v, want %+v", *got, want)
+	}
+}
@@ -0,0 +1,16 @@
+package threads
+
+import (
+	"regexp"
+	"strings"
+)
+
+// urlPattern matches http and https links up to the next whitespace.
+var urlPattern = regexp.MustCompile(`https?://[^\s]+`)
+
+// FirstURL returns the first http or https link in text, or "" if there is none.
+// Punctuation that usually ends the surrounding sentence rather than the link,
+// such as a trailing period or closing parenthesis, is not included.
+func FirstURL(text string) string {
+	return strings.TrimRight(urlPattern.FindString(text), `.,;:!?'")]}`)
+}
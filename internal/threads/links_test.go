@@ -0,0 +1,28 @@
+package threads
+
+import "testing"
+
+func TestFirstURL(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"none", "no links here", ""},
+		{"one", "read https://example.com/a now", "https://example.com/a"},
+		{"first of several", "http://one.example and https://two.example", "http://one.example"},
+		{"end of sentence", "See https://example.com/docs.", "https://example.com/docs"},
+		{"in parentheses", "(details: https://example.com/x?a=1)", "https://example.com/x?a=1"},
+		{"quoted", `"https://example.com"!`, "https://example.com"},
+		{"no scheme", "example.com/docs", ""},
+		{"other scheme", "ftp://example.com", ""},
+		{"query and fragment", "https://example.com/p?q=go#top", "https://example.com/p?q=go#top"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FirstURL(tt.text); got != tt.want {
+				t.Errorf("FirstURL(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
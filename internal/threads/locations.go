@@ -0,0 +1,64 @@
+package threads
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// locationFields are the fields requested when searching locations
+const locationFields = "id,name,address,city,country,latitude,longitude,postal_code"
+
+// Location is a place that can be tagged on a post via PostParams.LocationID.
+type Location struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	Address    string  `json:"address,omitempty"`
+	City       string  `json:"city,omitempty"`
+	Country    string  `json:"country,omitempty"`
+	Latitude   float64 `json:"latitude,omitempty"`
+	Longitude  float64 `json:"longitude,omitempty"`
+	PostalCode string  `json:"postal_code,omitempty"`
+}
+
+// SearchLocations looks up places matching query, so a place name can be resolved to
+// a location ID. It requires the threads_location_tagging permission.
+func (c *Client) SearchLocations(query string) ([]Location, error) {
+	return c.SearchLocationsContext(context.Background(), query)
+}
+
+// SearchLocationsContext is like SearchLocations, with the request bound to ctx.
+func (c *Client) SearchLocationsContext(ctx context.Context, query string) ([]Location, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("fields", locationFields)
+
+	endpoint := fmt.Sprintf("%s/location_search?%s", c.BaseURL, params.Encode())
+
+	resp, err := c.getContext(ctx, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search locations: %w", err)
+	}
+	defer resp.Body.Close()
+	c.recordUsage(resp.Header)
+
+	bodyBytes, err := c.readBody(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.parseError(bodyBytes, resp)
+	}
+
+	var result struct {
+		Data []Location `json:"data"`
+	}
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse location search response: %w", err)
+	}
+
+	return result.Data, nil
+}
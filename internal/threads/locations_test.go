@@ -0,0 +1,33 @@
+package threads_test
+
+import (
+	"testing"
+
+	"github.com/think-root/threads-connector/internal/threads"
+	"github.com/think-root/threads-connector/internal/threads/threadstest"
+)
+
+func TestSearchLocations(t *testing.T) {
+	api := threadstest.NewServer()
+	defer api.Close()
+	api.Locations = []threads.Location{
+		{ID: "101", Name: "Kyiv Pechersk Lavra", City: "Kyiv", Country: "UA", Latitude: 50.4344, Longitude: 30.5574},
+		{ID: "102", Name: "Lviv Opera", City: "Lviv", Country: "UA"},
+	}
+
+	got, err := api.Client().SearchLocations("lavra")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != api.Locations[0] {
+		t.Errorf("SearchLocations = %+v, want %+v", got, api.Locations[:1])
+	}
+
+	got, err = api.Client().SearchLocations("nowhere")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("SearchLocations of an unknown place = %+v, want none", got)
+	}
+}
@@ -0,0 +1,99 @@
+package threads
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	mdFence      = regexp.MustCompile("^\\s*(```|~~~)")
+	mdHeading    = regexp.MustCompile(`^\s{0,3}#{1,6}\s+(.*?)(?:\s+#+)?\s*$`)
+	mdRule       = regexp.MustCompile(`^\s{0,3}([-*_])(?:\s*([-*_])){2,}\s*$`)
+	mdQuote      = regexp.MustCompile(`^\s{0,3}>\s?`)
+	mdBullet     = regexp.MustCompile(`^(\s*)[-*+]\s+`)
+	mdInlineCode = regexp.MustCompile("`([^`]+)`")
+	mdImage      = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	mdLink       = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	mdAutolink   = regexp.MustCompile(`<(https?://[^>\s]+)>`)
+	mdBold       = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*|__(\S(?:.*?\S)?)__`)
+	mdStrike     = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+	mdItalicStar = regexp.MustCompile(`\*(\S(?:[^*]*?\S)?)\*`)
+	// Underscores only mark emphasis at word edges, so snake_case survives
+	mdItalicUnderscore = regexp.MustCompile(`(^|[^\p{L}\p{N}_])_(\S(?:[^_]*?\S)?)_($|[^\p{L}\p{N}_])`)
+	mdEscape           = regexp.MustCompile("\\\\([\\\\`*_{}\\[\\]()#+\\-.!>~|])")
+)
+
+// MarkdownToText flattens common Markdown into plain text, since Threads shows Markdown
+// syntax literally. Emphasis, headings, quotes and code markers are dropped, links become
+// "text (url)", images become their alt text and URL, and bullet list items start
+// with "•". Fenced code blocks are kept verbatim without their fences.
+func MarkdownToText(md string) string {
+	lines := strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")
+	out := make([]string, 0, len(lines))
+
+	inFence := false
+	for _, line := range lines {
+		if mdFence.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			out = append(out, line)
+			continue
+		}
+
+		if mdRule.MatchString(line) {
+			out = append(out, "")
+			continue
+		}
+		line = mdQuote.ReplaceAllString(line, "")
+		if m := mdHeading.FindStringSubmatch(line); m != nil {
+			line = m[1]
+		}
+		line = mdBullet.ReplaceAllString(line, "${1}• ")
+		out = append(out, markdownInline(line))
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// markdownInline flattens the inline Markdown of a single line. Code spans and escaped
+// characters are set aside first so they aren't mistaken for markup.
+func markdownInline(line string) string {
+	var spans, escaped []string
+	line = mdInlineCode.ReplaceAllStringFunc(line, func(m string) string {
+		spans = append(spans, mdInlineCode.FindStringSubmatch(m)[1])
+		return "\x00"
+	})
+	line = mdEscape.ReplaceAllStringFunc(line, func(m string) string {
+		escaped = append(escaped, m[1:])
+		return "\x01"
+	})
+
+	line = mdImage.ReplaceAllStringFunc(line, func(m string) string {
+		sub := mdImage.FindStringSubmatch(m)
+		if sub[1] == "" {
+			return sub[2]
+		}
+		return sub[1] + " (" + sub[2] + ")"
+	})
+	line = mdLink.ReplaceAllStringFunc(line, func(m string) string {
+		sub := mdLink.FindStringSubmatch(m)
+		if sub[1] == sub[2] {
+			return sub[2]
+		}
+		return sub[1] + " (" + sub[2] + ")"
+	})
+	line = mdAutolink.ReplaceAllString(line, "$1")
+	line = mdBold.ReplaceAllString(line, "$1$2")
+	line = mdStrike.ReplaceAllString(line, "$1")
+	line = mdItalicStar.ReplaceAllString(line, "$1")
+	line = mdItalicUnderscore.ReplaceAllString(line, "$1$2$3")
+
+	for _, char := range escaped {
+		line = strings.Replace(line, "\x01", char, 1)
+	}
+	for _, span := range spans {
+		line = strings.Replace(line, "\x00", span, 1)
+	}
+	return line
+}
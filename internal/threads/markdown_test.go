@@ -0,0 +1,40 @@
+package threads
+
+import "testing"
+
+func TestMarkdownToText(t *testing.T) {
+	tests := []struct {
+		name string
+		md   string
+		want string
+	}{
+		{"plain text", "just text", "just text"},
+		{"bold and italic", "**bold**, __also__, *italic* and _this_", "bold, also, italic and this"},
+		{"strikethrough", "~~gone~~ here", "gone here"},
+		{"snake_case survives", "set max_retry_count", "set max_retry_count"},
+		{"lone asterisk", "5 * 3 = 15", "5 * 3 = 15"},
+		{"heading", "## Release notes ##", "Release notes"},
+		{"hashtag is not a heading", "#golang rocks", "#golang rocks"},
+		{"link", "see [the docs](https://example.com/docs)", "see the docs (https://example.com/docs)"},
+		{"link with a title", `[docs](https://example.com "Docs")`, "docs (https://example.com)"},
+		{"link to itself", "[https://example.com](https://example.com)", "https://example.com"},
+		{"autolink", "<https://example.com>", "https://example.com"},
+		{"image", "![a cat](https://example.com/cat.jpg)", "a cat (https://example.com/cat.jpg)"},
+		{"image without alt text", "![](https://example.com/cat.jpg)", "https://example.com/cat.jpg"},
+		{"bullets", "- one\n* two\n  + nested", "• one\n• two\n  • nested"},
+		{"quote", "> quoted\n>also", "quoted\nalso"},
+		{"rule", "above\n\n---\n\nbelow", "above\n\n\n\nbelow"},
+		{"inline code keeps markup", "run `**not bold**` now", "run **not bold** now"},
+		{"escaped characters", `\*literal\* \# \[x\]`, "*literal* # [x]"},
+		{"fenced code", "before\n```go\nx := *p\n# not a heading\n```\nafter", "before\nx := *p\n# not a heading\nafter"},
+		{"CRLF line endings", "**a**\r\n- b", "a\n• b"},
+		{"surrounding blank lines", "\n\n# Title\n\n", "Title"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MarkdownToText(tt.md); got != tt.want {
+				t.Errorf("MarkdownToText(%q) = %q, want %q", tt.md, got, tt.want)
+			}
+		})
+	}
+}
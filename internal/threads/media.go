@@ -0,0 +1,126 @@
+package threads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// ErrInvalidMediaURL is returned when an image or video URL fails pre-flight validation.
+var ErrInvalidMediaURL = errors.New("invalid media URL")
+
+// MediaURLError is the error ValidateMedia returns for a specific media URL. It wraps
+// ErrInvalidMediaURL.
+type MediaURLError struct {
+	// Field names the offending URL as in the request, e.g. "image_urls[3]"
+	Field  string
+	Reason string
+}
+
+func (e *MediaURLError) Error() string {
+	return fmt.Sprintf("%v: %s %s", ErrInvalidMediaURL, e.Field, e.Reason)
+}
+
+func (e *MediaURLError) Unwrap() error {
+	return ErrInvalidMediaURL
+}
+
+// ValidateMedia checks the media URLs of p before any container is created, so a typo
+// surfaces as a clear error instead of a container ERROR status. URLs must be absolute
+// https URLs; when CheckMediaURLs is set they are also probed with a HEAD request, which
+// also enforces MaxImageBytes and MaxVideoBytes. Errors are *MediaURLError.
+func (c *Client) ValidateMedia(ctx context.Context, p PostParams) error {
+	if p.ImageURL != "" {
+		if err := c.validateMediaURL(ctx, "image_url", p.ImageURL, "image/"); err != nil {
+			return err
+		}
+	}
+	for i, imageURL := range p.ImageURLs {
+		if err := c.validateMediaURL(ctx, fmt.Sprintf("image_urls[%d]", i), imageURL, "image/"); err != nil {
+			return err
+		}
+	}
+	if p.VideoURL != "" {
+		if err := c.validateMediaURL(ctx, "video_url", p.VideoURL, "video/"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) validateMediaURL(ctx context.Context, field, rawURL, contentTypePrefix string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return &MediaURLError{Field: field, Reason: "is not a valid URL"}
+	}
+	if parsed.Scheme != "https" {
+		return &MediaURLError{Field: field, Reason: "must use https"}
+	}
+	if contentTypePrefix == "image/" {
+		if err := checkGIF(field, rawURL, ""); err != nil {
+			return err
+		}
+	}
+
+	if !c.CheckMediaURLs {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, rawURL, nil)
+	if err != nil {
+		return &MediaURLError{Field: field, Reason: "is not a valid URL"}
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &MediaURLError{Field: field, Reason: fmt.Sprintf("is not reachable: %v", err)}
+	}
+	resp.Body.Close()
+
+	// Some hosts don't implement HEAD; that says nothing about the media itself
+	if resp.StatusCode == http.StatusMethodNotAllowed {
+		return nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &MediaURLError{Field: field, Reason: fmt.Sprintf("returned %s", resp.Status)}
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, contentTypePrefix) {
+		return &MediaURLError{Field: field, Reason: fmt.Sprintf("has content type %q, expected %s*", contentType, contentTypePrefix)}
+	}
+	if err := checkGIF(field, "", contentType); err != nil {
+		return err
+	}
+	maxBytes := c.MaxImageBytes
+	if contentTypePrefix == "video/" {
+		maxBytes = c.MaxVideoBytes
+	}
+	// Hosts that don't report a length get the benefit of the doubt
+	if maxBytes > 0 && resp.ContentLength > maxBytes {
+		return &MediaURLError{Field: field, Reason: fmt.Sprintf("is %d bytes, more than the limit of %d", resp.ContentLength, maxBytes)}
+	}
+	return nil
+}
+
+// checkGIF returns a *MediaURLError for field when rawURL has a .gif extension or
+// contentType is image/gif. Image containers only accept JPEG and PNG; a GIF fails
+// processing or loses its animation, so it is rejected up front. Animated GIFs go
+// through gif_attachment instead.
+func checkGIF(field, rawURL, contentType string) error {
+	isGIF := strings.HasPrefix(contentType, "image/gif")
+	if parsed, err := url.Parse(rawURL); err == nil && strings.EqualFold(path.Ext(parsed.Path), ".gif") {
+		isGIF = true
+	}
+	if !isGIF {
+		return nil
+	}
+	return &MediaURLError{Field: field, Reason: "is a GIF, which Threads doesn't accept as an image; use a Tenor GIF ID instead"}
+}
@@ -0,0 +1,142 @@
+package threads
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// mediaHost serves HEAD requests for media URLs with the content type, length and
+// status given by the "type", "length" and "status" query parameters.
+func mediaHost(t *testing.T) (*Client, string) {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		w.Header().Set("Content-Type", query.Get("type"))
+		if length := query.Get("length"); length != "" {
+			w.Header().Set("Content-Length", length)
+		}
+		if status, err := strconv.Atoi(query.Get("status")); err == nil {
+			w.WriteHeader(status)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	c := NewClientWithHTTP("user", "token", srv.Client())
+	c.CheckMediaURLs = true
+	return c, srv.URL
+}
+
+func TestValidateMedia(t *testing.T) {
+	c, host := mediaHost(t)
+	c.MaxImageBytes = 1000
+	c.MaxVideoBytes = 5000
+
+	closed := httptest.NewTLSServer(http.NotFoundHandler())
+	closed.Close()
+
+	tests := []struct {
+		name    string
+		params  PostParams
+		wantErr string
+	}{
+		{"reachable image", PostParams{ImageURL: host + "/a.jpg?type=image/jpeg&length=1000"}, ""},
+		{"reachable video", PostParams{VideoURL: host + "/a.mp4?type=video/mp4"}, ""},
+		{"HEAD not allowed", PostParams{ImageURL: host + "/a.jpg?status=405"}, ""},
+		{"malformed URL", PostParams{ImageURL: "https://exa mple.com/a.jpg"}, "image_url is not a valid URL"},
+		{"relative URL", PostParams{ImageURL: "/a.jpg"}, "image_url is not a valid URL"},
+		{"plain http", PostParams{VideoURL: "http://example.com/a.mp4"}, "video_url must use https"},
+		{"unreachable host", PostParams{ImageURL: closed.URL + "/a.jpg"}, "image_url is not reachable"},
+		{"not found", PostParams{ImageURL: host + "/a.jpg?status=404"}, "image_url returned 404 Not Found"},
+		{"wrong content type", PostParams{ImageURL: host + "/a.jpg?type=text/html"}, `image_url has content type "text/html", expected image/*`},
+		{"video as image", PostParams{ImageURL: host + "/a.mp4?type=video/mp4"}, "expected image/*"},
+		{"too large", PostParams{ImageURL: host + "/a.jpg?type=image/jpeg&length=1001"}, "image_url is 1001 bytes, more than the limit of 1000"},
+		{"bad carousel item", PostParams{ImageURLs: []string{host + "/a.jpg?type=image/jpeg", "ftp://example.com/b.jpg"}}, "image_urls[1] must use https"},
+		{"video under the video limit", PostParams{VideoURL: host + "/a.mp4?type=video/mp4&length=5000"}, ""},
+		{"video too large", PostParams{VideoURL: host + "/a.mp4?type=video/mp4&length=5001"}, "video_url is 5001 bytes, more than the limit of 5000"},
+		{"carousel item too large", PostParams{ImageURLs: []string{host + "/a.jpg?type=image/jpeg&length=10", host + "/b.jpg?type=image/jpeg&length=10", host + "/c.jpg?type=image/jpeg&length=2000"}}, "image_urls[2] is 2000 bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.ValidateMedia(context.Background(), tt.params)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateMedia = %v, want nil", err)
+				}
+				return
+			}
+			var mediaErr *MediaURLError
+			if !errors.As(err, &mediaErr) || !errors.Is(err, ErrInvalidMediaURL) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ValidateMedia = %v, want *MediaURLError containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateMediaSkipsProbe(t *testing.T) {
+	c := NewClient("user", "token")
+	closed := httptest.NewTLSServer(http.NotFoundHandler())
+	closed.Close()
+
+	// Without CheckMediaURLs only the URL itself is checked
+	if err := c.ValidateMedia(context.Background(), PostParams{ImageURL: closed.URL + "/a.jpg"}); err != nil {
+		t.Errorf("ValidateMedia of an unreachable URL = %v, want nil", err)
+	}
+	if err := c.ValidateMedia(context.Background(), PostParams{ImageURL: "https://"}); !errors.Is(err, ErrInvalidMediaURL) {
+		t.Errorf("ValidateMedia of a URL without a host = %v, want ErrInvalidMediaURL", err)
+	}
+}
+
+func TestValidateMediaGIF(t *testing.T) {
+	c, host := mediaHost(t)
+
+	tests := []struct {
+		name    string
+		params  PostParams
+		wantErr string
+	}{
+		{"jpeg", PostParams{ImageURL: host + "/a.jpg?type=image/jpeg"}, ""},
+		{"gif extension", PostParams{ImageURL: host + "/a.gif?type=image/jpeg"}, "image_url is a GIF"},
+		{"upper case gif extension", PostParams{ImageURL: host + "/a.GIF?type=image/jpeg"}, "image_url is a GIF"},
+		{"gif content type", PostParams{ImageURL: host + "/a?type=image/gif"}, "image_url is a GIF"},
+		{"gif carousel item", PostParams{ImageURLs: []string{host + "/a.jpg?type=image/jpeg", host + "/b?type=image/gif"}}, "image_urls[1] is a GIF"},
+		{"gif extension on a video", PostParams{VideoURL: host + "/a.gif?type=video/mp4"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.ValidateMedia(context.Background(), tt.params)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateMedia = %v, want nil", err)
+				}
+				return
+			}
+			var mediaErr *MediaURLError
+			if !errors.As(err, &mediaErr) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ValidateMedia = %v, want *MediaURLError containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreateMediaContainerRejectsGIF(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer srv.Close()
+
+	c := NewClientWithHTTP("user", "token", srv.Client())
+	c.BaseURL = srv.URL
+	_, err := c.createMediaContainer(context.Background(), containerParams{ImageURL: "https://example.com/a.gif"})
+	if !errors.Is(err, ErrInvalidMediaURL) {
+		t.Fatalf("createMediaContainer = %v, want ErrInvalidMediaURL", err)
+	}
+	if requests != 0 {
+		t.Errorf("sent %d requests, want none", requests)
+	}
+}
@@ -0,0 +1,100 @@
+package threads
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// mentionPattern matches an @handle that isn't part of an email address or URL. Threads
+// usernames are up to 30 letters, digits, periods and underscores.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w.@/])@([A-Za-z0-9._]{1,30})`)
+
+// Mentions returns the usernames mentioned in text, without the '@', in order of first
+// appearance and without duplicates.
+func Mentions(text string) []string {
+	var handles []string
+	seen := make(map[string]bool)
+	for _, m := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		// A period ending the sentence is not part of the handle
+		handle := strings.TrimRight(m[1], ".")
+		key := strings.ToLower(handle)
+		if handle == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		handles = append(handles, handle)
+	}
+	return handles
+}
+
+// ValidateMentions checks that every account mentioned in text exists, so a typo in a
+// handle is caught before it is posted as plain text. The error wraps ErrInvalidPost and
+// lists the unknown handles. It requires the threads_profile_discovery permission.
+func (c *Client) ValidateMentions(text string) error {
+	return c.ValidateMentionsContext(context.Background(), text)
+}
+
+// ValidateMentionsContext is like ValidateMentions, with the lookups bound to ctx.
+func (c *Client) ValidateMentionsContext(ctx context.Context, text string) error {
+	var unknown []string
+	for _, handle := range Mentions(text) {
+		exists, err := c.profileExists(ctx, handle)
+		if err != nil {
+			return fmt.Errorf("failed to look up @%s: %w", handle, err)
+		}
+		if !exists {
+			unknown = append(unknown, "@"+handle)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("%w: unknown mentioned accounts: %s", ErrInvalidPost, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// profileExists looks up a public profile by username.
+func (c *Client) profileExists(ctx context.Context, username string) (bool, error) {
+	params := url.Values{}
+	params.Set("username", username)
+
+	endpoint := fmt.Sprintf("%s/profile_lookup?%s", c.BaseURL, params.Encode())
+
+	resp, err := c.getContext(ctx, endpoint)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up profile: %w", err)
+	}
+	defer resp.Body.Close()
+	c.recordUsage(resp.Header)
+
+	bodyBytes, err := c.readBody(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if isNotFound(resp.StatusCode, bodyBytes) {
+			return false, nil
+		}
+		// An unknown username is reported as an invalid parameter
+		err := c.parseError(bodyBytes, resp)
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.Code == ErrorCodeInvalidParameter {
+			return false, nil
+		}
+		return false, err
+	}
+
+	var result struct {
+		Username string `json:"username"`
+	}
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return false, fmt.Errorf("failed to parse profile: %w", err)
+	}
+	return result.Username != "", nil
+}
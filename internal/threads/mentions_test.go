@@ -0,0 +1,96 @@
+package threads_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/think-root/threads-connector/internal/threads"
+	"github.com/think-root/threads-connector/internal/threads/threadstest"
+)
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"none", "hello there", nil},
+		{"one", "thanks @zuck!", []string{"zuck"}},
+		{"at the start", "@zuck hi", []string{"zuck"}},
+		{"several in order", "@b.one and @a_two", []string{"b.one", "a_two"}},
+		{"duplicates ignore case", "@Zuck @zuck @ZUCK", []string{"Zuck"}},
+		{"end of sentence", "ask @zuck.", []string{"zuck"}},
+		{"email", "mail me@example.com", nil},
+		{"URL path", "see https://threads.net/@zuck", nil},
+		{"bare at sign", "meet @ noon", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := threads.Mentions(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("Mentions(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateMentions(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		text        string
+		wantInvalid bool
+		wantErr     error
+		wantHandles string
+	}{
+		{"no mentions", context.Background(), "hello there", false, nil, ""},
+		{"known account", context.Background(), "thanks @zuck!", false, nil, ""},
+		{"unknown account", context.Background(), "thanks @zuck and @nosuchuser", true, threads.ErrInvalidPost, "@nosuchuser"},
+		{"email is not a mention", context.Background(), "mail me@example.com", false, nil, ""},
+		{"canceled", canceled, "thanks @zuck", false, context.Canceled, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+			api.Usernames = []string{"zuck"}
+
+			err := api.Client().ValidateMentionsContext(tt.ctx, tt.text)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantHandles != "" && !strings.Contains(err.Error(), tt.wantHandles) {
+				t.Errorf("error = %v, want it to name %s", err, tt.wantHandles)
+			}
+		})
+	}
+}
+
+func TestPublishChecksMentions(t *testing.T) {
+	api := threadstest.NewServer()
+	defer api.Close()
+	c := api.Client()
+	c.CheckMentions = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Publish(ctx, threads.PostParams{Text: "hi @zuck"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled publish: error = %v, want context.Canceled", err)
+	}
+	if _, err := c.Publish(context.Background(), threads.PostParams{Text: "hi @nosuchuser"}); !errors.Is(err, threads.ErrInvalidPost) {
+		t.Errorf("unknown mention: error = %v, want ErrInvalidPost", err)
+	}
+	if n := len(api.Containers()); n != 0 {
+		t.Errorf("created %d containers, want 0", n)
+	}
+
+	api.Usernames = []string{"zuck"}
+	if _, err := c.Publish(context.Background(), threads.PostParams{Text: "hi @zuck"}); err != nil {
+		t.Errorf("known mention: error = %v, want nil", err)
+	}
+}
@@ -0,0 +1,275 @@
+package threads
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/think-root/threads-connector/internal/logging"
+)
+
+// ErrPostNotFound is returned when the requested post doesn't exist or isn't accessible.
+var ErrPostNotFound = errors.New("post not found")
+
+// postFields are the fields requested when fetching a post
+const postFields = "id,media_product_type,media_type,media_url,permalink,owner,username,text,timestamp,shortcode,is_quote_post"
+
+// Post is a published Threads post.
+type Post struct {
+	ID               string `json:"id"`
+	MediaProductType string `json:"media_product_type"`
+	MediaType        string `json:"media_type"`
+	MediaURL         string `json:"media_url,omitempty"`
+	Permalink        string `json:"permalink"`
+	Username         string `json:"username"`
+	Text             string `json:"text"`
+	Timestamp        string `json:"timestamp"`
+	Shortcode        string `json:"shortcode"`
+	IsQuotePost      bool   `json:"is_quote_post"`
+}
+
+// GetPost fetches a published post. It returns ErrPostNotFound when there is no such post.
+func (c *Client) GetPost(postID string) (*Post, error) {
+	return c.GetPostContext(context.Background(), postID)
+}
+
+// GetPostContext is like GetPost, with the request bound to ctx.
+func (c *Client) GetPostContext(ctx context.Context, postID string) (*Post, error) {
+	params := url.Values{}
+	params.Set("fields", postFields)
+
+	endpoint := fmt.Sprintf("%s/%s?%s", c.BaseURL, url.PathEscape(postID), params.Encode())
+
+	resp, err := c.getContext(ctx, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	defer resp.Body.Close()
+	c.recordUsage(resp.Header)
+
+	bodyBytes, err := c.readBody(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if isNotFound(resp.StatusCode, bodyBytes) {
+			return nil, fmt.Errorf("%w: %s", ErrPostNotFound, postID)
+		}
+		return nil, c.parseError(bodyBytes, resp)
+	}
+
+	var post Post
+	if err := json.Unmarshal(bodyBytes, &post); err != nil {
+		return nil, fmt.Errorf("failed to parse post: %w", err)
+	}
+	if post.ID == "" {
+		return nil, fmt.Errorf("failed to parse post: response has no id")
+	}
+
+	return &post, nil
+}
+
+// ListPosts returns a page of the user's posts, newest first. Pass the returned
+// nextCursor back in to fetch the following page; it is empty on the last page.
+func (c *Client) ListPosts(limit int, cursor string) (posts []Post, nextCursor string, err error) {
+	return c.ListPostsContext(context.Background(), limit, cursor)
+}
+
+// ListPostsContext is like ListPosts, with the request bound to ctx.
+func (c *Client) ListPostsContext(ctx context.Context, limit int, cursor string) (posts []Post, nextCursor string, err error) {
+	params := url.Values{}
+	params.Set("fields", postFields)
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	if cursor != "" {
+		params.Set("after", cursor)
+	}
+
+	endpoint := fmt.Sprintf("%s/%s/threads?%s", c.BaseURL, c.UserID, params.Encode())
+
+	resp, err := c.getContext(ctx, endpoint)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list posts: %w", err)
+	}
+	defer resp.Body.Close()
+	c.recordUsage(resp.Header)
+
+	bodyBytes, err := c.readBody(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", c.parseError(bodyBytes, resp)
+	}
+
+	var result struct {
+		Data   []Post `json:"data"`
+		Paging struct {
+			Cursors struct {
+				After string `json:"after"`
+			} `json:"cursors"`
+			Next string `json:"next"`
+		} `json:"paging"`
+	}
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return nil, "", fmt.Errorf("failed to parse posts: %w", err)
+	}
+
+	// The API keeps returning cursors on the last page; only "next" says there is more
+	if result.Paging.Next != "" {
+		nextCursor = result.Paging.Cursors.After
+	}
+	return result.Data, nextCursor, nil
+}
+
+// recentPostsLimit is how many of the latest posts FindRecentPost checks.
+const recentPostsLimit = 25
+
+// recentPostWindow is how far back FindRecentPost looks for a matching post.
+const recentPostWindow = time.Hour
+
+// postTimeLayout is the format of Post.Timestamp.
+const postTimeLayout = "2006-01-02T15:04:05-0700"
+
+// FindRecentPost looks for a post published within the last hour whose text is text,
+// or the first post CreatePost would have split text into. It tells whether a post
+// whose outcome is unknown, e.g. because the request timed out after publishing, went
+// through, so it isn't retried into a duplicate. Only the latest page of posts is checked.
+func (c *Client) FindRecentPost(text string) (postID string, found bool, err error) {
+	return c.FindRecentPostContext(context.Background(), text)
+}
+
+// FindRecentPostContext is like FindRecentPost, with the request bound to ctx.
+func (c *Client) FindRecentPostContext(ctx context.Context, text string) (postID string, found bool, err error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", false, nil
+	}
+	candidates := []string{text}
+	if chunks := c.chunkText(text); len(chunks) > 0 {
+		candidates = append(candidates, chunks[0])
+	}
+
+	posts, _, err := c.ListPostsContext(ctx, recentPostsLimit, "")
+	if err != nil {
+		return "", false, err
+	}
+
+	since := time.Now().Add(-recentPostWindow)
+	for _, post := range posts {
+		// Posts come newest first, so the rest are older still
+		if published, err := time.Parse(postTimeLayout, post.Timestamp); err == nil && published.Before(since) {
+			break
+		}
+		if slices.Contains(candidates, strings.TrimSpace(post.Text)) {
+			return post.ID, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// waitForPostAvailable polls until postID can be fetched, pausing a little longer after
+// each miss. A freshly published post takes a moment to propagate, and replies to it fail
+// until then. If the post still isn't available after timeout, it gives up waiting and
+// returns nil, leaving the reply to be attempted anyway.
+func (c *Client) waitForPostAvailable(ctx context.Context, postID string, timeout time.Duration) error {
+	logger := logging.FromContext(ctx)
+	deadline := time.Now().Add(timeout)
+	interval := postAvailablePollInterval
+
+	for attempt := 1; ; attempt++ {
+		_, err := c.GetPostContext(ctx, postID)
+		if err == nil {
+			logger.Info("Post is available", "post_id", postID, "attempts", attempt)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			logger.Warn("Post still not available, continuing anyway", "post_id", postID, "waited", timeout, "error", err)
+			return nil
+		}
+		logger.Info("Post not available yet", "post_id", postID, "attempt", attempt, "error", err)
+
+		if err := SleepContext(ctx, min(interval, remaining)); err != nil {
+			return err
+		}
+		interval = min(interval*2, maxPostAvailablePollInterval)
+	}
+}
+
+// DeletePost deletes a published post. It returns ErrPostNotFound when there is no such post.
+func (c *Client) DeletePost(postID string) error {
+	return c.DeletePostContext(context.Background(), postID)
+}
+
+// DeletePostContext is like DeletePost, with the request bound to ctx.
+func (c *Client) DeletePostContext(ctx context.Context, postID string) error {
+	endpoint := fmt.Sprintf("%s/%s", c.BaseURL, url.PathEscape(postID))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build delete request: %w", err)
+	}
+	c.authorize(req)
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to delete post: %w", err)
+	}
+	defer resp.Body.Close()
+	c.recordUsage(resp.Header)
+
+	bodyBytes, err := c.readBody(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	c.logDecodedResponse(ctx, "Threads API delete response", resp.Status, bodyBytes)
+
+	if resp.StatusCode != http.StatusOK {
+		if isNotFound(resp.StatusCode, bodyBytes) {
+			return fmt.Errorf("%w: %s", ErrPostNotFound, postID)
+		}
+		return c.parseError(bodyBytes, resp)
+	}
+
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return fmt.Errorf("failed to parse delete response: %w", err)
+	}
+	if !result.Success {
+		return fmt.Errorf("deletion of post %s was not confirmed", postID)
+	}
+
+	return nil
+}
+
+// isNotFound reports whether an error response means the requested object doesn't exist.
+func isNotFound(statusCode int, body []byte) bool {
+	if statusCode == http.StatusNotFound {
+		return true
+	}
+
+	var errResp APIErrorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil {
+		return false
+	}
+	// Code 100 with subcode 33: the object does not exist or cannot be loaded
+	return errResp.Error.Code == 100 && errResp.Error.ErrorSubcode == 33
+}
@@ -0,0 +1,154 @@
+package threads_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/think-root/threads-connector/internal/threads"
+	"github.com/think-root/threads-connector/internal/threads/threadstest"
+)
+
+func TestContextCanceled(t *testing.T) {
+	api := threadstest.NewServer()
+	defer api.Close()
+	c := api.Client()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"GetPostContext", func() error { _, err := c.GetPostContext(ctx, "1"); return err }},
+		{"ListPostsContext", func() error { _, _, err := c.ListPostsContext(ctx, 10, ""); return err }},
+		{"GetPostInsightsContext", func() error { _, err := c.GetPostInsightsContext(ctx, "1"); return err }},
+		{"GetUserInsightsContext", func() error { _, err := c.GetUserInsightsContext(ctx, time.Time{}, time.Time{}); return err }},
+		{"DeletePostContext", func() error { return c.DeletePostContext(ctx, "1") }},
+		{"SearchLocationsContext", func() error { _, err := c.SearchLocationsContext(ctx, "Kyiv"); return err }},
+		{"ValidateTokenContext", func() error { _, err := c.ValidateTokenContext(ctx); return err }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, context.Canceled) {
+				t.Errorf("error = %v, want context.Canceled", err)
+			}
+		})
+	}
+}
+
+func TestGetPostContext(t *testing.T) {
+	api := threadstest.NewServer()
+	defer api.Close()
+	c := api.Client()
+
+	postID, err := c.CreatePost(threads.PostParams{Text: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	post, err := c.GetPostContext(context.Background(), postID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.ID != postID || post.Text != "hello" {
+		t.Errorf("got post %q with text %q, want %q with text %q", post.ID, post.Text, postID, "hello")
+	}
+
+	if _, err := c.GetPostContext(context.Background(), "missing"); !errors.Is(err, threads.ErrPostNotFound) {
+		t.Errorf("missing post: error = %v, want ErrPostNotFound", err)
+	}
+}
+
+func TestDeletePost(t *testing.T) {
+	tests := []struct {
+		name    string
+		postID  func(published string) string
+		wantErr error
+	}{
+		{"published post", func(published string) string { return published }, nil},
+		{"unknown post", func(string) string { return "post-404" }, threads.ErrPostNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+			c := api.Client()
+
+			published, err := c.CreatePost(threads.PostParams{Text: "hello"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = c.DeletePost(tt.postID(published))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeletePost error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if _, err := c.GetPost(published); !errors.Is(err, threads.ErrPostNotFound) {
+				t.Errorf("GetPost after delete: error = %v, want ErrPostNotFound", err)
+			}
+			// Deleting again finds nothing
+			if err := c.DeletePost(published); !errors.Is(err, threads.ErrPostNotFound) {
+				t.Errorf("second DeletePost: error = %v, want ErrPostNotFound", err)
+			}
+		})
+	}
+}
+
+func TestFindRecentPost(t *testing.T) {
+	var words []string
+	for i := range 150 {
+		words = append(words, fmt.Sprintf("word%d", i))
+	}
+	long := strings.Join(words, " ")
+
+	tests := []struct {
+		name      string
+		published string
+		text      string
+		wantFound bool
+	}{
+		{"same text", "hello world", "hello world", true},
+		{"surrounding space", "hello world", "  hello world\n", true},
+		{"first chunk of a thread", long, long, true},
+		{"no match", "hello world", "goodbye world", false},
+		{"nothing published", "", "hello world", false},
+		{"empty text", "hello world", " ", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+			c := api.Client()
+
+			var wantID string
+			if tt.published != "" {
+				if _, err := c.CreatePost(threads.PostParams{Text: "an older post"}); err != nil {
+					t.Fatal(err)
+				}
+				id, err := c.CreatePost(threads.PostParams{Text: tt.published})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if tt.wantFound {
+					wantID = id
+				}
+			}
+
+			postID, found, err := c.FindRecentPostContext(context.Background(), tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found != tt.wantFound || postID != wantID {
+				t.Errorf("FindRecentPostContext = (%q, %v), want (%q, %v)", postID, found, wantID, tt.wantFound)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package threads
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	// rateLimitThreshold is the usage percentage above which CreatePost slows down
+	rateLimitThreshold = 90
+	throttledPostDelay = 10 * time.Second
+)
+
+// RateLimitStatus is the API usage Meta reports in the X-App-Usage and
+// X-Business-Use-Case-Usage response headers. All values are percentages of the quota.
+type RateLimitStatus struct {
+	CallCount    int
+	TotalCPUTime int
+	TotalTime    int
+	// EstimatedTimeToRegainAccess is set by Meta once a limit has been hit
+	EstimatedTimeToRegainAccess time.Duration
+	UpdatedAt                   time.Time
+}
+
+// Usage returns the highest of the reported percentages.
+func (s RateLimitStatus) Usage() int {
+	return max(s.CallCount, s.TotalCPUTime, s.TotalTime)
+}
+
+type usageHeader struct {
+	CallCount                   int `json:"call_count"`
+	TotalCPUTime                int `json:"total_cputime"`
+	TotalTime                   int `json:"total_time"`
+	EstimatedTimeToRegainAccess int `json:"estimated_time_to_regain_access"`
+}
+
+// RateLimitStatus returns the usage reported by the most recent API response.
+// It is the zero value until the first response carrying usage headers arrives.
+func (c *Client) RateLimitStatus() RateLimitStatus {
+	c.rateMu.Lock()
+	defer c.rateMu.Unlock()
+	return c.rateLimit
+}
+
+// recordUsage updates the rate limit status from the usage headers of an API response.
+// Responses without usage headers leave the previous status untouched.
+func (c *Client) recordUsage(header http.Header) {
+	status, ok := parseUsageHeaders(header)
+	if !ok {
+		return
+	}
+
+	c.rateMu.Lock()
+	c.rateLimit = status
+	c.rateMu.Unlock()
+
+	if status.Usage() >= rateLimitThreshold {
+		slog.Warn("Threads API rate limit usage is high", "usage_percent", status.Usage())
+	}
+}
+
+// parseUsageHeaders combines X-App-Usage and every entry of X-Business-Use-Case-Usage,
+// keeping the highest value reported for each metric.
+func parseUsageHeaders(header http.Header) (RateLimitStatus, bool) {
+	var usages []usageHeader
+
+	if raw := header.Get("X-App-Usage"); raw != "" {
+		var app usageHeader
+		if err := json.Unmarshal([]byte(raw), &app); err == nil {
+			usages = append(usages, app)
+		}
+	}
+
+	if raw := header.Get("X-Business-Use-Case-Usage"); raw != "" {
+		var business map[string][]usageHeader
+		if err := json.Unmarshal([]byte(raw), &business); err == nil {
+			for _, entries := range business {
+				usages = append(usages, entries...)
+			}
+		}
+	}
+
+	if len(usages) == 0 {
+		return RateLimitStatus{}, false
+	}
+
+	status := RateLimitStatus{UpdatedAt: time.Now()}
+	for _, u := range usages {
+		status.CallCount = max(status.CallCount, u.CallCount)
+		status.TotalCPUTime = max(status.TotalCPUTime, u.TotalCPUTime)
+		status.TotalTime = max(status.TotalTime, u.TotalTime)
+		status.EstimatedTimeToRegainAccess = max(status.EstimatedTimeToRegainAccess,
+			time.Duration(u.EstimatedTimeToRegainAccess)*time.Minute)
+	}
+	return status, true
+}
+
+// interPostDelay returns the base pause after publishing post i of a thread.
+func (c *Client) interPostDelay(i int) time.Duration {
+	if i < len(c.InterPostDelays) {
+		return c.InterPostDelays[i]
+	}
+	return c.InterPostDelay
+}
+
+// NextPostDelay is how long to wait before publishing another, independent post:
+// InterPostDelay, or longer while the app is close to its rate limit.
+func (c *Client) NextPostDelay() time.Duration {
+	return c.postDelay(c.InterPostDelay)
+}
+
+// postDelay returns how long to wait between posts of a thread, backing off when
+// the reported usage is close to the limit. The result is jittered by PostDelayJitter.
+func (c *Client) postDelay(base time.Duration) time.Duration {
+	if c.RateLimitStatus().Usage() >= rateLimitThreshold {
+		base = max(base, throttledPostDelay)
+	}
+	return c.jitter(base, c.PostDelayJitter)
+}
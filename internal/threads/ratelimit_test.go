@@ -0,0 +1,50 @@
+package threads
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRecordUsage(t *testing.T) {
+	tests := []struct {
+		name     string
+		app      string
+		business string
+		// want is the status after a response with these headers, following one that
+		// reported 10% of calls
+		want RateLimitStatus
+	}{
+		{"app usage", `{"call_count":28,"total_cputime":25,"total_time":25}`, "", RateLimitStatus{CallCount: 28, TotalCPUTime: 25, TotalTime: 25}},
+		{"business usage", "", `{"1234":[{"type":"threads","call_count":5,"total_cputime":60,"total_time":7,"estimated_time_to_regain_access":3}]}`, RateLimitStatus{CallCount: 5, TotalCPUTime: 60, TotalTime: 7, EstimatedTimeToRegainAccess: 3 * time.Minute}},
+		{"highest of both", `{"call_count":40,"total_cputime":1,"total_time":1}`, `{"1234":[{"call_count":5,"total_time":70}]}`, RateLimitStatus{CallCount: 40, TotalCPUTime: 1, TotalTime: 70}},
+		{"missing headers keep the last status", "", "", RateLimitStatus{CallCount: 10}},
+		{"malformed app usage keeps the last status", `{"call_count":`, "", RateLimitStatus{CallCount: 10}},
+		{"wrong types keep the last status", `{"call_count":"high"}`, "", RateLimitStatus{CallCount: 10}},
+		{"malformed business usage is skipped", `{"call_count":30}`, `not json`, RateLimitStatus{CallCount: 30}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient("user", "token")
+			c.recordUsage(http.Header{"X-App-Usage": {`{"call_count":10}`}})
+
+			header := http.Header{}
+			if tt.app != "" {
+				header.Set("X-App-Usage", tt.app)
+			}
+			if tt.business != "" {
+				header.Set("X-Business-Use-Case-Usage", tt.business)
+			}
+			c.recordUsage(header)
+
+			got := c.RateLimitStatus()
+			if got.UpdatedAt.IsZero() {
+				t.Error("UpdatedAt is zero")
+			}
+			got.UpdatedAt = time.Time{}
+			if got != tt.want {
+				t.Errorf("RateLimitStatus() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
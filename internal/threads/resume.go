@@ -0,0 +1,72 @@
+package threads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ContinueThread publishes chunks as consecutive replies under parentID, picking up a
+// thread that failed part way: parentID is the last post that went live, and chunks are
+// the ones that didn't, e.g. from SplitText. They are posted as given, without splitting,
+// footer or numbering. PostResult.ID is the first new post. A parentID that doesn't exist
+// is rejected with ErrInvalidPost, wrapping ErrPostNotFound, before anything is published.
+func (c *Client) ContinueThread(ctx context.Context, parentID string, chunks []string) (*PostResult, error) {
+	if parentID == "" {
+		return nil, fmt.Errorf("%w: parent post ID is required", ErrInvalidPost)
+	}
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("%w: no chunks to post", ErrInvalidPost)
+	}
+	if c.MaxThreadChunks > 0 && len(chunks) > c.MaxThreadChunks {
+		return nil, fmt.Errorf("%w: %d chunks are more than the thread limit of %d", ErrInvalidPost, len(chunks), c.MaxThreadChunks)
+	}
+	for i, chunk := range chunks {
+		if strings.TrimSpace(chunk) == "" {
+			return nil, fmt.Errorf("%w: chunk %d is empty", ErrInvalidPost, i)
+		}
+		if n := utf8.RuneCountInString(chunk); n > maxCharLimit {
+			return nil, fmt.Errorf("%w: chunk %d is %d characters, more than the limit of %d", ErrInvalidPost, i, n, maxCharLimit)
+		}
+	}
+
+	return c.publish(ctx, func(ctx context.Context) (*PostResult, error) {
+		return c.continueThread(ctx, parentID, chunks)
+	})
+}
+
+func (c *Client) continueThread(ctx context.Context, parentID string, chunks []string) (*PostResult, error) {
+	if _, err := c.GetPostContext(ctx, parentID); errors.Is(err, ErrPostNotFound) {
+		return nil, fmt.Errorf("%w: parent %w", ErrInvalidPost, err)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to check parent post: %w", err)
+	}
+
+	var publishedIDs []string
+	partial := func(err error) (*PostResult, error) {
+		if len(publishedIDs) == 0 {
+			return nil, err
+		}
+		return nil, &PartialPostError{PublishedIDs: publishedIDs, Err: err}
+	}
+
+	previousPostID := parentID
+	for i, chunk := range chunks {
+		publishedID, err := c.createAndPublish(ctx, containerParams{Text: chunk, ReplyToID: previousPostID})
+		if err != nil {
+			return partial(fmt.Errorf("chunk %d: %w", i, err))
+		}
+		previousPostID = publishedID
+		publishedIDs = append(publishedIDs, publishedID)
+
+		if i < len(chunks)-1 {
+			if err := SleepContext(ctx, c.postDelay(c.interPostDelay(i))); err != nil {
+				return partial(err)
+			}
+		}
+	}
+
+	return &PostResult{ID: publishedIDs[0], IDs: publishedIDs, Chunks: len(chunks)}, nil
+}
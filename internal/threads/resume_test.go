@@ -0,0 +1,114 @@
+package threads_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/think-root/threads-connector/internal/threads"
+	"github.com/think-root/threads-connector/internal/threads/threadstest"
+)
+
+func TestContinueThreadInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		parentID string
+		chunks   []string
+	}{
+		{"no parent", "", []string{"more"}},
+		{"no chunks", "parent", nil},
+		{"empty chunk", "parent", []string{"more", " "}},
+		{"chunk too long", "parent", []string{strings.Repeat("ї", 501)}},
+		{"unknown parent", "post-404", []string{"more"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+
+			_, err := api.Client().ContinueThread(context.Background(), tt.parentID, tt.chunks)
+			if !errors.Is(err, threads.ErrInvalidPost) {
+				t.Errorf("error = %v, want ErrInvalidPost", err)
+			}
+			if n := len(api.Containers()); n != 0 {
+				t.Errorf("created %d containers, want 0", n)
+			}
+		})
+	}
+}
+
+func TestContinueThread(t *testing.T) {
+	tests := []struct {
+		name string
+		// failAt fails creating the container of this chunk; -1 fails none
+		failAt        int
+		wantPublished int
+	}{
+		{"all chunks", -1, 3},
+		{"first chunk fails", 0, 0},
+		{"later chunk fails", 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := threadstest.NewServer()
+			defer api.Close()
+			c := api.Client()
+			c.MaxAttempts = 1
+
+			parentID, err := c.CreatePost(threads.PostParams{Text: "part 1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			api.Fail = func(r *http.Request) *threads.APIError {
+				if tt.failAt >= 0 && r.Method == http.MethodPost && r.PostForm.Get("text") == chunkText(tt.failAt) {
+					return &threads.APIError{StatusCode: http.StatusBadRequest, Message: "rejected", Code: 10}
+				}
+				return nil
+			}
+
+			chunks := []string{chunkText(0), chunkText(1), chunkText(2)}
+			result, err := c.ContinueThread(context.Background(), parentID, chunks)
+
+			// The parent is the first published container
+			published := api.Published()[1:]
+			if len(published) != tt.wantPublished {
+				t.Fatalf("published %d chunks, want %d", len(published), tt.wantPublished)
+			}
+			var ids []string
+			previous := parentID
+			for i, p := range published {
+				if p.Params.Get("text") != chunks[i] || p.Params.Get("reply_to_id") != previous {
+					t.Errorf("chunk %d posted %q replying to %q, want %q replying to %q", i, p.Params.Get("text"), p.Params.Get("reply_to_id"), chunks[i], previous)
+				}
+				previous = p.PublishedID
+				ids = append(ids, p.PublishedID)
+			}
+
+			var partial *threads.PartialPostError
+			switch {
+			case tt.failAt < 0:
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.ID != ids[0] || !slices.Equal(result.IDs, ids) || result.Chunks != len(chunks) {
+					t.Errorf("result = %+v, want IDs %v", result, ids)
+				}
+			case tt.wantPublished == 0:
+				if err == nil || errors.As(err, &partial) {
+					t.Errorf("error = %v, want a plain error", err)
+				}
+			default:
+				if !errors.As(err, &partial) || !slices.Equal(partial.PublishedIDs, ids) {
+					t.Errorf("error = %v, want a PartialPostError with %v", err, ids)
+				}
+			}
+		})
+	}
+}
+
+func chunkText(i int) string {
+	return "part " + string(rune('2'+i))
+}
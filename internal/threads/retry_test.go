@@ -0,0 +1,123 @@
+package threads
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   bool
+	}{
+		{"ok", http.StatusOK, `{}`, false},
+		{"too many requests", http.StatusTooManyRequests, ``, true},
+		{"server error", http.StatusInternalServerError, ``, true},
+		{"bad gateway", http.StatusBadGateway, `<html>`, true},
+		{"rate limit code", http.StatusBadRequest, `{"error":{"code":4}}`, true},
+		{"temporary issue code", http.StatusBadRequest, `{"error":{"code":2}}`, true},
+		{"flagged transient", http.StatusBadRequest, `{"error":{"code":100,"is_transient":true}}`, true},
+		{"invalid parameter", http.StatusBadRequest, `{"error":{"code":100}}`, false},
+		{"invalid token", http.StatusUnauthorized, `{"error":{"code":190}}`, false},
+		{"unparsable body", http.StatusBadRequest, `not json`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransient(tt.status, []byte(tt.body)); got != tt.want {
+				t.Errorf("isTransient(%d, %s) = %v, want %v", tt.status, tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   bool
+	}{
+		{"ok", http.StatusOK, `{}`, false},
+		{"too many requests", http.StatusTooManyRequests, ``, true},
+		{"server error", http.StatusInternalServerError, ``, false},
+		{"server error with rate limit code", http.StatusServiceUnavailable, `{"error":{"code":4}}`, false},
+		{"rate limit code", http.StatusBadRequest, `{"error":{"code":4}}`, true},
+		{"temporary issue code", http.StatusBadRequest, `{"error":{"code":2}}`, false},
+		{"flagged transient", http.StatusBadRequest, `{"error":{"code":100,"is_transient":true}}`, false},
+		{"invalid parameter", http.StatusBadRequest, `{"error":{"code":100}}`, false},
+		{"unparsable body", http.StatusBadRequest, `not json`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRejected(tt.status, []byte(tt.body)); got != tt.want {
+				t.Errorf("isRejected(%d, %s) = %v, want %v", tt.status, tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	c := NewClient("user", "token")
+	c.RetryBaseDelay = time.Second
+
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{1, 500 * time.Millisecond, time.Second},
+		{2, time.Second, 2 * time.Second},
+		{3, 2 * time.Second, 4 * time.Second},
+	}
+	for _, tt := range tests {
+		for range 100 {
+			if d := c.backoffDelay(tt.attempt); d < tt.min || d > tt.max {
+				t.Fatalf("backoffDelay(%d) = %v, want between %v and %v", tt.attempt, d, tt.min, tt.max)
+			}
+		}
+	}
+
+	c.RetryBaseDelay = 0
+	if d := c.backoffDelay(1); d != 0 {
+		t.Errorf("backoffDelay with no base delay = %v, want 0", d)
+	}
+}
+
+func TestJitterVaries(t *testing.T) {
+	// delays draws n backoff and post delays from a client seeded with seed
+	delays := func(seed uint64, n int) []time.Duration {
+		c := NewClient("user", "token")
+		c.RetryBaseDelay = time.Second
+		c.Rand = rand.New(rand.NewPCG(seed, seed))
+		var out []time.Duration
+		for range n {
+			out = append(out, c.backoffDelay(1), c.jitter(time.Second, 0.2))
+		}
+		return out
+	}
+
+	first := delays(1, 20)
+	distinct := make(map[time.Duration]bool)
+	for i, d := range first {
+		distinct[d] = true
+		// Backoff and post delays alternate
+		if i%2 == 1 && (d < 800*time.Millisecond || d > 1200*time.Millisecond) {
+			t.Errorf("jitter(1s, 0.2) = %v, want between 800ms and 1.2s", d)
+		}
+	}
+	if len(distinct) < len(first)/2 {
+		t.Errorf("%d distinct delays out of %d, want them to vary: %v", len(distinct), len(first), first)
+	}
+
+	// The same seed replays the same delays, so tests can pin them down
+	if again := delays(1, 20); !slices.Equal(again, first) {
+		t.Errorf("delays with the same seed = %v, want %v", again, first)
+	}
+	if other := delays(2, 20); slices.Equal(other, first) {
+		t.Error("delays with another seed are identical")
+	}
+}
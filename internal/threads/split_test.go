@@ -0,0 +1,146 @@
+package threads
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{"empty", "", 10, []string{}},
+		{"fits", "short text", 10, []string{"short text"}},
+		{"words", "one two three four", 9, []string{"one two", "three", "four"}},
+		{"cyrillic counted in runes", "привіт світ", 6, []string{"привіт", "світ"}},
+		{"cyrillic fits despite its bytes", "привіт світ", 11, []string{"привіт світ"}},
+		{"emoji", "😀😀 😀😀 😀😀", 5, []string{"😀😀 😀😀", "😀😀"}},
+		{"long word", "abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"long word after text", "ab cdefghij", 4, []string{"ab", "cdef", "ghij"}},
+		{"long emoji word", "😀😀😀😀😀", 2, []string{"😀😀", "😀😀", "😀"}},
+		{"combining marks stay with their letter", "ééé", 3, []string{"é", "é", "é"}},
+		{"skin tone stays with its emoji", "👍🏽👍🏽👍🏽", 3, []string{"👍🏽", "👍🏽", "👍🏽"}},
+		{"paragraph break", "one two\n\nthree four five", 15, []string{"one two", "three four five"}},
+		{"paragraph break too early", "a\n\nthree four five", 15, []string{"a\n\nthree four", "five"}},
+		{"text that fits is left alone", "one\ntwo\n\n\n\nthree", 20, []string{"one\ntwo\n\n\n\nthree"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitText(tt.text, tt.limit)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("splitText(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+			}
+			for _, chunk := range got {
+				if n := utf8.RuneCountInString(chunk); n > tt.limit {
+					t.Errorf("chunk %q has %d runes, more than %d", chunk, n, tt.limit)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitTextLinks(t *testing.T) {
+	link := "https://example.com/a/long/path"
+	longLink := "https://example.com/" + strings.Repeat("x", 40)
+
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{"link kept whole", "aaaa bbbb cccc dddd " + link, 40, []string{"aaaa bbbb cccc dddd", link}},
+		{
+			"sentence moves with the link",
+			"The first sentence is right here. Read more at " + link,
+			50,
+			[]string{"The first sentence is right here.", "Read more at " + link},
+		},
+		{
+			"sentence too long to move along",
+			"First. Then a longer sentence before it " + link,
+			50,
+			[]string{"First. Then a longer sentence before it", link},
+		},
+		{"link longer than the limit", longLink, 30, []string{longLink[:30], longLink[30:]}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitText(tt.text, tt.limit)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("splitText(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitSentences(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{"breaks after a sentence", "One two three. Four five six", 20, []string{"One two three.", "Four five six"}},
+		{"sentence end too early", "One. Two three four five six", 20, []string{"One. Two three four", "five six"}},
+		{"closing quote", `He said "stop." And left then`, 20, []string{`He said "stop."`, "And left then"}},
+		{"ellipsis", "Привіт усім… Як справи сьогодні", 20, []string{"Привіт усім…", "Як справи сьогодні"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitSentences(tt.text, tt.limit); !slices.Equal(got, tt.want) {
+				t.Errorf("splitSentences(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNumberChunks(t *testing.T) {
+	words := func(n int) string { return strings.TrimSpace(strings.Repeat("abcd ", n)) }
+
+	tests := []struct {
+		name       string
+		text       string
+		wantChunks int
+	}{
+		{"single post isn't numbered", "hello", 1},
+		{"full single post isn't numbered", words(100), 1},
+		{"thread", words(200), 3},
+		{"two-digit count", words(1000), 11},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient("user", "token")
+			c.NumberChunks = true
+
+			chunks := c.SplitText(tt.text)
+			if len(chunks) != tt.wantChunks {
+				t.Fatalf("SplitText returned %d posts, want %d", len(chunks), tt.wantChunks)
+			}
+			var text []string
+			for i, chunk := range chunks {
+				if n := utf8.RuneCountInString(chunk); n > maxCharLimit {
+					t.Errorf("post %d has %d characters, more than %d", i, n, maxCharLimit)
+				}
+				if len(chunks) > 1 {
+					suffix := chunkSuffix(i+1, len(chunks))
+					if !strings.HasSuffix(chunk, suffix) {
+						t.Errorf("post %d = %q, want it to end with %q", i, chunk, suffix)
+					}
+					chunk = strings.TrimSuffix(chunk, suffix)
+				} else if strings.HasSuffix(chunk, ")") {
+					t.Errorf("single post %q is numbered", chunk)
+				}
+				text = append(text, chunk)
+			}
+			if got := strings.Join(text, " "); got != tt.text {
+				t.Errorf("posts without their numbers = %q, want %q", got, tt.text)
+			}
+		})
+	}
+}
@@ -0,0 +1,416 @@
+// Package threadstest provides a fake Threads API for exercising threads.Client end to
+// end without the network, in the spirit of net/http/httptest.
+package threadstest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/think-root/threads-connector/internal/threads"
+)
+
+// UserID is the Threads user the fake API serves.
+const UserID = "1234567890"
+
+// AccessToken is the token the fake API accepts until it is refreshed.
+const AccessToken = "test-token"
+
+// refreshedTokenLifetime is the expires_in reported for a refreshed token, 60 days.
+const refreshedTokenLifetime = 60 * 24 * 60 * 60
+
+// Container is a media container created through the fake API.
+type Container struct {
+	ID string
+	// Params are the form values the container was created with
+	Params url.Values
+	// Polls counts the status checks made so far
+	Polls int
+	// PublishedID is the ID of the post made from the container, empty until published
+	PublishedID string
+	// PublishedAt is when the container was published
+	PublishedAt time.Time
+	// Deleted is set once the published post was deleted
+	Deleted bool
+}
+
+// Server is a fake Threads API. It implements container creation, status polling,
+// publishing, fetching, listing and deleting published posts, post and account
+// insights, location_search, profile_lookup, debug_token and refresh_access_token, and records every container
+// so callers can check what was sent.
+type Server struct {
+	*httptest.Server
+
+	// Status decides the status reported for a container poll; nil reports FINISHED.
+	// It is called with the server's lock held, so it must not call back into Server.
+	Status func(c Container) string
+	// Fail, when set, is consulted before every request; a non-nil *threads.APIError
+	// is sent as the response instead of handling the request.
+	Fail func(r *http.Request) *threads.APIError
+	// Usernames are the profiles profile_lookup finds; any other username is reported
+	// as an invalid parameter, as the real API does.
+	Usernames []string
+	// TokenInfo decides what debug_token reports; nil reports a valid token that
+	// expires in 60 days. Like Status, it is called with the server's lock held.
+	TokenInfo func() threads.TokenInfo
+	// Insights are the metric values reported for every published post and for the
+	// account, by metric name such as "views"; missing metrics are reported as zero.
+	Insights map[string]int64
+	// Locations are the places location_search can find; a search returns those whose
+	// name contains the query, ignoring case.
+	Locations []threads.Location
+
+	mu         sync.Mutex
+	nextID     int
+	containers []*Container
+	token      string
+	refreshes  int
+}
+
+// NewServer starts a fake Threads API. Call Close when done.
+func NewServer() *Server {
+	s := &Server{token: AccessToken}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /{user}/threads", s.handleCreate)
+	mux.HandleFunc("POST /{user}/threads_publish", s.handlePublish)
+	mux.HandleFunc("GET /{user}/threads", s.handleList)
+	mux.HandleFunc("GET /debug_token", s.handleDebugToken)
+	mux.HandleFunc("GET /profile_lookup", s.handleProfileLookup)
+	mux.HandleFunc("GET /location_search", s.handleLocationSearch)
+	mux.HandleFunc("GET /refresh_access_token", s.handleRefreshToken)
+	mux.HandleFunc("GET /{user}/threads_insights", s.handleUserInsights)
+	mux.HandleFunc("GET /{id}/insights", s.handlePostInsights)
+	mux.HandleFunc("GET /{id}", s.handleGet)
+	mux.HandleFunc("DELETE /{id}", s.handleDelete)
+	s.Server = httptest.NewServer(s.wrap(mux))
+	return s
+}
+
+// Client returns a client for the fake API that polls without delay.
+func (s *Server) Client() *threads.Client {
+	c := threads.NewClientWithHTTP(UserID, AccessToken, s.Server.Client())
+	c.BaseURL = s.URL
+	c.InterPostDelay = 0
+	c.PostDelayJitter = 0
+	c.RetryBaseDelay = time.Millisecond
+	c.ContainerPollInterval = time.Millisecond
+	c.URLReplyTimeout = time.Second
+	return c
+}
+
+// Containers returns copies of all containers created so far, oldest first.
+func (s *Server) Containers() []Container {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	containers := make([]Container, len(s.containers))
+	for i, c := range s.containers {
+		containers[i] = *c
+		containers[i].Params = cloneValues(c.Params)
+	}
+	return containers
+}
+
+// Token returns the access token the fake API currently accepts: AccessToken, or the
+// one handed out by the latest refresh_access_token call.
+func (s *Server) Token() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token
+}
+
+// Published returns the containers that were published, in publishing order.
+func (s *Server) Published() []Container {
+	var published []Container
+	for _, c := range s.Containers() {
+		if c.PublishedID != "" {
+			published = append(published, c)
+		}
+	}
+	return published
+}
+
+// wrap checks the access token and applies Fail before handing the request on. The
+// token must come in the Authorization header; one in the URL, where it would end up
+// in access logs, fails the request, except for refresh_access_token, which takes the
+// token it replaces as a parameter.
+func (s *Server) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			writeError(w, &threads.APIError{StatusCode: http.StatusBadRequest, Message: err.Error(), Code: threads.ErrorCodeInvalidParameter})
+			return
+		}
+		if r.URL.Query().Has("access_token") && r.URL.Path != "/refresh_access_token" {
+			writeError(w, &threads.APIError{StatusCode: http.StatusBadRequest, Message: "threadstest: access_token sent in the URL", Code: threads.ErrorCodeInvalidParameter})
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token != s.Token() {
+			writeError(w, &threads.APIError{StatusCode: http.StatusUnauthorized, Message: "Invalid OAuth access token", Code: threads.ErrorCodeInvalidToken})
+			return
+		}
+		if s.Fail != nil {
+			if apiErr := s.Fail(r); apiErr != nil {
+				writeError(w, apiErr)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("user") != UserID {
+		writeError(w, &threads.APIError{StatusCode: http.StatusBadRequest, Message: "Unknown user", Code: threads.ErrorCodeInvalidParameter})
+		return
+	}
+
+	s.mu.Lock()
+	s.nextID++
+	c := &Container{ID: fmt.Sprintf("container-%d", s.nextID), Params: cloneValues(r.PostForm)}
+	s.containers = append(s.containers, c)
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]string{"id": c.ID})
+}
+
+func (s *Server) handlePublish(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.container(r.PostForm.Get("creation_id"))
+	if c == nil {
+		writeError(w, &threads.APIError{StatusCode: http.StatusBadRequest, Message: "Unknown creation_id", Code: threads.ErrorCodeInvalidParameter})
+		return
+	}
+	if c.PublishedID == "" {
+		s.nextID++
+		c.PublishedID = fmt.Sprintf("post-%d", s.nextID)
+		c.PublishedAt = time.Now()
+	}
+	writeJSON(w, map[string]string{"id": c.PublishedID})
+}
+
+func (s *Server) handleDebugToken(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info := threads.TokenInfo{
+		IsValid:   true,
+		ExpiresAt: time.Now().Add(60 * 24 * time.Hour).Unix(),
+		Scopes:    []string{"threads_basic", "threads_content_publish"},
+		UserID:    UserID,
+	}
+	if s.TokenInfo != nil {
+		info = s.TokenInfo()
+	}
+	writeJSON(w, map[string]any{"data": info})
+}
+
+// handleRefreshToken swaps the access token for a new one, which the fake API accepts
+// from then on instead.
+func (s *Server) handleRefreshToken(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Form.Get("grant_type") != "th_refresh_token" || r.Form.Get("access_token") != s.token {
+		writeError(w, &threads.APIError{StatusCode: http.StatusBadRequest, Message: "Invalid refresh request", Code: threads.ErrorCodeInvalidParameter})
+		return
+	}
+	s.refreshes++
+	s.token = fmt.Sprintf("%s-refreshed-%d", AccessToken, s.refreshes)
+	writeJSON(w, map[string]any{"access_token": s.token, "token_type": "bearer", "expires_in": refreshedTokenLifetime})
+}
+
+func (s *Server) handleProfileLookup(w http.ResponseWriter, r *http.Request) {
+	username := r.Form.Get("username")
+	if !slices.Contains(s.Usernames, username) {
+		writeError(w, &threads.APIError{StatusCode: http.StatusBadRequest, Message: "Invalid username", Code: threads.ErrorCodeInvalidParameter})
+		return
+	}
+	writeJSON(w, map[string]string{"username": username})
+}
+
+func (s *Server) handleLocationSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.ToLower(r.Form.Get("q"))
+	data := []threads.Location{}
+	for _, l := range s.Locations {
+		if strings.Contains(strings.ToLower(l.Name), query) {
+			data = append(data, l)
+		}
+	}
+	writeJSON(w, map[string]any{"data": data})
+}
+
+// handleGet serves both container status checks and published posts, which share
+// the /{id} path on the real API.
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c := s.container(id); c != nil {
+		c.Polls++
+		status := "FINISHED"
+		if s.Status != nil {
+			status = s.Status(*c)
+		}
+		writeJSON(w, map[string]string{"id": c.ID, "status": status})
+		return
+	}
+
+	if c := s.published(id); c != nil {
+		writeJSON(w, post(c))
+		return
+	}
+	writeError(w, &threads.APIError{StatusCode: http.StatusNotFound, Message: "Object does not exist", Code: threads.ErrorCodeInvalidParameter})
+}
+
+// handlePostInsights reports Insights for each requested metric of a published post.
+func (s *Server) handlePostInsights(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.published(r.PathValue("id")) == nil {
+		writeError(w, &threads.APIError{StatusCode: http.StatusBadRequest, Message: "Object does not exist", Code: threads.ErrorCodeInvalidParameter, Subcode: 33})
+		return
+	}
+	writeJSON(w, map[string]any{"data": s.metrics(r.Form.Get("metric"))})
+}
+
+// handleUserInsights reports Insights for each requested metric of the account.
+func (s *Server) handleUserInsights(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, map[string]any{"data": s.metrics(r.Form.Get("metric"))})
+}
+
+// metrics lists the Insights values of the comma-separated metric names in the shape
+// of the insights endpoints; s.mu must be held.
+func (s *Server) metrics(names string) []map[string]any {
+	data := []map[string]any{}
+	for name := range strings.SplitSeq(names, ",") {
+		// Like the real API, followers_count is a total rather than a list of values
+		if name == "followers_count" {
+			data = append(data, map[string]any{"name": name, "total_value": map[string]int64{"value": s.Insights[name]}})
+			continue
+		}
+		data = append(data, map[string]any{"name": name, "values": []map[string]int64{{"value": s.Insights[name]}}})
+	}
+	return data
+}
+
+// handleDelete deletes a published post, answering like the real API does for an
+// object that doesn't exist when there is no such post.
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.published(r.PathValue("id"))
+	if c == nil {
+		writeError(w, &threads.APIError{StatusCode: http.StatusBadRequest, Message: "Object does not exist", Code: threads.ErrorCodeInvalidParameter, Subcode: 33})
+		return
+	}
+	c.Deleted = true
+	writeJSON(w, map[string]bool{"success": true})
+}
+
+// handleList lists published posts newest first, limit per page. Like the real API it
+// reports an after cursor on every page, and a next link only when more posts follow.
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	posts := []threads.Post{}
+	for i := len(s.containers) - 1; i >= 0; i-- {
+		if c := s.containers[i]; c.PublishedID != "" && !c.Deleted {
+			posts = append(posts, post(c))
+		}
+	}
+
+	start, _ := strconv.Atoi(strings.TrimPrefix(r.Form.Get("after"), "cursor-"))
+	start = min(start, len(posts))
+	end := len(posts)
+	if limit, err := strconv.Atoi(r.Form.Get("limit")); err == nil && limit > 0 {
+		end = min(start+limit, end)
+	}
+
+	paging := map[string]any{"cursors": map[string]string{"after": fmt.Sprintf("cursor-%d", end)}}
+	if end < len(posts) {
+		paging["next"] = fmt.Sprintf("%s%s?after=cursor-%d", s.URL, r.URL.Path, end)
+	}
+	writeJSON(w, map[string]any{"data": posts[start:end], "paging": paging})
+}
+
+// post describes the published post made from c.
+func post(c *Container) threads.Post {
+	return threads.Post{
+		ID:               c.PublishedID,
+		MediaProductType: "THREADS",
+		MediaType:        c.Params.Get("media_type"),
+		Permalink:        "https://www.threads.net/@test/post/" + c.PublishedID,
+		Username:         "test",
+		Text:             c.Params.Get("text"),
+		Timestamp:        c.PublishedAt.UTC().Format("2006-01-02T15:04:05-0700"),
+	}
+}
+
+// published looks up a post that was published and not deleted by its ID; s.mu must be held.
+func (s *Server) published(id string) *Container {
+	for _, c := range s.containers {
+		if c.PublishedID == id && !c.Deleted {
+			return c
+		}
+	}
+	return nil
+}
+
+// container looks up a container by ID; s.mu must be held.
+func (s *Server) container(id string) *Container {
+	for _, c := range s.containers {
+		if c.ID == id {
+			return c
+		}
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError sends apiErr in the Graph API error format.
+func writeError(w http.ResponseWriter, apiErr *threads.APIError) {
+	status := apiErr.StatusCode
+	if status == 0 {
+		status = http.StatusBadRequest
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{
+		"message":       apiErr.Message,
+		"type":          "OAuthException",
+		"code":          apiErr.Code,
+		"error_subcode": apiErr.Subcode,
+		"is_transient":  apiErr.Transient,
+		"fbtrace_id":    "threadstest",
+	}})
+}
+
+func cloneValues(v url.Values) url.Values {
+	clone := make(url.Values, len(v))
+	for key, values := range v {
+		clone[key] = append([]string(nil), values...)
+	}
+	return clone
+}
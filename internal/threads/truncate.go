@@ -0,0 +1,60 @@
+package threads
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ellipsis marks where truncated text was cut off
+const ellipsis = "…"
+
+// truncateText fits text into a single post instead of splitting it into a thread.
+// Text over the limit is cut at the last word that fits and ends with "…"; a non-empty
+// readMore link then follows on its own line in place of the rest, unless the link is
+// too long to leave room for any text. The footer is kept either way. linked reports
+// whether the text was cut and ends with readMore.
+func (c *Client) truncateText(text, readMore string) (chunks []string, linked bool) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, false
+	}
+
+	footer := c.footerFor(text)
+	limit := maxCharLimit - utf8.RuneCountInString(footer)
+	if utf8.RuneCountInString(text) <= limit {
+		return []string{text + footer}, false
+	}
+
+	suffix := ellipsis
+	if readMore != "" {
+		suffix += "\n\n" + readMore
+	}
+	room := limit - utf8.RuneCountInString(suffix)
+	linked = readMore != ""
+	if room <= 0 {
+		// A link this long would push the post over the limit on its own
+		suffix, linked = ellipsis, false
+		room = max(limit-utf8.RuneCountInString(suffix), 0)
+	}
+	return []string{cutAtWord(text, room) + suffix + footer}, linked
+}
+
+// cutAtWord returns the longest prefix of text with at most limit runes that ends at
+// a word boundary. A first word longer than limit is cut mid-word.
+func cutAtWord(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+
+	cut := string(runes[:limit])
+	// The cut already falls between words when the next rune is a space
+	if !unicode.IsSpace(runes[limit]) {
+		if i := strings.LastIndexFunc(cut, unicode.IsSpace); i > 0 {
+			cut = cut[:i]
+		}
+	}
+	// A dangling comma or dash reads badly right before the ellipsis
+	return strings.TrimRight(strings.TrimRightFunc(cut, unicode.IsSpace), ",;:-–—")
+}
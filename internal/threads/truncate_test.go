@@ -0,0 +1,76 @@
+package threads
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateText(t *testing.T) {
+	long := strings.Repeat("word ", 150)
+	longURL := "https://example.com/" + strings.Repeat("a", 600)
+
+	tests := []struct {
+		name       string
+		text       string
+		readMore   string
+		wantLinked bool
+		wantSuffix string
+	}{
+		{"fits", "short text", "https://example.com", false, "short text"},
+		{"cut without link", long, "", false, "word…"},
+		{"cut with link", long, "https://example.com", true, "word…\n\nhttps://example.com"},
+		{"link too long", long, longURL, false, "word…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient("user", "token")
+			chunks, linked := c.truncateText(tt.text, tt.readMore)
+			if len(chunks) != 1 {
+				t.Fatalf("got %d chunks, want 1", len(chunks))
+			}
+			if linked != tt.wantLinked {
+				t.Errorf("linked = %v, want %v", linked, tt.wantLinked)
+			}
+			if n := utf8.RuneCountInString(chunks[0]); n > maxCharLimit {
+				t.Errorf("chunk is %d runes, over the limit of %d", n, maxCharLimit)
+			}
+			if !strings.HasSuffix(chunks[0], tt.wantSuffix) {
+				t.Errorf("chunk ends with %q, want suffix %q", chunks[0][max(0, len(chunks[0])-40):], tt.wantSuffix)
+			}
+		})
+	}
+}
+
+func TestTruncateTextKeepsFooter(t *testing.T) {
+	c := NewClient("user", "token")
+	c.Footer = "#golang"
+
+	chunks, _ := c.truncateText(strings.Repeat("word ", 150), "https://example.com")
+	if n := utf8.RuneCountInString(chunks[0]); n > maxCharLimit {
+		t.Fatalf("chunk is %d runes, over the limit of %d", n, maxCharLimit)
+	}
+	if !strings.HasSuffix(chunks[0], "#golang") {
+		t.Errorf("footer missing from %q", chunks[0])
+	}
+}
+
+func TestCutAtWord(t *testing.T) {
+	tests := []struct {
+		text  string
+		limit int
+		want  string
+	}{
+		{"hello world", 20, "hello world"},
+		{"hello world", 8, "hello"},
+		{"hello world", 5, "hello"},
+		{"hello, world", 8, "hello"},
+		{"supercalifragilistic", 5, "super"},
+		{"привіт світе", 9, "привіт"},
+	}
+	for _, tt := range tests {
+		if got := cutAtWord(tt.text, tt.limit); got != tt.want {
+			t.Errorf("cutAtWord(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+		}
+	}
+}
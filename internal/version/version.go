@@ -0,0 +1,13 @@
+// Package version holds build information, set at build time with -ldflags, e.g.
+//
+//	go build -ldflags "-X github.com/think-root/threads-connector/internal/version.Version=v1.2.0" ./cmd/server
+package version
+
+var (
+	// Version is the release version of the build
+	Version = "dev"
+	// Commit is the git commit the binary was built from
+	Commit = "unknown"
+	// BuildDate is when the binary was built, in RFC 3339
+	BuildDate = "unknown"
+)